@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/agent"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+var errAgentUsage = errors.New("usage: agent start [-socket=PATH] [-ttl=DURATION] [-idle=DURATION] [-policy=NAME] -keyring=FILE|-key-file=FILE|... | agent status|lock [-socket=PATH]")
+
+// runAgent запускает агент ключей или управляет запущенным агентом
+func runAgent(args []string) error {
+	if len(args) == 0 {
+		return errAgentUsage
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("agent "+action, flag.ExitOnError)
+	socket := fs.String("socket", defaultAgentSocket(), "agent Unix socket path")
+	switch action {
+	case "start":
+		return agentStart(fs, socket, args[1:])
+	case "status", "lock":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return agentControl(action, *socket)
+	}
+	return errAgentUsage
+}
+
+// defaultAgentSocket возвращает сокет из ENCRYPTOR_AGENT_SOCK или путь по умолчанию
+func defaultAgentSocket() string {
+	if socket, ok := config.AgentSocket(); ok {
+		return socket
+	}
+	return agent.DefaultSocketPath()
+}
+
+// agentStart разблокирует ключи один раз и обслуживает запросы до блокировки
+func agentStart(fs *flag.FlagSet, socket *string, args []string) error {
+	keys := addKeyFlags(fs)
+	ttl := fs.Duration("ttl", 8*time.Hour, "lock the agent this long after start (0 disables)")
+	idle := fs.Duration("idle", 30*time.Minute, "lock the agent after this long without requests (0 disables)")
+	policyName := fs.String("policy", "", "enforce a policy on the key and algorithms (e.g. fips)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if keys.count() == 0 {
+		return errAgentUsage
+	}
+	// Сам агент работает с ключами напрямую, а не через другой агент
+	os.Unsetenv(config.AgentSockEnv)
+
+	var opts []config.Option
+	var status agent.Status
+	if *policyName != "" {
+		policy, err := config.LookupPolicy(*policyName)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, config.WithPolicy(policy))
+		status.Policy = policy.Name
+	}
+	if *keys.keyring != "" {
+		path, err := filepath.Abs(*keys.keyring)
+		if err != nil {
+			return err
+		}
+		status.Keyring = path
+	}
+	cfg, err := keys.config(opts...)
+	if err != nil {
+		return err
+	}
+	encryptor, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		return err
+	}
+
+	l, err := agent.Listen(*socket)
+	if err != nil {
+		encryptor.Close()
+		return err
+	}
+	srv := agent.NewServer(encryptor, status)
+	srv.TTL = *ttl
+	srv.IdleTimeout = *idle
+	srv.Logger = log.New(os.Stderr, "", log.LstdFlags)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			srv.Lock()
+		case <-srv.Done():
+		}
+	}()
+
+	// Строку можно выполнить в shell, чтобы CLI и библиотека нашли агента
+	fmt.Printf("%s=%s; export %s;\n", config.AgentSockEnv, *socket, config.AgentSockEnv)
+	fmt.Fprintf(os.Stderr, "agent (pid %d) listening on %s, ttl %s, idle timeout %s\n", os.Getpid(), *socket, *ttl, *idle)
+	err = srv.Serve(l)
+	fmt.Fprintln(os.Stderr, "agent locked, no longer serving requests; keys are released when the process exits")
+	return err
+}
+
+// agentControl выполняет status или lock для запущенного агента
+func agentControl(action, socket string) error {
+	client := agent.NewClient(&config.AgentConfig{Socket: socket})
+	if action == "lock" {
+		if err := client.Lock(context.Background()); err != nil {
+			return err
+		}
+		fmt.Println("agent locked")
+		return nil
+	}
+
+	status, err := client.Status(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("socket:  %s\npid:     %d\n", socket, status.PID)
+	if status.Keyring != "" {
+		fmt.Printf("keyring: %s\n", status.Keyring)
+	}
+	if status.Policy != "" {
+		fmt.Printf("policy:  %s\n", status.Policy)
+	}
+	fmt.Printf("ttl:     %s\nidle:    %s\n", remaining(status.TTL), remaining(status.IdleTimeout))
+	return nil
+}
+
+// remaining форматирует оставшееся до блокировки время
+func remaining(seconds int64) string {
+	if seconds == 0 {
+		return "unlimited"
+	}
+	return (time.Duration(seconds) * time.Second).String() + " left"
+}
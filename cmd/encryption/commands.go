@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// commands подкоманды CLI: имя -> обработчик аргументов после имени
+var commands = map[string]func(args []string) error{
+	"vault":   runVault,
+	"policy":  runPolicy,
+	"keyring": runKeyring,
+	"keygen":  runKeygen,
+	"rotate":  runRotate,
+	"agent":   runAgent,
+	"encrypt": runEncrypt,
+	"key":     runKey,
+}
+
+// newEncryptor создает шифратор по ключу из флагов подкоманды
+func newEncryptor(keys *keyFlags) (*encryption.Encryptor, error) {
+	cfg, err := keys.config()
+	if err != nil {
+		return nil, err
+	}
+	return encryption.NewEncryptor(cfg)
+}
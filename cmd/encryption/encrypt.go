@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JohnnyFes/go-encryptor/internal/configfile"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// runEncrypt шифрует поля конфигурационных файлов по правилам .encryptor.yaml.
+// Без аргументов обрабатывается весь каталог проекта. Флаги ключа заменяют
+// ключ из правил.
+func runEncrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	keys := addKeyFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "only count the values to encrypt, do not write files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if keys.count() > 1 {
+		return errMultipleKeySources
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	project, err := config.FindProjectConfig(wd)
+	if err != nil {
+		return err
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{filepath.Dir(project.Path)}
+	}
+	files, err := configfile.ConfigFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	// Файлы ключей проекта не шифруются, даже если подходят под правило
+	skip := map[string]bool{project.Path: true}
+	for _, r := range project.CreationRules {
+		for _, p := range []string{r.Keyring, r.KeyFile} {
+			if p != "" {
+				skip[p] = true
+			}
+		}
+	}
+
+	encryptors := make(map[*config.CreationRule]*encryption.Encryptor)
+	defer func() {
+		for _, enc := range encryptors {
+			enc.Close()
+		}
+	}()
+
+	encrypted, processed, failed := 0, 0, 0
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		if skip[abs] {
+			continue
+		}
+		rule, err := project.Match(f)
+		if errors.Is(err, config.ErrNoCreationRule) {
+			fmt.Printf("%s: skipped, no creation rule\n", f)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !rule.HasFields() {
+			fmt.Printf("%s: skipped, rule sets no encrypted_fields or encrypted_regex\n", f)
+			continue
+		}
+
+		enc, ok := encryptors[rule]
+		if !ok {
+			cfg, err := keys.ruleConfig(rule)
+			if err == nil {
+				enc, err = encryption.NewEncryptor(cfg)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", f, err)
+			}
+			encryptors[rule] = enc
+		}
+
+		n, err := configfile.EncryptFile(f, rule.ShouldEncrypt, enc.EncryptString, *dryRun)
+		processed++
+		encrypted += n
+		if err != nil {
+			failed++
+			fmt.Printf("%s: %v\n", f, err)
+			continue
+		}
+		fmt.Printf("%s: %d encrypted\n", f, n)
+	}
+
+	verb := "encrypted"
+	if *dryRun {
+		verb = "would be encrypted (dry run)"
+	}
+	fmt.Printf("%d value(s) in %d file(s) %s\n", encrypted, processed, verb)
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) could not be encrypted", failed)
+	}
+	return nil
+}
+
+// projectRule возвращает правило .encryptor.yaml для файла path или nil,
+// если файла настроек проекта нет
+func projectRule(path string) (*config.CreationRule, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	project, err := config.FindProjectConfig(wd)
+	if errors.Is(err, config.ErrNoProjectConfig) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rule, err := project.Match(path)
+	if errors.Is(err, config.ErrNoCreationRule) {
+		return nil, nil
+	}
+	return rule, err
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+var errKeyUsage = errors.New("usage: key fingerprint [-key-file=FILE|-key-env=VAR|-key-fd=N|-key-component=...|-keyring=FILE] [ENC[...]...]\n" +
+	"       key ceremony -components=N [-algorithm=ALG] [-out=DIR]")
+
+// runKey выводит сведения о ключах, не раскрывая ключевой материал,
+// и проводит церемонию создания раздельного ключа
+func runKey(args []string) error {
+	if len(args) == 0 {
+		return errKeyUsage
+	}
+	switch args[0] {
+	case "fingerprint":
+		return keyFingerprint(args[1:])
+	case "ceremony":
+		return keyCeremony(args[1:])
+	}
+	return errKeyUsage
+}
+
+// keyFingerprint выводит отпечаток и KCV ключа или ключи, которыми зашифрованы значения
+func keyFingerprint(args []string) error {
+	fs := flag.NewFlagSet("key fingerprint", flag.ExitOnError)
+	keys := addKeyFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Значения ENC[...]: какой ключ нужен для расшифровки
+	if fs.NArg() > 0 {
+		for _, value := range fs.Args() {
+			env, err := encryption.ParseEnvelope(value)
+			if err != nil {
+				return err
+			}
+			key := "- (value has no key ID)"
+			if kfp := env.Attrs[encryption.AttrKeyFingerprint]; kfp != "" {
+				key = "fingerprint " + kfp
+			} else if kid := env.Attrs[encryption.AttrKeyID]; kid != "" {
+				key = "key " + kid
+			}
+			fmt.Printf("%s\t%s\n", env.Algorithm, key)
+		}
+		return nil
+	}
+
+	switch {
+	case keys.count() > 1:
+		return errMultipleKeySources
+	case *keys.keyring != "":
+		ring, err := loadKeyring(*keys.keyring)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATE\tFINGERPRINT\tKCV")
+		for _, k := range ring.Keys {
+			raw, err := base64.StdEncoding.DecodeString(k.Key)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", k.ID, err)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.ID, k.State, config.KeyFingerprint(raw), config.KeyCheckValue(raw))
+		}
+		return w.Flush()
+	case *keys.ssh || *keys.sshKey != "" || *keys.helper != "" || keys.count() == 0:
+		return errors.New("fingerprints are computed for local keys: use -key-file, -key-env, -key-fd, -key-component or -keyring")
+	}
+
+	src, err := keys.source()
+	if err != nil {
+		return err
+	}
+	var opts []config.Option
+	if *keys.legacy {
+		opts = append(opts, config.WithLegacyKey())
+	}
+	cfg, err := config.NewConfigFromSource(src, opts...)
+	if err != nil {
+		return err
+	}
+	var raw []byte
+	if cfg.LegacyKey {
+		// Тот же ключ, что получает шифратор в режиме совместимости
+		raw = encryption.NormalizeKey(cfg.Key, config.DefaultKeyLength)
+	} else if raw, err = cfg.KeyBytes(); err != nil {
+		return err
+	}
+	fmt.Printf("fingerprint: %s\nkcv:         %s\n", config.KeyFingerprint(raw), config.KeyCheckValue(raw))
+	return nil
+}
+
+// keyCeremony создает компоненты раздельного ключа для хранителей. С -out
+// компоненты записываются в отдельные файлы 0600, иначе показываются в терминале
+// по очереди, и экран очищается перед следующим хранителем. Сам ключ
+// не выводится: только его отпечаток и KCV для записи в протокол церемонии.
+func keyCeremony(args []string) error {
+	fs := flag.NewFlagSet("key ceremony", flag.ExitOnError)
+	n := fs.Int("components", config.MinKeyComponents, "number of key components (custodians)")
+	algorithm := fs.String("algorithm", config.AlgorithmAES256GCM, "encryption algorithm the key is generated for")
+	out := fs.String("out", "", "write components to DIR/component-N.txt (0600) instead of showing them on the terminal")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	components, err := config.GenerateKeyComponents(*algorithm, *n)
+	if err != nil {
+		return err
+	}
+	key, err := config.CombineKeyComponents(components)
+	if err != nil {
+		return err
+	}
+
+	var specs []string
+	if *out != "" {
+		for i, c := range components {
+			path := filepath.Join(*out, fmt.Sprintf("component-%d.txt", i+1))
+			if err := config.WriteKeyFile(path, []byte(base64.StdEncoding.EncodeToString(c)+"\n")); err != nil {
+				return err
+			}
+			fmt.Printf("component %d of %d: %s, KCV %s\n", i+1, len(components), path, config.KeyCheckValue(c))
+			specs = append(specs, fmt.Sprintf("-key-component=file:%s,kcv=%s", path, config.KeyCheckValue(c)))
+		}
+	} else {
+		if err := showComponents(components); err != nil {
+			return err
+		}
+		for i, c := range components {
+			specs = append(specs, fmt.Sprintf("-key-component=prompt:custodian-%d,kcv=%s", i+1, config.KeyCheckValue(c)))
+		}
+	}
+
+	fmt.Printf("key fingerprint: %s\nkey KCV:         %s\n", config.KeyFingerprint(key), config.KeyCheckValue(key))
+	fmt.Printf("use: %s -key-kcv=%s\n", strings.Join(specs, " "), config.KeyCheckValue(key))
+	return nil
+}
+
+// showComponents показывает компоненты хранителям по очереди и очищает экран после каждой
+func showComponents(components [][]byte) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("components are shown on a terminal only: run in a terminal or use -out=DIR")
+	}
+	in := bufio.NewReader(os.Stdin)
+	wait := func(prompt string) error {
+		fmt.Print(prompt)
+		_, err := in.ReadString('\n')
+		return err
+	}
+	for i, c := range components {
+		if err := wait(fmt.Sprintf("Custodian %d of %d: make sure nobody else can see the screen and press Enter ", i+1, len(components))); err != nil {
+			return err
+		}
+		fmt.Printf("\ncomponent %d: %s\nKCV:         %s\n\n", i+1, base64.StdEncoding.EncodeToString(c), config.KeyCheckValue(c))
+		if err := wait("Write down the component and its KCV, then press Enter to clear the screen "); err != nil {
+			return err
+		}
+		// Очистка экрана и буфера прокрутки
+		fmt.Print("\033[H\033[2J\033[3J")
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// keyFlags флаги выбора источника ключа шифрования
+type keyFlags struct {
+	key     *string
+	file    *string
+	env     *string
+	fd      *int
+	helper  *string
+	keyring *string
+	ssh     *bool
+	sshKey  *string
+	legacy  *bool
+	embedID *bool
+	// components - компоненты раздельного ключа (-key-component) и KCV собранного ключа
+	components *stringList
+	kcv        *string
+}
+
+// addKeyFlags регистрирует флаги -key, -key-file, -key-env, -key-fd, -key-helper, -keyring,
+// -ssh-agent, -ssh-key, -key-component, -key-kcv, -legacy-key и -embed-key-id
+func addKeyFlags(fs *flag.FlagSet) *keyFlags {
+	var components stringList
+	fs.Var(&components, "key-component", "split key component: file:PATH, env:VAR or prompt[:LABEL], with optional ,kcv=XXXXXX (repeat for each custodian)")
+	return &keyFlags{
+		key:     fs.String("key", "", "32-byte encryption key in base64, or with hex:/raw: prefix (insecure: visible in shell history and ps, prefer -key-file/-key-env/-key-fd)"),
+		file:    fs.String("key-file", "", "read the encryption key from a file with 0600 permissions (- for stdin)"),
+		env:     fs.String("key-env", "", "read the encryption key from the environment variable"),
+		fd:      fs.Int("key-fd", -1, "read the encryption key from an inherited file descriptor"),
+		helper:  fs.String("key-helper", "", "get the encryption key from a helper program (command and space-separated arguments)"),
+		keyring: fs.String("keyring", "", "use keys from a keyring file (JSON or YAML)"),
+		ssh:     fs.Bool("ssh-agent", false, "derive the encryption key from an Ed25519 signature of ssh-agent (SSH_AUTH_SOCK)"),
+		sshKey:  fs.String("ssh-key", "", "ssh-agent key fingerprint (SHA256:...), implies -ssh-agent (default: first Ed25519 key)"),
+		legacy:  fs.Bool("legacy-key", false, "compatibility: pad or hash keys that are not exactly 32 bytes, as older versions did"),
+		embedID: fs.Bool("embed-key-id", false, "write the key fingerprint into ENC[...] values, so decrypting with another key reports which key is needed"),
+
+		components: &components,
+		kcv:        fs.String("key-kcv", "", "expected KCV of the key assembled from -key-component values"),
+	}
+}
+
+// count возвращает число указанных источников ключа
+func (k *keyFlags) count() int {
+	n := 0
+	for _, set := range []bool{*k.key != "", *k.file != "", *k.env != "", *k.fd >= 0, *k.helper != "", *k.keyring != "", *k.ssh || *k.sshKey != "", len(*k.components) > 0} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// isSet сообщает, указан ли хотя бы один источник ключа или запущен агент ключей
+func (k *keyFlags) isSet() bool {
+	_, agent := config.AgentSocket()
+	return k.count() > 0 || agent
+}
+
+// source возвращает источник ключа; должен быть указан ровно один флаг
+func (k *keyFlags) source() (config.KeySource, error) {
+	var sources []config.KeySource
+	if *k.key != "" {
+		fmt.Fprintln(os.Stderr, "warning: -key exposes the encryption key in shell history and process list; use -key-file, -key-env or -key-fd")
+		sources = append(sources, &literalKey{key: *k.key})
+	}
+	if *k.file == "-" {
+		sources = append(sources, config.StdinKeySource())
+	} else if *k.file != "" {
+		sources = append(sources, &config.FileKeySource{Path: *k.file})
+	}
+	if *k.env != "" {
+		sources = append(sources, &config.EnvKeySource{Name: *k.env})
+	}
+	if *k.fd >= 0 {
+		sources = append(sources, &config.FDKeySource{FD: uintptr(*k.fd)})
+	}
+	if len(*k.components) > 0 {
+		src, err := k.componentSource()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+
+	switch len(sources) {
+	case 0:
+		return nil, errors.New("encryption key is required: use -key-file, -key-env, -key-fd, -key-helper, -key-component, -keyring, -ssh-agent or start an agent and set " + config.AgentSockEnv)
+	case 1:
+		return sources[0], nil
+	}
+	return nil, errMultipleKeySources
+}
+
+// errMultipleKeySources ошибка, если указано несколько источников ключа
+var errMultipleKeySources = errors.New("only one of -key, -key-file, -key-env, -key-fd, -key-helper, -key-component, -keyring and -ssh-agent may be used")
+
+// config создает конфигурацию с ключом из выбранного источника
+func (k *keyFlags) config(opts ...config.Option) (*config.Config, error) {
+	if k.count() > 1 {
+		return nil, errMultipleKeySources
+	}
+	if *k.embedID {
+		opts = append(opts, config.WithEmbedKeyID())
+	}
+	if k.count() == 0 {
+		// Без флагов ключа шифрует агент из ENCRYPTOR_AGENT_SOCK
+		if socket, ok := config.AgentSocket(); ok {
+			return config.NewAgentConfig(socket, opts...)
+		}
+	}
+	if *k.keyring != "" {
+		opts = append(opts, config.WithKeyringPassphrase(keyringPassphrase))
+		return config.NewKeyringConfig(*k.keyring, opts...)
+	}
+	if *k.ssh || *k.sshKey != "" {
+		return config.NewSSHAgentConfig(&config.SSHAgentConfig{Fingerprint: *k.sshKey}, opts...)
+	}
+	if *k.helper != "" {
+		// Ключ получает программа-помощник, локальный ключ не нужен
+		args := strings.Fields(*k.helper)
+		if len(args) == 0 {
+			return nil, errors.New("-key-helper: empty command")
+		}
+		return config.NewKeyHelperConfig(&config.KeyHelperConfig{Command: args[0], Args: args[1:]}, opts...)
+	}
+
+	src, err := k.source()
+	if err != nil {
+		return nil, err
+	}
+	if *k.legacy {
+		opts = append(opts, config.WithLegacyKey())
+	}
+	return config.NewConfigFromSource(src, opts...)
+}
+
+// ruleConfig создает конфигурацию по правилу .encryptor.yaml: алгоритм и политика
+// берутся из правила, ключ - из флагов, а если они не указаны, из правила.
+// opts применяются последними.
+func (k *keyFlags) ruleConfig(rule *config.CreationRule, opts ...config.Option) (*config.Config, error) {
+	if rule == nil {
+		return k.config(opts...)
+	}
+	if k.count() == 0 && rule.HasKey() {
+		if rule.Keyring != "" {
+			opts = append(opts, config.WithKeyringPassphrase(keyringPassphrase))
+		}
+		if *k.embedID {
+			opts = append(opts, config.WithEmbedKeyID())
+		}
+		return rule.NewConfig(opts...)
+	}
+	ruleOpts, err := rule.Options()
+	if err != nil {
+		return nil, err
+	}
+	return k.config(append(ruleOpts, opts...)...)
+}
+
+// componentSource собирает источник раздельного ключа из значений -key-component
+func (k *keyFlags) componentSource() (*config.ComponentKeySource, error) {
+	src := &config.ComponentKeySource{CheckValue: *k.kcv}
+	for i, spec := range *k.components {
+		c, err := parseComponent(spec, i+1)
+		if err != nil {
+			return nil, err
+		}
+		src.Components = append(src.Components, c)
+	}
+	return src, nil
+}
+
+// parseComponent разбирает значение -key-component: file:PATH, env:VAR или
+// prompt[:LABEL], с необязательным суффиксом ,kcv=XXXXXX
+func parseComponent(spec string, n int) (config.KeyComponent, error) {
+	var c config.KeyComponent
+	if i := strings.LastIndex(spec, ",kcv="); i >= 0 {
+		spec, c.CheckValue = spec[:i], spec[i+len(",kcv="):]
+	}
+	kind, value, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "file":
+		c.Name, c.Source = value, &config.FileKeySource{Path: value}
+	case "env":
+		c.Name, c.Source = value, &config.EnvKeySource{Name: value}
+	case "prompt":
+		if value == "" {
+			value = fmt.Sprintf("component %d", n)
+		}
+		c.Name, c.Source = value, &config.PromptKeySource{Label: value, Prompt: promptComponent}
+	default:
+		return c, fmt.Errorf("invalid -key-component %q: expected file:PATH, env:VAR or prompt[:LABEL]", spec)
+	}
+	if value == "" {
+		return c, fmt.Errorf("invalid -key-component %q: missing %s", spec, kind)
+	}
+	return c, nil
+}
+
+// promptComponent запрашивает компоненту ключа в терминале без эха
+// и показывает ее KCV, чтобы хранитель сверил его со своей записью
+func promptComponent(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("stdin is not a terminal, cannot prompt for %s", label)
+	}
+	fmt.Fprintf(os.Stderr, "Key %s: ", label)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", label, err)
+	}
+	if part, err := config.ParseKey(strings.TrimSpace(string(value)), config.KeyEncodingBase64); err == nil {
+		fmt.Fprintf(os.Stderr, "%s KCV: %s\n", label, config.KeyCheckValue(part))
+	}
+	return string(value), nil
+}
+
+// literalKey источник ключа, переданного прямо в командной строке
+type literalKey struct {
+	key string
+}
+
+func (l *literalKey) LoadKey() (string, error) {
+	return l.key, nil
+}
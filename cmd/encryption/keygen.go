@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// runKeygen генерирует ключ шифрования и выводит его или записывает в файл
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	algorithm := fs.String("algorithm", config.AlgorithmAES256GCM, "encryption algorithm the key is generated for")
+	format := fs.String("format", "base64", "output format: base64, hex (with hex: prefix) or keyring (JSON keyring entry with ID and fingerprint)")
+	id := fs.String("id", "key-1", "key ID for -format=keyring")
+	out := fs.String("out", "", "write the key to a new file with 0600 permissions instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var data []byte
+	switch *format {
+	case "base64", "hex":
+		key, err := config.GenerateKey(*algorithm)
+		if err != nil {
+			return err
+		}
+		if *format == "hex" {
+			data = []byte(string(config.KeyEncodingHex) + ":" + hex.EncodeToString(key) + "\n")
+		} else {
+			data = []byte(base64.StdEncoding.EncodeToString(key) + "\n")
+		}
+	case "keyring":
+		key, err := config.NewKeyringKey(*id, *algorithm)
+		if err != nil {
+			return err
+		}
+		key.State = config.KeyStateActive
+		data, err = json.MarshalIndent(key, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("unknown format %q: expected base64, hex or keyring", *format)
+	}
+
+	if *out == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := config.WriteKeyFile(*out, data); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "key written to %s\n", *out)
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+var errKeyringUsage = errors.New("usage: keyring add|promote|retire|revoke|list|passwd -keyring=FILE [ID]")
+
+// runKeyring управляет файлом набора ключей
+func runKeyring(args []string) error {
+	if len(args) == 0 {
+		return errKeyringUsage
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("keyring "+action, flag.ExitOnError)
+	path := fs.String("keyring", "", "keyring file (JSON, or YAML with .yaml/.yml extension)")
+	id := fs.String("id", "", "add: key ID (default key-N)")
+	algorithm := fs.String("algorithm", config.AlgorithmAES256GCM, "add: encryption algorithm")
+	expires := fs.String("expires", "", "add: expiry as a date (2006-01-02), RFC 3339 time or duration (2160h)")
+	activate := fs.Bool("activate", false, "add: make the new key active immediately")
+	encrypt := fs.Bool("encrypt", false, "add: encrypt a new keyring file with a passphrase")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errKeyringUsage
+	}
+
+	switch action {
+	case "list":
+		ring, err := loadKeyring(*path)
+		if err != nil {
+			return err
+		}
+		return printKeyring(ring)
+	case "add":
+		return keyringAdd(*path, *id, *algorithm, *expires, *activate, *encrypt)
+	case "passwd":
+		return keyringPasswd(*path)
+	case "promote", "retire", "revoke":
+		if fs.NArg() != 1 {
+			return errKeyringUsage
+		}
+		return keyringSetState(*path, action, fs.Arg(0))
+	}
+	return errKeyringUsage
+}
+
+// keyringAdd создает случайный ключ и добавляет его в набор (создавая файл при необходимости)
+func keyringAdd(path, id, algorithm, expires string, activate, encrypt bool) error {
+	ring, err := loadKeyring(path)
+	if errors.Is(err, os.ErrNotExist) {
+		ring = config.NewKeyring()
+		if encrypt {
+			passphrase, err := newKeyringPassphrase()
+			if err != nil {
+				return err
+			}
+			ring.SetPassphrase(passphrase)
+		}
+	} else if err != nil {
+		return err
+	} else if encrypt && !ring.Encrypted() {
+		return errors.New("keyring already exists unencrypted, use keyring passwd to encrypt it")
+	}
+
+	if id == "" {
+		id = fmt.Sprintf("key-%d", len(ring.Keys)+1)
+	}
+	key, err := config.NewKeyringKey(id, algorithm)
+	if err != nil {
+		return err
+	}
+	if expires != "" {
+		t, err := parseExpiry(expires, key.Created)
+		if err != nil {
+			return err
+		}
+		key.Expires = &t
+	}
+	if activate {
+		key.State = config.KeyStateActive
+	}
+
+	if err := ring.Add(key); err != nil {
+		return err
+	}
+	if err := ring.Save(path); err != nil {
+		return err
+	}
+	added, _ := ring.Lookup(id)
+	fmt.Printf("added key %s (%s, %s, fingerprint %s)\n", id, key.Algorithm, added.State, key.Fingerprint)
+	return nil
+}
+
+// keyringSetState продвигает, выводит или отзывает ключ
+func keyringSetState(path, action, id string) error {
+	ring, err := loadKeyring(path)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "promote":
+		err = ring.Promote(id)
+	case "retire":
+		err = ring.Retire(id)
+	case "revoke":
+		err = ring.Revoke(id)
+	}
+	if err != nil {
+		return err
+	}
+	if err := ring.Save(path); err != nil {
+		return err
+	}
+	key, _ := ring.Lookup(id)
+	fmt.Printf("key %s is now %s\n", id, key.State)
+	if _, ok := ring.Active(); !ok {
+		fmt.Fprintln(os.Stderr, "warning: keyring has no active key, encryption will fail until a key is promoted")
+	}
+	return nil
+}
+
+// keyringPasswd меняет парольную фразу набора ключей (или шифрует открытый набор);
+// ключи при этом не меняются
+func keyringPasswd(path string) error {
+	ring, err := loadKeyring(path)
+	if err != nil {
+		return err
+	}
+	passphrase, err := newKeyringPassphrase()
+	if err != nil {
+		return err
+	}
+	ring.SetPassphrase(passphrase)
+	if err := ring.Save(path); err != nil {
+		return err
+	}
+	fmt.Printf("keyring %s passphrase changed, %d key(s) unchanged\n", path, len(ring.Keys))
+	return nil
+}
+
+// loadKeyring читает набор ключей, запрашивая парольную фразу, если он зашифрован
+func loadKeyring(path string) (*config.Keyring, error) {
+	return config.LoadKeyring(path, config.WithPassphraseFunc(keyringPassphrase))
+}
+
+// printKeyring выводит ключи без ключевого материала
+func printKeyring(ring *config.Keyring) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tALGORITHM\tSTATE\tFINGERPRINT\tCREATED\tEXPIRES")
+	now := time.Now()
+	for _, k := range ring.Keys {
+		expires := "-"
+		if k.Expires != nil {
+			expires = k.Expires.Format(time.RFC3339)
+			if k.Expired(now) {
+				expires += " (expired)"
+			}
+		}
+		fingerprint := k.Fingerprint
+		if fingerprint == "" {
+			raw, _ := base64.StdEncoding.DecodeString(k.Key)
+			fingerprint = config.KeyFingerprint(raw)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Algorithm, k.State, fingerprint, k.Created.Format(time.RFC3339), expires)
+	}
+	return w.Flush()
+}
+
+// parseExpiry разбирает срок действия: дату, время RFC 3339 или длительность от from
+func parseExpiry(value string, from time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return from.Add(d), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -expires %q: expected a date, RFC 3339 time or duration", value)
+}
@@ -14,14 +14,19 @@ import (
 )
 
 var (
-	// Ключ шифрования в формате base64 или как есть
-	key = flag.String("key", "", "32-byte encryption key")
+	// Источник ключа шифрования: -key, -key-file, -key-env, -key-fd, -key-helper, -keyring,
+	// -ssh-agent или агент ключей из ENCRYPTOR_AGENT_SOCK
+	keys = addKeyFlags(flag.CommandLine)
 	// Путь к конфигурационному файлу
 	configPath = flag.String("config", "configs/config.default.yml", "path to YAML config file")
 	// Пароли для шифрования (через запятую)
 	passwords = flag.String("passwords", "", "comma-separated list of passwords to encrypt")
 	// Поля в конфигурации (через запятую)
 	fields = flag.String("fields", "", "comma-separated list of fields to update (e.g. redis.password,database.password)")
+	// Формат зашифрованных значений: enc (ENC[...]) или openssl (openssl enc -aes-256-cbc -pbkdf2 -a)
+	format = flag.String("format", "enc", "output format: enc or openssl")
+	// Политика допустимых алгоритмов и ключей (например, fips)
+	policyName = flag.String("policy", "", "enforce a policy on the key and algorithms (e.g. fips)")
 	// Флаг для вывода справки
 	helpFlag = flag.Bool("help", false, "show help message")
 	hFlag    = flag.Bool("h", false, "show help message (shorthand)")
@@ -40,17 +45,58 @@ type UserConfig struct {
 func printUsage() {
 	fmt.Println("Usage examples:")
 	fmt.Println("1. Encrypt multiple passwords:")
-	fmt.Println("   ./encrypt -key=\"your-32-byte-key\" -passwords=\"secret123,password456,key789\"")
+	fmt.Println("   ./encrypt -key-file=\"key.txt\" -passwords=\"secret123,password456,key789\"")
 	fmt.Println("2. Update multiple config fields:")
-	fmt.Println("   ./encrypt -key=\"your-32-byte-key\" -config=\"config.yml\" -fields=\"database.password,redis.password\" -passwords=\"secret123,password456\"")
+	fmt.Println("   ./encrypt -key-file=\"key.txt\" -config=\"config.yml\" -fields=\"database.password,redis.password\" -passwords=\"secret123,password456\"")
+	fmt.Println("3. Convert Ansible Vault values into ENC[...] values and back:")
+	fmt.Println("   ./encrypt vault import -key-file=\"key.txt\" -vault-password-file=\".vault_pass\" secrets.yml")
+	fmt.Println("   ./encrypt vault export -key-file=\"key.txt\" -vault-password-file=\".vault_pass\" secrets.yml")
+	fmt.Println("4. Encrypt in OpenSSL format (decrypt with: openssl enc -d -aes-256-cbc -pbkdf2 -a -pass file:key.txt):")
+	fmt.Println("   ./encrypt -key-file=\"key.txt\" -format=openssl -passwords=\"secret123\"")
+	fmt.Println("5. Show config values that violate the FIPS policy:")
+	fmt.Println("   ./encrypt policy check -policy=fips config.yml")
+	fmt.Println("6. Manage a keyring and encrypt with its active key:")
+	fmt.Println("   ./encrypt keyring add -keyring=\"keys.json\" -id=2026-10 -expires=2160h")
+	fmt.Println("   ./encrypt keyring promote -keyring=\"keys.json\" 2026-10")
+	fmt.Println("   ./encrypt keyring list -keyring=\"keys.json\"")
+	fmt.Println("   ./encrypt -keyring=\"keys.json\" -passwords=\"secret123\"")
+	fmt.Println("7. Re-encrypt every ENC[...] value in config files (or directories) under a new key:")
+	fmt.Println("   ./encrypt rotate -key-file=\"new-key.txt\" -old-key-file=\"key.txt\" configs/")
+	fmt.Println("8. Unlock a keyring once in a key agent and encrypt through it:")
+	fmt.Println("   ./encrypt agent start -keyring=\"keys.json\" -idle=30m")
+	fmt.Println("   ENCRYPTOR_AGENT_SOCK=... ./encrypt -keyring=\"keys.json\" -passwords=\"secret123\"")
+	fmt.Println("9. Encrypt the fields listed in .encryptor.yaml creation rules (keys and algorithm come from the matching rule):")
+	fmt.Println("   ./encrypt encrypt")
+	fmt.Println("   ./encrypt encrypt -dry-run configs/prod.yml")
 	fmt.Println()
-	fmt.Println("How to generate a 32-byte key (base64) with openssl:")
-	fmt.Println("   openssl rand -base64 32")
-	fmt.Println("Use the result as the -key argument.")
+	fmt.Println("How to generate a 32-byte key (base64) straight into a 0600 key file:")
+	fmt.Println("   ./encrypt keygen -out=key.txt")
+	fmt.Println("Pass the file as -key-file,")
+	fmt.Println("or pass it through -key-env/-key-fd. The literal -key flag is visible in shell history and ps.")
+	fmt.Println("Keys from an in-house key service can be fetched by a helper program:")
+	fmt.Println("   ./encrypt -key-helper=\"/usr/local/bin/fetch-key --env prod\" -passwords=\"secret123\"")
+	fmt.Println("Compare keys across machines by fingerprint and KCV, or see which key a value needs:")
+	fmt.Println("   ./encrypt key fingerprint -key-file=key.txt")
+	fmt.Println("   ./encrypt -key-file=key.txt -embed-key-id -passwords=\"secret123\"")
+	fmt.Println("Dual control: generate key components for custodians and assemble the key from all of them:")
+	fmt.Println("   ./encrypt key ceremony -components=2")
+	fmt.Println("   ./encrypt -key-component=prompt:alice,kcv=91F63C -key-component=prompt:bob,kcv=E885D7 -key-kcv=ADAB2D -passwords=\"secret123\"")
+	fmt.Println("Dev secrets can use a key derived from your Ed25519 key in ssh-agent:")
+	fmt.Println("   ./encrypt -ssh-agent -passwords=\"secret123\"")
 	os.Exit(0)
 }
 
 func main() {
+	// Подкоманды (например, vault) обрабатываются отдельно со своим набором флагов
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			os.Exit(0)
+		}
+	}
+
 	flag.Parse()
 
 	configfile.SetDebug(*debugFlag)
@@ -59,13 +105,43 @@ func main() {
 		printUsage()
 	}
 
+	// При обновлении конфига применяется правило .encryptor.yaml для этого файла
+	updateConfig := *configPath != "" && *fields != "" && *passwords != ""
+	var rule *config.CreationRule
+	if updateConfig {
+		var err error
+		if rule, err = projectRule(*configPath); err != nil {
+			log.Fatalf("Failed to load %s: %v", config.ProjectFileName, err)
+		}
+	}
+
 	// Проверяем обязательные параметры
-	if *key == "" {
-		log.Fatal("encryption key is required")
+	if !keys.isSet() && (rule == nil || !rule.HasKey()) {
+		log.Fatal("encryption key is required: use -key-file, -key-env, -key-fd, -key-helper, -key-component, -keyring, -ssh-agent, set ENCRYPTOR_AGENT_SOCK or add a key to " + config.ProjectFileName)
 	}
 
 	// Создаем конфигурацию с ключом шифрования
-	cfg, err := config.NewConfig(*key)
+	var opts []config.Option
+	var policy *config.Policy
+	if *policyName != "" {
+		var err error
+		if policy, err = config.LookupPolicy(*policyName); err != nil {
+			log.Fatalf("Failed to load policy: %v", err)
+		}
+		opts = append(opts, config.WithPolicy(policy))
+	}
+	if *format == "openssl" {
+		// Формат openssl шифрует AES-256-CBC с ключом из PBKDF2, а не алгоритмом
+		// конфигурации: политика проверяет эти параметры
+		if policy != nil {
+			if err := encryption.CheckOpenSSLPolicy(policy); err != nil {
+				log.Fatalf("openssl format: %v", err)
+			}
+		}
+		// В формате openssl ключ - парольная фраза, а не ключ AEAD: строгая проверка к нему не применяется
+		opts = append(opts, config.WithLegacyKey())
+	}
+	cfg, err := keys.ruleConfig(rule, opts...)
 	if err != nil {
 		log.Fatalf("Failed to create config: %v", err)
 	}
@@ -76,8 +152,29 @@ func main() {
 		log.Fatalf("Failed to create encryptor: %v", err)
 	}
 
+	// Выбираем функцию шифрования в зависимости от формата
+	encryptValue := encryptor.EncryptString
+	switch *format {
+	case "enc":
+	case "openssl":
+		if cfg.Key == "" {
+			log.Fatal("openssl format needs a passphrase: use -key-file, -key-env or -key-fd")
+		}
+		// В файл конфигурации значение пишется одной строкой (openssl enc -d -a -A):
+		// перенос строк сделал бы его многострочным скаляром YAML
+		var sslOpts []encryption.OpenSSLOption
+		if updateConfig {
+			sslOpts = append(sslOpts, encryption.WithOpenSSLSingleLine())
+		}
+		encryptValue = func(data string) (string, error) {
+			return encryption.OpenSSLEncryptString(cfg.Key, data, sslOpts...)
+		}
+	default:
+		log.Fatalf("unknown format %q: expected enc or openssl", *format)
+	}
+
 	// Сначала проверяем: если переданы все параметры для обновления конфига — только обновляем файл
-	if *configPath != "" && *fields != "" && *passwords != "" {
+	if updateConfig {
 		fieldList := strings.Split(*fields, ",")
 		for i := range fieldList {
 			fieldList[i] = strings.TrimSpace(fieldList[i])
@@ -92,7 +189,7 @@ func main() {
 		// Шифруем пароли
 		encrypted := make([]string, len(passwordList))
 		for i, pwd := range passwordList {
-			enc, err := encryptor.EncryptString(pwd)
+			enc, err := encryptValue(pwd)
 			if err != nil {
 				log.Fatalf("Failed to encrypt password '%s': %v", pwd, err)
 			}
@@ -113,7 +210,7 @@ func main() {
 			if pwd == "" {
 				continue
 			}
-			encrypted, err := encryptor.EncryptString(pwd)
+			encrypted, err := encryptValue(pwd)
 			if err != nil {
 				log.Printf("Failed to encrypt password '%s': %v", pwd, err)
 				continue
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// newPassphraseEnv переменная окружения с новой парольной фразой для keyring passwd в CI
+const newPassphraseEnv = "ENCRYPTOR_KEYRING_NEW_PASSPHRASE"
+
+// keyringPassphrase возвращает парольную фразу набора ключей из переменной
+// окружения, а если она не задана - запрашивает ее в терминале без эха
+func keyringPassphrase() ([]byte, error) {
+	if value := os.Getenv(config.KeyringPassphraseEnv); value != "" {
+		return []byte(value), nil
+	}
+	return readPassphrase("Keyring passphrase: ")
+}
+
+// newKeyringPassphrase запрашивает новую парольную фразу дважды
+// (или берет ее из переменной окружения newPassphraseEnv)
+func newKeyringPassphrase() ([]byte, error) {
+	if value := os.Getenv(newPassphraseEnv); value != "" {
+		return []byte(value), nil
+	}
+	passphrase, err := readPassphrase("New keyring passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	confirm, err := readPassphrase("Repeat new keyring passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirm) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// readPassphrase читает строку из терминала без эха, подсказка выводится в stderr
+func readPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("%w: stdin is not a terminal, set %s", config.ErrKeyringLocked, config.KeyringPassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/JohnnyFes/go-encryptor/internal/ansiblevault"
+	"github.com/JohnnyFes/go-encryptor/internal/configfile"
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+var errPolicyUsage = errors.New("usage: policy check [-policy=fips] [-key-file=FILE] FILE...")
+
+// opensslBase64Prefix начало base64 от заголовка Salted__ формата openssl enc
+const opensslBase64Prefix = "U2FsdGVkX1"
+
+// runPolicy проверяет значения конфигурационных файлов на соответствие политике
+func runPolicy(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return errPolicyUsage
+	}
+
+	fs := flag.NewFlagSet("policy check", flag.ExitOnError)
+	name := fs.String("policy", "fips", "policy name")
+	keys := addKeyFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errPolicyUsage
+	}
+
+	policy, err := config.LookupPolicy(*name)
+	if err != nil {
+		return err
+	}
+
+	violations := 0
+	if keys.isSet() {
+		cfg, err := keys.config()
+		if err == nil {
+			err = policy.CheckConfig(cfg)
+		}
+		if err != nil {
+			fmt.Printf("key: %v\n", err)
+			violations++
+		}
+	}
+
+	for _, path := range fs.Args() {
+		err := configfile.ScanValues(path, func(field, tag, value string) {
+			if err := checkValuePolicy(policy, tag, value); err != nil {
+				fmt.Printf("%s: %s: %v\n", path, field, err)
+				violations++
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if violations > 0 {
+		return fmt.Errorf("%d violation(s) of policy %s", violations, policy.Name)
+	}
+	fmt.Printf("No violations of policy %s\n", policy.Name)
+	return nil
+}
+
+// checkValuePolicy определяет формат зашифрованного значения и проверяет его алгоритмы
+func checkValuePolicy(policy *config.Policy, tag, value string) error {
+	switch {
+	case tag == "!vault" || ansiblevault.IsEncrypted([]byte(value)):
+		if err := policy.CheckAlgorithm(config.AlgorithmAES256CTRHMAC); err != nil {
+			return err
+		}
+		if err := policy.CheckKDF(config.KDFPBKDF2SHA256, ansiblevault.Iterations); err != nil {
+			return err
+		}
+		return policy.CheckMAC(config.MACHMACSHA256)
+	case encryption.IsEnvelope(value):
+		env, err := encryption.ParseEnvelope(value)
+		if err != nil {
+			return err
+		}
+		return policy.CheckAlgorithm(env.Algorithm)
+	case strings.HasPrefix(value, opensslBase64Prefix):
+		return policy.CheckAlgorithm(config.AlgorithmAES256CBC)
+	}
+	return nil
+}
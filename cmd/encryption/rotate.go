@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/JohnnyFes/go-encryptor/internal/configfile"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+var errRotateUsage = errors.New("usage: rotate -key-file=NEW [-old-key-file=OLD]... [-old-key-env=VAR]... [-old-keyring=FILE]... [-dry-run] FILE|DIR...")
+
+// stringList значение флага, который можно указать несколько раз
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runRotate перешифровывает значения ENC[...] в конфигурационных файлах новым ключом
+func runRotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	keys := addKeyFlags(fs)
+	var oldFiles, oldEnvs, oldKeyrings stringList
+	fs.Var(&oldFiles, "old-key-file", "file with an old key (repeatable)")
+	fs.Var(&oldEnvs, "old-key-env", "environment variable with an old key (repeatable)")
+	fs.Var(&oldKeyrings, "old-keyring", "keyring file with old keys (repeatable)")
+	dryRun := fs.Bool("dry-run", false, "decrypt and re-encrypt in memory only, do not write files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 || !keys.isSet() {
+		return errRotateUsage
+	}
+
+	newEncryptor, err := newEncryptor(keys)
+	if err != nil {
+		return fmt.Errorf("new key: %w", err)
+	}
+
+	// Старые ключи применяются по порядку; новый ключ последним, чтобы значения,
+	// уже зашифрованные им (или ключами его набора), тоже перешифровывались
+	var decryptors []*encryption.Encryptor
+	var legacy []config.Option
+	if *keys.legacy {
+		legacy = append(legacy, config.WithLegacyKey())
+	}
+	for _, path := range oldFiles {
+		cfg, err := config.NewConfigFromSource(&config.FileKeySource{Path: path}, legacy...)
+		if err != nil {
+			return fmt.Errorf("old key %s: %w", path, err)
+		}
+		if decryptors, err = appendEncryptor(decryptors, cfg); err != nil {
+			return fmt.Errorf("old key %s: %w", path, err)
+		}
+	}
+	for _, name := range oldEnvs {
+		cfg, err := config.NewConfigFromSource(&config.EnvKeySource{Name: name}, legacy...)
+		if err != nil {
+			return fmt.Errorf("old key %s: %w", name, err)
+		}
+		if decryptors, err = appendEncryptor(decryptors, cfg); err != nil {
+			return fmt.Errorf("old key %s: %w", name, err)
+		}
+	}
+	for _, path := range oldKeyrings {
+		cfg, err := config.NewKeyringConfig(path, config.WithKeyringPassphrase(keyringPassphrase))
+		if err != nil {
+			return fmt.Errorf("old keyring %s: %w", path, err)
+		}
+		if decryptors, err = appendEncryptor(decryptors, cfg); err != nil {
+			return fmt.Errorf("old keyring %s: %w", path, err)
+		}
+	}
+	decryptors = append(decryptors, newEncryptor)
+
+	decrypt := func(value string) (string, error) {
+		var firstErr error
+		for _, d := range decryptors {
+			plaintext, err := d.DecryptString(value)
+			if err == nil {
+				return plaintext, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return "", firstErr
+	}
+
+	results, err := configfile.Rotate(fs.Args(), decrypt, newEncryptor.EncryptString, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	rotated, failed := 0, 0
+	for _, r := range results {
+		rotated += r.Rotated
+		failed += len(r.Failed)
+		fmt.Printf("%s: %d rotated, %d failed\n", r.Path, r.Rotated, len(r.Failed))
+		for _, f := range r.Failed {
+			if f.Field == "" {
+				fmt.Printf("  %v\n", f.Err)
+			} else {
+				fmt.Printf("  %s: %v\n", f.Field, f.Err)
+			}
+		}
+	}
+
+	verb := "rotated"
+	if *dryRun {
+		verb = "would be rotated (dry run)"
+	}
+	fmt.Printf("%d value(s) in %d file(s) %s, %d failed\n", rotated, len(results), verb, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d value(s) could not be rotated", failed)
+	}
+	return nil
+}
+
+// appendEncryptor создает шифратор по конфигурации и добавляет его к списку
+func appendEncryptor(list []*encryption.Encryptor, cfg *config.Config) ([]*encryption.Encryptor, error) {
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		return list, err
+	}
+	return append(list, enc), nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/JohnnyFes/go-encryptor/internal/configfile"
+)
+
+var errVaultUsage = errors.New("usage: vault import|export -key-file=FILE -vault-password-file=FILE [-vault-id=ID] [-whole-file] FILE...")
+
+// runVault конвертирует значения Ansible Vault в ENC[...] (import) и обратно (export)
+func runVault(args []string) error {
+	if len(args) == 0 || (args[0] != "import" && args[0] != "export") {
+		return errVaultUsage
+	}
+	mode := args[0]
+
+	fs := flag.NewFlagSet("vault "+mode, flag.ExitOnError)
+	keys := addKeyFlags(fs)
+	passwordFile := fs.String("vault-password-file", "", "file containing the Ansible Vault password")
+	vaultID := fs.String("vault-id", "", "vault-id label for exported values (format 1.2)")
+	wholeFile := fs.Bool("whole-file", false, "export: encrypt the whole file with Ansible Vault instead of inline !vault values")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 || *passwordFile == "" {
+		return errVaultUsage
+	}
+
+	raw, err := os.ReadFile(*passwordFile)
+	if err != nil {
+		return fmt.Errorf("failed to read vault password: %w", err)
+	}
+	// Как и ansible-vault, отбрасываем завершающий перевод строки
+	password := []byte(strings.TrimRight(string(raw), "\r\n"))
+
+	encryptor, err := newEncryptor(keys)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range fs.Args() {
+		var count int
+		switch {
+		case mode == "import":
+			count, err = configfile.ImportAnsibleVault(path, password, encryptor.EncryptString)
+		case *wholeFile:
+			count, err = configfile.ExportAnsibleVaultFile(path, password, *vaultID, encryptor.DecryptString)
+		default:
+			count, err = configfile.ExportAnsibleVault(path, password, *vaultID, encryptor.DecryptString)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("%s: %d value(s) converted\n", path, count)
+	}
+	return nil
+}
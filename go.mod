@@ -3,3 +3,10 @@ module github.com/JohnnyFes/go-encryptor
 go 1.21
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	github.com/miekg/pkcs11 v1.1.1
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sys v0.28.0
+	golang.org/x/term v0.27.0
+)
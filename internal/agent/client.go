@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// Client клиент агента ключей
+type Client struct {
+	socket  string
+	timeout time.Duration
+}
+
+// NewClient создает клиента агента по настройкам
+func NewClient(cfg *config.AgentConfig) *Client {
+	c := &Client{socket: cfg.Socket, timeout: cfg.Timeout}
+	if c.timeout == 0 {
+		c.timeout = config.DefaultAgentTimeout
+	}
+	return c
+}
+
+// Call отправляет запрос агенту и возвращает его ответ. Перед подключением
+// проверяются владелец и права сокета.
+func (c *Client) Call(ctx context.Context, req *Request) (*Response, error) {
+	if err := checkSocket(c.socket); err != nil {
+		return nil, fmt.Errorf("%w: %v", config.ErrAgentUnavailable, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", config.ErrAgentUnavailable, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req.Version = ProtocolVersion
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, c.connError(ctx, err)
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	if !scanner.Scan() {
+		err := scanner.Err()
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		return nil, c.connError(ctx, err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("key agent returned invalid JSON: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, remoteError(&resp)
+	}
+	return &resp, nil
+}
+
+// connError оборачивает ошибку обмена с агентом; отмена контекста возвращается как есть
+func (c *Client) connError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("key agent %s: %w", c.socket, err)
+}
+
+// Status возвращает состояние агента
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	resp, err := c.Call(ctx, &Request{Operation: OperationStatus})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == nil {
+		return nil, errors.New("key agent returned no status")
+	}
+	return resp.Status, nil
+}
+
+// Lock блокирует агент
+func (c *Client) Lock(ctx context.Context) error {
+	_, err := c.Call(ctx, &Request{Operation: OperationLock})
+	return err
+}
+
+// Encryptor реализует interfaces.Encryptor, делегируя шифрование агенту ключей
+type Encryptor struct {
+	client *Client
+}
+
+// NewEncryptor создает шифровальщик, работающий через агента
+func NewEncryptor(cfg *config.AgentConfig) *Encryptor {
+	return &Encryptor{client: NewClient(cfg)}
+}
+
+// Encrypt шифрует данные
+func (e *Encryptor) Encrypt(text string) (string, error) {
+	return e.EncryptContext(context.Background(), text)
+}
+
+// Decrypt расшифровывает данные
+func (e *Encryptor) Decrypt(encrypted string) (string, error) {
+	return e.DecryptContext(context.Background(), encrypted)
+}
+
+// EncryptContext шифрует данные ключами агента
+func (e *Encryptor) EncryptContext(ctx context.Context, text string) (string, error) {
+	if !utf8.ValidString(text) {
+		return "", errBinaryData
+	}
+	resp, err := e.client.Call(ctx, &Request{Operation: OperationEncrypt, Data: text})
+	if err != nil {
+		return "", err
+	}
+	return resp.Data, nil
+}
+
+// DecryptContext расшифровывает данные ключами агента
+func (e *Encryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	resp, err := e.client.Call(ctx, &Request{Operation: OperationDecrypt, Data: encrypted})
+	if err != nil {
+		return "", err
+	}
+	return resp.Data, nil
+}
+
+// Provider реализует interfaces.EncryptorProvider для агента ключей.
+// Провайдер с запасным вариантом используется, когда агент выбран автоматически
+// по AgentSockEnv: если агент недоступен или держит другие ключи, шифровальщик
+// создает запасной провайдер.
+type Provider struct {
+	fallback interfaces.EncryptorProvider
+}
+
+// NewProvider создает провайдер шифровальщиков агента
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// NewFallbackProvider создает провайдер, который переходит на fallback,
+// если агент недоступен или не подходит конфигурации
+func NewFallbackProvider(fallback interfaces.EncryptorProvider) *Provider {
+	return &Provider{fallback: fallback}
+}
+
+// ProvideEncryptor предоставляет шифровальщик агента по настройкам cfg.Agent
+func (p *Provider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext предоставляет шифровальщик агента с учетом контекста.
+// Агент должен быть разблокирован, держать набор ключей cfg.KeyringPath
+// (если он задан) и соблюдать ту же политику, что и cfg.Policy.
+func (p *Provider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	agentCfg := cfg.Agent
+	if agentCfg == nil {
+		socket, ok := config.AgentSocket()
+		if !ok {
+			return p.fail(ctx, cfg, fmt.Errorf("%w: key agent is not configured", interfaces.ErrInvalidConfig))
+		}
+		agentCfg = &config.AgentConfig{Socket: socket}
+	}
+
+	enc := NewEncryptor(agentCfg)
+	status, err := enc.client.Status(ctx)
+	if err == nil {
+		err = matchStatus(cfg, status)
+	}
+	if err != nil {
+		return p.fail(ctx, cfg, err)
+	}
+	return enc, nil
+}
+
+// fail возвращает ошибку или шифровальщик запасного провайдера
+func (p *Provider) fail(ctx context.Context, cfg *config.Config, err error) (interfaces.Encryptor, error) {
+	if p.fallback == nil || ctx.Err() != nil {
+		return nil, err
+	}
+	return p.fallback.ProvideEncryptorContext(ctx, cfg)
+}
+
+// matchStatus проверяет, что агент держит ключи, которые ожидает конфигурация
+func matchStatus(cfg *config.Config, status *Status) error {
+	if cfg.KeyringPath != "" {
+		path, err := filepath.Abs(cfg.KeyringPath)
+		if err != nil {
+			return err
+		}
+		if status.Keyring != path {
+			return fmt.Errorf("%w: key agent holds keyring %q, not %q", interfaces.ErrInvalidConfig, status.Keyring, path)
+		}
+	}
+	if cfg.KeyringKeyID != "" {
+		return fmt.Errorf("%w: key agent encrypts with its active key, not %q", interfaces.ErrInvalidConfig, cfg.KeyringKeyID)
+	}
+	if cfg.Policy != nil && status.Policy != cfg.Policy.Name {
+		return fmt.Errorf("%w: key agent does not enforce policy %q", config.ErrPolicyViolation, cfg.Policy.Name)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// errPeer ошибка, если к сокету подключился процесс другого пользователя
+var errPeer = errors.New("connection from another user rejected")
+
+// checkPeer проверяет по SO_PEERCRED, что клиент запущен тем же пользователем
+func checkPeer(conn net.Conn) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err == nil {
+		err = credErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get peer credentials: %w", err)
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: uid %d", errPeer, cred.Uid)
+	}
+	return nil
+}
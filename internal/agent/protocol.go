@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// ProtocolVersion версия протокола агента
+	ProtocolVersion = 1
+	// OperationStatus запрос состояния агента
+	OperationStatus = "status"
+	// OperationEncrypt запрос на шифрование строки
+	OperationEncrypt = "encrypt"
+	// OperationDecrypt запрос на расшифровку значения
+	OperationDecrypt = "decrypt"
+	// OperationLock запрос на немедленную блокировку агента
+	OperationLock = "lock"
+	// maxMessageSize предельный размер одного сообщения (строки JSON)
+	maxMessageSize = 1 << 20
+)
+
+// Request запрос к агенту: один JSON-объект в строке
+type Request struct {
+	Version   int    `json:"version"`
+	Operation string `json:"operation"`
+	Data      string `json:"data,omitempty"`
+}
+
+// Response ответ агента: один JSON-объект в строке.
+// При ошибке заполняются Error и Code, по которому клиент восстанавливает sentinel-ошибку.
+type Response struct {
+	Data   string  `json:"data,omitempty"`
+	Status *Status `json:"status,omitempty"`
+	Error  string  `json:"error,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// Status состояние агента
+type Status struct {
+	// Keyring - абсолютный путь набора ключей, если агент держит набор ключей
+	Keyring string `json:"keyring,omitempty"`
+	// Policy - название политики, которую соблюдает агент
+	Policy string `json:"policy,omitempty"`
+	// PID - идентификатор процесса агента
+	PID int `json:"pid"`
+	// TTL и IdleTimeout - оставшееся время до блокировки в секундах (0 - без ограничения)
+	TTL         int64 `json:"ttl,omitempty"`
+	IdleTimeout int64 `json:"idle_timeout,omitempty"`
+}
+
+// errorCodes коды ошибок протокола; более частные ошибки идут первыми
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{"locked", config.ErrAgentLocked},
+	{"policy_violation", config.ErrPolicyViolation},
+	{"key_revoked", interfaces.ErrKeyRevoked},
+	{"key_expired", interfaces.ErrKeyExpired},
+	{"no_active_key", interfaces.ErrNoActiveKey},
+	{"key_usage_exceeded", interfaces.ErrKeyUsageExceeded},
+	{"tenant_mismatch", interfaces.ErrTenantMismatch},
+	{"invalid_data", interfaces.ErrInvalidData},
+	{"invalid_config", interfaces.ErrInvalidConfig},
+	{"encryption_failed", interfaces.ErrEncryptionFailed},
+	{"decryption_failed", interfaces.ErrDecryptionFailed},
+}
+
+// errBinaryData ошибка для данных, которые не являются текстом UTF-8: JSON
+// протокола заменил бы такие байты, и значение испортилось бы без ошибки
+var errBinaryData = fmt.Errorf("%w: key agent protocol carries UTF-8 text only, binary data cannot pass through the agent", interfaces.ErrInvalidData)
+
+// errorCode возвращает код протокола для ошибки (пусто, если ошибка не из списка)
+func errorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// RemoteError ошибка, которую вернул агент
+type RemoteError struct {
+	Code    string
+	Message string
+	err     error
+}
+
+func (e *RemoteError) Error() string {
+	return "key agent: " + e.Message
+}
+
+// Unwrap возвращает sentinel-ошибку по коду, чтобы работал errors.Is
+func (e *RemoteError) Unwrap() error {
+	return e.err
+}
+
+// remoteError восстанавливает ошибку агента по ответу
+func remoteError(resp *Response) error {
+	e := &RemoteError{Code: resp.Code, Message: resp.Error}
+	for _, c := range errorCodes {
+		if c.code == resp.Code {
+			e.err = c.err
+			break
+		}
+	}
+	return e
+}
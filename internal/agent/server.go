@@ -0,0 +1,258 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// requestTimeout ограничивает время чтения запроса и записи ответа на соединении
+const requestTimeout = time.Minute
+
+// Server агент ключей: держит разблокированный шифровальщик в памяти и выполняет
+// шифрование по запросам через Unix-сокет. По истечении TTL или после простоя
+// дольше IdleTimeout агент блокируется: новые запросы отклоняются, после
+// завершения начатых шифровальщик закрывается (io.Closer), сокет закрывается
+// и Serve возвращает управление. Ключи AEAD при этом не затираются: Go не дает
+// обнулить их копии в памяти, они освобождаются вместе с процессом.
+type Server struct {
+	// TTL - время жизни ключей с момента запуска (0 - без ограничения)
+	TTL time.Duration
+	// IdleTimeout - блокировка после простоя (0 - без ограничения)
+	IdleTimeout time.Duration
+	// Logger - журнал отклоненных подключений (nil - без журнала)
+	Logger *log.Logger
+
+	mu       sync.Mutex
+	enc      interfaces.Encryptor
+	status   Status
+	started  time.Time
+	lastUsed time.Time
+	done     chan struct{}
+	lockOnce sync.Once
+	// inflight - запросы, выполняющиеся шифровальщиком; Lock ждет их перед Close
+	inflight sync.WaitGroup
+	now      func() time.Time
+}
+
+// NewServer создает агент с разблокированным шифровальщиком enc.
+// status описывает ключи агента (набор ключей, политику) для клиентов.
+func NewServer(enc interfaces.Encryptor, status Status) *Server {
+	s := &Server{
+		enc:    enc,
+		status: status,
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}
+	s.status.PID = os.Getpid()
+	s.started = s.now()
+	s.lastUsed = s.started
+	return s
+}
+
+// Serve обслуживает подключения до блокировки агента. Закрывает l при выходе.
+func (s *Server) Serve(l net.Listener) error {
+	go s.expire()
+	go func() {
+		<-s.done
+		l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+			}
+			s.Lock()
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// Lock блокирует агент: отклоняет новые запросы, дожидается начатых,
+// закрывает шифровальщик и останавливает Serve
+func (s *Server) Lock() {
+	s.lockOnce.Do(func() {
+		s.mu.Lock()
+		enc := s.enc
+		s.enc = nil
+		s.mu.Unlock()
+		// Запрос, получивший шифровальщик до блокировки, не должен застать его закрытым
+		s.inflight.Wait()
+		if closer, ok := enc.(io.Closer); ok {
+			closer.Close()
+		}
+		close(s.done)
+	})
+}
+
+// Done закрывается, когда агент заблокирован
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// expire блокирует агент по истечении TTL или простоя
+func (s *Server) expire() {
+	for {
+		deadline, ok := s.deadline()
+		if !ok {
+			return
+		}
+		timer := time.NewTimer(deadline.Sub(s.now()))
+		select {
+		case <-s.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		// За время ожидания могли прийти запросы, продлевающие простой
+		if deadline, _ := s.deadline(); !s.now().Before(deadline) {
+			s.Lock()
+			return
+		}
+	}
+}
+
+// deadline возвращает ближайший момент блокировки; false - ограничений нет
+func (s *Server) deadline() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deadline time.Time
+	if s.TTL > 0 {
+		deadline = s.started.Add(s.TTL)
+	}
+	if s.IdleTimeout > 0 {
+		if idle := s.lastUsed.Add(s.IdleTimeout); deadline.IsZero() || idle.Before(deadline) {
+			deadline = idle
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
+
+// handle обслуживает одно подключение: запросы и ответы по одной строке JSON
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		s.logf("agent: %v", err)
+		return
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	encoder := json.NewEncoder(conn)
+	for {
+		conn.SetDeadline(time.Now().Add(requestTimeout))
+		if !scanner.Scan() {
+			return
+		}
+		var resp *Response
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = &Response{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else {
+			resp = s.serve(&req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// serve выполняет один запрос
+func (s *Server) serve(req *Request) *Response {
+	if req.Version != ProtocolVersion {
+		return &Response{Error: fmt.Sprintf("unsupported protocol version %d", req.Version)}
+	}
+
+	switch req.Operation {
+	case OperationStatus:
+		status, err := s.currentStatus()
+		if err != nil {
+			return errorResponse(err)
+		}
+		return &Response{Status: status}
+	case OperationLock:
+		s.Lock()
+		return &Response{}
+	case OperationEncrypt, OperationDecrypt:
+	default:
+		return &Response{Error: fmt.Sprintf("unknown operation %q", req.Operation)}
+	}
+
+	s.mu.Lock()
+	enc := s.enc
+	s.lastUsed = s.now()
+	if enc != nil {
+		s.inflight.Add(1)
+	}
+	s.mu.Unlock()
+	if enc == nil {
+		return errorResponse(config.ErrAgentLocked)
+	}
+	defer s.inflight.Done()
+
+	var data string
+	var err error
+	if req.Operation == OperationEncrypt {
+		data, err = enc.EncryptContext(context.Background(), req.Data)
+	} else {
+		data, err = enc.DecryptContext(context.Background(), req.Data)
+		if err == nil && !utf8.ValidString(data) {
+			err = errBinaryData
+		}
+	}
+	if err != nil {
+		return errorResponse(err)
+	}
+	return &Response{Data: data}
+}
+
+// currentStatus возвращает состояние агента с оставшимся временем до блокировки
+func (s *Server) currentStatus() (*Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.enc == nil {
+		return nil, config.ErrAgentLocked
+	}
+	status := s.status
+	now := s.now()
+	if s.TTL > 0 {
+		status.TTL = int64(s.started.Add(s.TTL).Sub(now).Seconds())
+	}
+	if s.IdleTimeout > 0 {
+		status.IdleTimeout = int64(s.lastUsed.Add(s.IdleTimeout).Sub(now).Seconds())
+	}
+	return &status, nil
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+	}
+}
+
+// errorResponse формирует ответ с ошибкой и ее кодом протокола
+func errorResponse(err error) *Response {
+	return &Response{Error: err.Error(), Code: errorCode(err)}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// DefaultSocketPath возвращает путь к сокету агента по умолчанию:
+// $XDG_RUNTIME_DIR/go-encryptor/agent.sock или go-encryptor-UID/agent.sock во временном каталоге
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "go-encryptor", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("go-encryptor-%d", os.Getuid()), "agent.sock")
+}
+
+// Listen создает сокет агента. Каталог сокета создается с правами 0700 и должен
+// принадлежать текущему пользователю, сам сокет получает права 0600.
+// Оставшийся от упавшего агента сокет удаляется; если агент уже слушает сокет,
+// возвращается ошибка.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create agent socket directory: %w", err)
+	}
+	if err := checkPrivate(dir, true); err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("an agent is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale agent socket: %w", err)
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to restrict agent socket permissions: %w", err)
+	}
+	return l, nil
+}
+
+// checkSocket проверяет перед подключением, что сокет принадлежит текущему
+// пользователю и недоступен группе и остальным: иначе ответы мог бы подменить
+// чужой процесс
+func checkSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s is not a socket", path)
+	}
+	if err := checkPrivate(path, false); err != nil {
+		return err
+	}
+	return checkPrivate(filepath.Dir(path), true)
+}
+
+// checkPrivate проверяет владельца и права файла или каталога.
+// Для каталога запрещена только запись группе и остальным.
+func checkPrivate(path string, dir bool) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if uid, ok := fileOwner(info); ok && uid != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not by the current user", path, uid)
+	}
+	mask := os.FileMode(0o077)
+	if dir {
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", path)
+		}
+		mask = 0o022
+	}
+	if info.Mode().Perm()&mask != 0 {
+		return fmt.Errorf("%s is accessible by group or others (mode %04o)", path, info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package agent
+
+import "os"
+
+// fileOwner на этой платформе недоступен: остаются проверки прав доступа
+func fileOwner(os.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,187 @@
+package ansiblevault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// HeaderPrefix префикс заголовка файла Ansible Vault
+	HeaderPrefix = "$ANSIBLE_VAULT"
+	// CipherAES256 единственный шифр, поддерживаемый форматом 1.1/1.2
+	CipherAES256 = "AES256"
+	// Iterations число итераций PBKDF2-SHA256 при получении ключей
+	Iterations = 10000
+
+	saltLength = 32
+	keyLength  = 32
+	ivLength   = aes.BlockSize
+	lineWidth  = 80
+)
+
+var (
+	// ErrInvalidFormat ошибка при неверном формате данных Ansible Vault
+	ErrInvalidFormat = errors.New("invalid ansible vault format")
+	// ErrUnsupportedCipher ошибка при неподдерживаемом шифре или версии
+	ErrUnsupportedCipher = errors.New("unsupported ansible vault cipher")
+	// ErrHMACMismatch ошибка при неверном пароле или поврежденных данных
+	ErrHMACMismatch = errors.New("ansible vault HMAC mismatch: wrong password or corrupted data")
+)
+
+// IsEncrypted проверяет, являются ли данные зашифрованными Ansible Vault
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(HeaderPrefix+";"))
+}
+
+// Decrypt расшифровывает данные в формате $ANSIBLE_VAULT;1.1;AES256 (или 1.2 с vault-id).
+// Возвращает открытый текст и метку vault-id, если она указана в заголовке.
+func Decrypt(data, password []byte) ([]byte, string, error) {
+	text := strings.TrimSpace(string(data))
+	lines := strings.Split(text, "\n")
+	header := strings.Split(strings.TrimSpace(lines[0]), ";")
+	if len(header) < 3 || header[0] != HeaderPrefix {
+		return nil, "", ErrInvalidFormat
+	}
+	if header[1] != "1.1" && header[1] != "1.2" {
+		return nil, "", fmt.Errorf("%w: version %s", ErrUnsupportedCipher, header[1])
+	}
+	if strings.TrimSpace(header[2]) != CipherAES256 {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedCipher, header[2])
+	}
+	var vaultID string
+	if header[1] == "1.2" && len(header) > 3 {
+		vaultID = strings.TrimSpace(header[3])
+	}
+
+	// Тело: hex от "hex(salt)\nhex(hmac)\nhex(ciphertext)", разбитое на строки
+	var body strings.Builder
+	for _, l := range lines[1:] {
+		body.WriteString(strings.TrimSpace(l))
+	}
+	inner, err := hex.DecodeString(body.String())
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	parts := bytes.Split(inner, []byte("\n"))
+	if len(parts) != 3 {
+		return nil, "", ErrInvalidFormat
+	}
+	salt, err := hex.DecodeString(string(parts[0]))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: salt: %v", ErrInvalidFormat, err)
+	}
+	mac, err := hex.DecodeString(string(parts[1]))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: hmac: %v", ErrInvalidFormat, err)
+	}
+	ciphertext, err := hex.DecodeString(string(parts[2]))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: ciphertext: %v", ErrInvalidFormat, err)
+	}
+
+	cipherKey, macKey, iv := deriveKeys(password, salt)
+
+	// Проверяем HMAC до расшифровки
+	h := hmac.New(sha256.New, macKey)
+	h.Write(ciphertext)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		return nil, "", ErrHMACMismatch
+	}
+
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+	padded := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(padded, ciphertext)
+
+	plaintext, err := unpad(padded)
+	if err != nil {
+		return nil, "", err
+	}
+	return plaintext, vaultID, nil
+}
+
+// Encrypt шифрует данные в формат Ansible Vault.
+// Если vaultID не пустой, используется формат 1.2 с меткой, иначе 1.1.
+func Encrypt(plaintext, password []byte, vaultID string) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	cipherKey, macKey, iv := deriveKeys(password, salt)
+
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	padded := pad(plaintext)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, padded)
+
+	h := hmac.New(sha256.New, macKey)
+	h.Write(ciphertext)
+
+	inner := strings.Join([]string{
+		hex.EncodeToString(salt),
+		hex.EncodeToString(h.Sum(nil)),
+		hex.EncodeToString(ciphertext),
+	}, "\n")
+	body := hex.EncodeToString([]byte(inner))
+
+	var out strings.Builder
+	if vaultID != "" {
+		fmt.Fprintf(&out, "%s;1.2;%s;%s\n", HeaderPrefix, CipherAES256, vaultID)
+	} else {
+		fmt.Fprintf(&out, "%s;1.1;%s\n", HeaderPrefix, CipherAES256)
+	}
+	for len(body) > lineWidth {
+		out.WriteString(body[:lineWidth])
+		out.WriteByte('\n')
+		body = body[lineWidth:]
+	}
+	out.WriteString(body)
+	out.WriteByte('\n')
+	return []byte(out.String()), nil
+}
+
+// deriveKeys получает ключ шифрования, ключ HMAC и IV из пароля (PBKDF2-SHA256)
+func deriveKeys(password, salt []byte) (cipherKey, macKey, iv []byte) {
+	derived := pbkdf2.Key(password, salt, Iterations, 2*keyLength+ivLength, sha256.New)
+	return derived[:keyLength], derived[keyLength : 2*keyLength], derived[2*keyLength:]
+}
+
+// pad дополняет данные по PKCS#7 до размера блока AES
+func pad(data []byte) []byte {
+	n := aes.BlockSize - len(data)%aes.BlockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// unpad удаляет дополнение PKCS#7
+func unpad(data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: bad padding", ErrInvalidFormat)
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize {
+		return nil, fmt.Errorf("%w: bad padding", ErrInvalidFormat)
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, fmt.Errorf("%w: bad padding", ErrInvalidFormat)
+		}
+	}
+	return data[:len(data)-n], nil
+}
@@ -72,10 +72,7 @@ func UpdateConfigFile(configPath string, fields, values []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
-	if err := os.WriteFile(configPath, out, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-	return nil
+	return writeFile(configPath, out)
 }
 
 // setNestedField устанавливает значение вложенного поля по пути вида "a.b.c"
@@ -0,0 +1,52 @@
+package configfile
+
+import "gopkg.in/yaml.v3"
+
+// EncryptFile шифрует на месте значения YAML/JSON файла, для путей которых
+// shouldEncrypt возвращает true. Уже зашифрованные значения ENC[...], значения
+// !vault и null пропускаются; числа и логические значения становятся строками ENC[...].
+// Возвращает количество зашифрованных значений; при dryRun файл не изменяется.
+func EncryptFile(configPath string, shouldEncrypt func(field string) bool, encrypt func(string) (string, error), dryRun bool) (int, error) {
+	doc, err := readYAMLNode(configPath)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := walkScalars(doc, "", func(field string, n *yaml.Node) (bool, error) {
+		if n.Tag == vaultTag || n.ShortTag() == "!!null" || isEncryptedValue(n.Value) || !shouldEncrypt(field) {
+			return false, nil
+		}
+		encrypted, err := encrypt(n.Value)
+		if err != nil {
+			return false, err
+		}
+		setStringValue(n, encrypted)
+		return true, nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if dryRun || count == 0 {
+		return count, nil
+	}
+
+	debugPrint("[DEBUG] Encrypted %d values in %s\n", count, configPath)
+	if isJSONPath(configPath) {
+		return count, writeJSONNode(configPath, doc)
+	}
+	return count, writeYAMLNode(configPath, doc)
+}
+
+// ConfigFiles возвращает YAML и JSON файлы из paths; каталоги обходятся рекурсивно,
+// скрытые каталоги пропускаются
+func ConfigFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		found, err := configFiles(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+	return files, nil
+}
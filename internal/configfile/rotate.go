@@ -0,0 +1,218 @@
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RotateFailure значение, которое не удалось перешифровать
+type RotateFailure struct {
+	// Field - путь к значению вида "a.b[0].c"
+	Field string
+	// Err - причина
+	Err error
+}
+
+// RotateResult итог перешифрования одного файла
+type RotateResult struct {
+	// Path - путь к файлу
+	Path string
+	// Rotated - число перешифрованных значений
+	Rotated int
+	// Failed - значения, оставшиеся без изменений из-за ошибки
+	Failed []RotateFailure
+}
+
+// RotateFile перешифровывает все значения ENC[...] YAML/JSON файла: каждое
+// расшифровывается decrypt (старым ключом) и шифруется encrypt (новым).
+// Значение, которое не удалось перешифровать, остается как есть и попадает
+// в Failed, остальные значения при этом все равно перешифровываются.
+// Файл записывается атомарно; при dryRun файл не изменяется.
+func RotateFile(configPath string, decrypt, encrypt func(string) (string, error), dryRun bool) (*RotateResult, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	doc, err := parseYAMLNode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RotateResult{Path: configPath}
+	result.Rotated, err = walkScalars(doc, "", func(field string, n *yaml.Node) (bool, error) {
+		if !isStringScalar(n) || !isEncryptedValue(n.Value) {
+			return false, nil
+		}
+		plaintext, err := decrypt(n.Value)
+		if err != nil {
+			result.Failed = append(result.Failed, RotateFailure{Field: field, Err: fmt.Errorf("decrypt: %w", err)})
+			return false, nil
+		}
+		encrypted, err := encrypt(plaintext)
+		if err != nil {
+			result.Failed = append(result.Failed, RotateFailure{Field: field, Err: fmt.Errorf("encrypt: %w", err)})
+			return false, nil
+		}
+		n.Value = encrypted
+		return true, nil
+	})
+	if err != nil {
+		return result, err
+	}
+	if dryRun || result.Rotated == 0 {
+		return result, nil
+	}
+
+	if isJSONPath(configPath) {
+		err = writeJSONNode(configPath, doc)
+	} else {
+		err = writeYAMLNode(configPath, doc)
+	}
+	return result, err
+}
+
+// Rotate перешифровывает значения ENC[...] в файлах и каталогах paths.
+// В каталогах рекурсивно обрабатываются файлы .yaml, .yml и .json
+// (скрытые каталоги, например .git, пропускаются). Ошибка чтения или записи
+// отдельного файла попадает в его RotateResult.Failed с пустым Field
+// и не прерывает обработку остальных файлов.
+func Rotate(paths []string, decrypt, encrypt func(string) (string, error), dryRun bool) ([]*RotateResult, error) {
+	files, err := ConfigFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*RotateResult, 0, len(files))
+	for _, f := range files {
+		result, err := RotateFile(f, decrypt, encrypt, dryRun)
+		if err != nil {
+			if result == nil {
+				result = &RotateResult{Path: f}
+			}
+			result.Failed = append(result.Failed, RotateFailure{Err: err})
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// configFiles возвращает path, если это файл, или конфигурационные файлы каталога
+func configFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isConfigPath(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// isConfigPath проверяет, что файл по расширению является YAML или JSON
+func isConfigPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// isJSONPath проверяет, что файл по расширению является JSON
+func isJSONPath(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".json"
+}
+
+// writeJSONNode сохраняет дерево узлов в файл как JSON, сохраняя порядок ключей
+func writeJSONNode(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	if err := encodeJSONNode(&buf, doc); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	out.WriteByte('\n')
+	return writeFile(path, out.Bytes())
+}
+
+// encodeJSONNode кодирует узел YAML, разобранный из JSON, обратно в компактный JSON
+func encodeJSONNode(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeJSONNode(buf, n.Content[0])
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 1; i < len(n.Content); i += 2 {
+			if i > 1 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, n.Content[i-1].Value)
+			buf.WriteByte(':')
+			if err := encodeJSONNode(buf, n.Content[i]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSONNode(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			buf.WriteString(n.Value)
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			writeJSONString(buf, n.Value)
+		}
+	default:
+		return fmt.Errorf("unsupported YAML node kind %d in JSON document", n.Kind)
+	}
+	return nil
+}
+
+// writeJSONString записывает строку JSON без экранирования HTML-символов
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	// Encode добавляет перевод строки
+	buf.Truncate(buf.Len() - 1)
+}
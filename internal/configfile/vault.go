@@ -0,0 +1,145 @@
+package configfile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/JohnnyFes/go-encryptor/internal/ansiblevault"
+)
+
+// vaultTag тег YAML для встроенных значений Ansible Vault
+const vaultTag = "!vault"
+
+// ImportAnsibleVault преобразует данные Ansible Vault в значения ENC[...] на месте.
+// Если весь файл зашифрован Ansible Vault, он расшифровывается, и шифруются все
+// строковые значения документа. Иначе заменяются только значения с тегом !vault.
+// Возвращает количество преобразованных значений.
+func ImportAnsibleVault(configPath string, password []byte, encrypt func(string) (string, error)) (int, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc *yaml.Node
+	wholeFile := ansiblevault.IsEncrypted(data)
+	if wholeFile {
+		plaintext, _, err := ansiblevault.Decrypt(data, password)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt vault file: %w", err)
+		}
+		data = plaintext
+	}
+	if doc, err = parseYAMLNode(data); err != nil {
+		return 0, err
+	}
+
+	count, err := walkScalars(doc, "", func(_ string, n *yaml.Node) (bool, error) {
+		var value string
+		switch {
+		case n.Tag == vaultTag:
+			plaintext, _, err := ansiblevault.Decrypt([]byte(n.Value), password)
+			if err != nil {
+				return false, err
+			}
+			value = string(plaintext)
+		case wholeFile && isStringScalar(n) && !isEncryptedValue(n.Value):
+			value = n.Value
+		default:
+			return false, nil
+		}
+		encrypted, err := encrypt(value)
+		if err != nil {
+			return false, err
+		}
+		setStringValue(n, encrypted)
+		return true, nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	debugPrint("[DEBUG] Imported %d vault values into %s\n", count, configPath)
+	return count, writeYAMLNode(configPath, doc)
+}
+
+// ExportAnsibleVault заменяет значения ENC[...] на встроенные значения !vault.
+// vaultID задает метку vault-id (формат 1.2), пустая строка — формат 1.1.
+// Возвращает количество преобразованных значений.
+func ExportAnsibleVault(configPath string, password []byte, vaultID string, decrypt func(string) (string, error)) (int, error) {
+	doc, err := readYAMLNode(configPath)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := walkScalars(doc, "", func(_ string, n *yaml.Node) (bool, error) {
+		if !isStringScalar(n) || !isEncryptedValue(n.Value) {
+			return false, nil
+		}
+		plaintext, err := decrypt(n.Value)
+		if err != nil {
+			return false, err
+		}
+		vault, err := ansiblevault.Encrypt([]byte(plaintext), password, vaultID)
+		if err != nil {
+			return false, err
+		}
+		n.Tag = vaultTag
+		n.Style = yaml.LiteralStyle
+		n.Value = string(vault)
+		return true, nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, writeYAMLNode(configPath, doc)
+}
+
+// ExportAnsibleVaultFile расшифровывает все значения ENC[...] и шифрует весь файл
+// целиком в формате Ansible Vault. Возвращает количество расшифрованных значений.
+func ExportAnsibleVaultFile(configPath string, password []byte, vaultID string, decrypt func(string) (string, error)) (int, error) {
+	doc, err := readYAMLNode(configPath)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := walkScalars(doc, "", func(_ string, n *yaml.Node) (bool, error) {
+		if !isStringScalar(n) || !isEncryptedValue(n.Value) {
+			return false, nil
+		}
+		plaintext, err := decrypt(n.Value)
+		if err != nil {
+			return false, err
+		}
+		setStringValue(n, plaintext)
+		return true, nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	plaintext, err := yaml.Marshal(doc)
+	if err != nil {
+		return count, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	vault, err := ansiblevault.Encrypt(plaintext, password, vaultID)
+	if err != nil {
+		return count, err
+	}
+	return count, writeFile(configPath, vault)
+}
+
+// isEncryptedValue проверяет, что значение имеет формат ENC[...]
+func isEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, "ENC[") && strings.HasSuffix(value, "]")
+}
+
+// setStringValue записывает в узел обычное строковое значение
+func setStringValue(n *yaml.Node, value string) {
+	n.Tag = "!!str"
+	n.Style = 0
+	n.Value = value
+}
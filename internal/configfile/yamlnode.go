@@ -0,0 +1,153 @@
+package configfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readYAMLNode читает YAML-документ в виде дерева узлов, сохраняя теги и комментарии
+func readYAMLNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseYAMLNode(data)
+}
+
+// parseYAMLNode разбирает YAML-документ в дерево узлов
+func parseYAMLNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &doc, nil
+}
+
+// writeYAMLNode сохраняет дерево узлов обратно в файл
+func writeYAMLNode(path string, doc *yaml.Node) error {
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return writeFile(path, out)
+}
+
+// writeFile атомарно записывает данные конфигурации в файл: через временный файл
+// в том же каталоге и rename, так что при сбое остается прежнее содержимое.
+// Права существующего файла сохраняются, новый файл создается с 0644.
+func writeFile(path string, data []byte) error {
+	// Для символической ссылки заменяем файл, на который она указывает, а не саму ссылку
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// walkScalars обходит все скалярные значения документа (ключи map пропускаются).
+// fn получает путь к значению вида "a.b[0].c" и возвращает true, если узел был изменен;
+// возвращается число измененных узлов.
+func walkScalars(n *yaml.Node, path string, fn func(path string, n *yaml.Node) (bool, error)) (int, error) {
+	if n == nil {
+		return 0, nil
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		changed, err := fn(path, n)
+		if err != nil {
+			if path != "" {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+			return 0, err
+		}
+		if !changed {
+			return 0, nil
+		}
+		return 1, nil
+	case yaml.MappingNode:
+		total := 0
+		for i := 1; i < len(n.Content); i += 2 {
+			key := n.Content[i-1].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			c, err := walkScalars(n.Content[i], key, fn)
+			total += c
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	case yaml.SequenceNode:
+		total := 0
+		for i, child := range n.Content {
+			c, err := walkScalars(child, fmt.Sprintf("%s[%d]", path, i), fn)
+			total += c
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	case yaml.DocumentNode:
+		total := 0
+		for _, child := range n.Content {
+			c, err := walkScalars(child, path, fn)
+			total += c
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	}
+	return 0, nil
+}
+
+// ScanValues вызывает fn для каждого скалярного значения YAML/JSON файла
+// с путем к значению, тегом YAML и самим значением
+func ScanValues(configPath string, fn func(path, tag, value string)) error {
+	doc, err := readYAMLNode(configPath)
+	if err != nil {
+		return err
+	}
+	_, err = walkScalars(doc, "", func(path string, n *yaml.Node) (bool, error) {
+		fn(path, n.Tag, n.Value)
+		return false, nil
+	})
+	return err
+}
+
+// isStringScalar проверяет, что узел является строковым скаляром
+func isStringScalar(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && (n.Tag == "!!str" || n.ShortTag() == "!!str")
+}
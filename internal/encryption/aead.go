@@ -0,0 +1,285 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// DefaultAlgorithm алгоритм шифрования по умолчанию (AES-256-GCM)
+	DefaultAlgorithm = "AES256"
+	// AttrTenant атрибут конверта с идентификатором арендатора
+	AttrTenant = "tenant"
+	// AttrKeyID атрибут конверта с идентификатором ключа у провайдера:
+	// ID ключа в наборе ключей, имя/vN ключа Transit, отпечаток SSH-ключа
+	AttrKeyID = "kid"
+	// AttrKeyFingerprint атрибут конверта с отпечатком ключа (config.KeyFingerprint);
+	// не зависит от провайдера, поэтому по нему ключ находится и в наборе ключей
+	AttrKeyFingerprint = "kfp"
+	// tenantInfoPrefix контекст HKDF для подключей арендаторов
+	tenantInfoPrefix = "go-encryptor/tenant/"
+)
+
+// AEADEncryptor реализует шифрование данных алгоритмом AEAD (по умолчанию AES-256-GCM).
+// Структура содержит ключ и экземпляр AEAD, который используется для:
+// - Шифрования чувствительных данных в конфигурации
+// - Расшифровки данных при загрузке конфигурации
+// - Обеспечения безопасности паролей и других конфиденциальных данных
+// Расшифровка выбирает алгоритм по заголовку конверта ENC[ALG:...].
+type AEADEncryptor struct {
+	alg  Algorithm
+	aead cipher.AEAD
+	key  []byte
+	// master - мастер-ключ, из которого получаются подключи арендаторов
+	master []byte
+	// tenant - арендатор, для которого получен ключ (пусто для мастер-ключа)
+	tenant string
+	// embedKeyID - записывать отпечаток мастер-ключа в конверт (kfp)
+	embedKeyID bool
+}
+
+// NewEncryptor создает новый экземпляр шифровальщика AES-256-GCM
+func NewEncryptor(key string) (*AEADEncryptor, error) {
+	return NewEncryptorWithAlgorithm(key, DefaultAlgorithm)
+}
+
+// NewEncryptorWithAlgorithm создает шифровальщик для указанного алгоритма из реестра
+func NewEncryptorWithAlgorithm(key, algorithm string) (*AEADEncryptor, error) {
+	if strings.HasPrefix(key, "ENC[") {
+		return nil, fmt.Errorf("encryption key cannot be encrypted")
+	}
+	alg, ok := LookupAlgorithm(algorithm)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidConfig, algorithm)
+	}
+	return newAEADEncryptor(alg, NormalizeKey(key, alg.KeySize))
+}
+
+// NewEncryptorFromKey создает шифровальщик из ключа, длина которого
+// в точности равна размеру ключа алгоритма
+func NewEncryptorFromKey(key []byte, algorithm string) (*AEADEncryptor, error) {
+	alg, ok := LookupAlgorithm(algorithm)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidConfig, algorithm)
+	}
+	if len(key) != alg.KeySize {
+		return nil, fmt.Errorf("%w: %s needs a %d-byte key, got %d bytes", interfaces.ErrInvalidConfig, alg.Name, alg.KeySize, len(key))
+	}
+	return newAEADEncryptor(alg, append([]byte(nil), key...))
+}
+
+// newAEADEncryptor создает шифровальщик из ключа нужной длины
+func newAEADEncryptor(alg Algorithm, key []byte) (*AEADEncryptor, error) {
+	aead, err := alg.NewAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &AEADEncryptor{
+		alg:    alg,
+		aead:   aead,
+		key:    key,
+		master: key,
+	}, nil
+}
+
+// DecodeKey декодирует ключ из base64, а если это не base64 — возвращает его байты как есть
+func DecodeKey(key string) []byte {
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		// Если не получилось, используем ключ как есть
+		keyBytes = []byte(key)
+	}
+	return keyBytes
+}
+
+// NormalizeKey приводит ключ к длине size: декодирует его, хэширует слишком длинный
+// ключ SHA-256 и дополняет нулями слишком короткий
+func NormalizeKey(key string, size int) []byte {
+	keyBytes := DecodeKey(key)
+
+	// Если ключ длиннее нужного, используем SHA-256 для получения ключа нужной длины
+	if len(keyBytes) > size {
+		hash := sha256.Sum256(keyBytes)
+		keyBytes = hash[:]
+	}
+
+	// Если ключ короче нужного, дополняем его нулями
+	if len(keyBytes) < size {
+		newKey := make([]byte, size)
+		copy(newKey, keyBytes)
+		keyBytes = newKey
+	}
+
+	return keyBytes[:size]
+}
+
+// Algorithm возвращает идентификатор алгоритма шифрования
+func (e *AEADEncryptor) Algorithm() string {
+	return e.alg.Name
+}
+
+// KeyID возвращает идентификатор ключа - его отпечаток (config.KeyFingerprint).
+// По идентификатору нельзя восстановить ключ, но он стабилен между запусками.
+func (e *AEADEncryptor) KeyID() string {
+	return config.KeyFingerprint(e.key)
+}
+
+// EmbedKeyID включает запись отпечатка ключа в конверт (атрибут kfp).
+// Для подключей арендаторов записывается отпечаток мастер-ключа.
+func (e *AEADEncryptor) EmbedKeyID() {
+	e.embedKeyID = true
+}
+
+// masterKeyID возвращает отпечаток мастер-ключа, который записывается в конверт
+func (e *AEADEncryptor) masterKeyID() string {
+	return config.KeyFingerprint(e.master)
+}
+
+// ForTenant возвращает шифровальщик с подключом арендатора, полученным из
+// мастер-ключа через HKDF-SHA256. Идентификатор арендатора записывается в конверт
+// и проверяется при расшифровке.
+func (e *AEADEncryptor) ForTenant(tenantID string) (interfaces.Encryptor, error) {
+	if tenantID == "" {
+		return nil, fmt.Errorf("%w: empty tenant ID", interfaces.ErrInvalidConfig)
+	}
+	key := make([]byte, e.alg.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, e.master, nil, []byte(tenantInfoPrefix+tenantID)), key); err != nil {
+		return nil, fmt.Errorf("failed to derive tenant key: %w", err)
+	}
+	derived, err := newAEADEncryptor(e.alg, key)
+	if err != nil {
+		return nil, err
+	}
+	derived.master = e.master
+	derived.tenant = tenantID
+	derived.embedKeyID = e.embedKeyID
+	return derived, nil
+}
+
+// Encrypt шифрует данные
+func (e *AEADEncryptor) Encrypt(plaintext string) (string, error) {
+	env := &Envelope{Algorithm: e.alg.Name, Attrs: map[string]string{}}
+	if e.tenant != "" {
+		env.Attrs[AttrTenant] = e.tenant
+	}
+	if e.embedKeyID {
+		env.Attrs[AttrKeyFingerprint] = e.masterKeyID()
+	}
+	if err := SealEnvelope(env, e.aead, []byte(plaintext)); err != nil {
+		return "", err
+	}
+	return env.String(), nil
+}
+
+// Decrypt расшифровывает данные
+func (e *AEADEncryptor) Decrypt(encrypted string) (string, error) {
+	env, err := ParseEnvelope(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if tenant := env.Attrs[AttrTenant]; tenant != e.tenant {
+		return "", fmt.Errorf("%w: data belongs to tenant %q, encryptor is for tenant %q",
+			interfaces.ErrTenantMismatch, tenant, e.tenant)
+	}
+	// Отпечаток в конверте отличает чужой ключ от поврежденных данных.
+	// kid (например, ID ключа в наборе ключей) не сверяется: это имя ключа
+	// у другого провайдера, и тот же ключ может быть передан напрямую.
+	if kfp, ok := env.Attrs[AttrKeyFingerprint]; ok && kfp != e.masterKeyID() {
+		return "", &interfaces.KeyMismatchError{Expected: kfp, Actual: e.masterKeyID()}
+	}
+
+	aead, err := e.aeadFor(env.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := OpenEnvelope(env, aead)
+	if err != nil {
+		return "", fmt.Errorf("%w: wrong key or corrupted data: %v", interfaces.ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+// EncryptContext шифрует данные, если контекст еще не отменен
+func (e *AEADEncryptor) EncryptContext(ctx context.Context, plaintext string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return e.Encrypt(plaintext)
+}
+
+// DecryptContext расшифровывает данные, если контекст еще не отменен
+func (e *AEADEncryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return e.Decrypt(encrypted)
+}
+
+// SealEnvelope шифрует данные и записывает nonce||ciphertext в base64 в конверт.
+// Атрибуты конверта должны быть заданы заранее: заголовок аутентифицируется.
+func SealEnvelope(env *Envelope, aead cipher.AEAD, plaintext []byte) error {
+	// Создаем nonce
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// Шифруем данные, заголовок конверта аутентифицируется
+	ciphertext := aead.Seal(nonce, nonce, plaintext, env.AdditionalData())
+
+	// Кодируем в base64
+	env.Payload = base64.StdEncoding.EncodeToString(ciphertext)
+	return nil
+}
+
+// OpenEnvelope расшифровывает данные конверта
+func OpenEnvelope(env *Envelope, aead cipher.AEAD) ([]byte, error) {
+	// Декодируем base64
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64: %w", err)
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	// Извлекаем nonce
+	nonce := ciphertext[:aead.NonceSize()]
+	ciphertext = ciphertext[aead.NonceSize():]
+
+	// Расшифровываем данные
+	plaintext, err := aead.Open(nil, nonce, ciphertext, env.AdditionalData())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// aeadFor возвращает AEAD для алгоритма из заголовка конверта
+func (e *AEADEncryptor) aeadFor(name string) (cipher.AEAD, error) {
+	if name == e.alg.Name {
+		return e.aead, nil
+	}
+	alg, ok := LookupAlgorithm(name)
+	if !ok || alg.KeySize != len(e.key) {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidData, name)
+	}
+	aead, err := alg.NewAEAD(e.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
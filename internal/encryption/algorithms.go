@@ -0,0 +1,136 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithm описывает зарегистрированный алгоритм AEAD
+type Algorithm struct {
+	// Name - идентификатор алгоритма в конверте ENC[...]
+	Name string
+	// KeySize - требуемая длина ключа в байтах
+	KeySize int
+	// NewAEAD создает экземпляр AEAD для ключа
+	NewAEAD func(key []byte) (cipher.AEAD, error)
+	// KAT - опубликованный тестовый вектор для самопроверки
+	KAT KnownAnswer
+}
+
+// KnownAnswer тестовый вектор AEAD, все значения в hex
+type KnownAnswer struct {
+	// Source - происхождение вектора
+	Source         string
+	Key            string
+	Nonce          string
+	Plaintext      string
+	AdditionalData string
+	// Ciphertext - шифротекст вместе с тегом аутентификации
+	Ciphertext string
+}
+
+// algorithms реестр поддерживаемых алгоритмов. Размеры ключей повторены
+// в config.GenerateKey; новый алгоритм нужно добавить и туда.
+var algorithms = map[string]Algorithm{
+	"AES256": {
+		Name:    "AES256",
+		KeySize: 32,
+		NewAEAD: func(key []byte) (cipher.AEAD, error) {
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return nil, err
+			}
+			return cipher.NewGCM(block)
+		},
+		KAT: KnownAnswer{
+			Source:     "GCM spec (McGrew, Viega), test case 14",
+			Key:        "0000000000000000000000000000000000000000000000000000000000000000",
+			Nonce:      "000000000000000000000000",
+			Plaintext:  "00000000000000000000000000000000",
+			Ciphertext: "cea7403d4d606b6e074ec5d3baf39d18d0d1c8a799996bf0265b98b5d48ab919",
+		},
+	},
+	"CHACHA20": {
+		Name:    "CHACHA20",
+		KeySize: chacha20poly1305.KeySize,
+		NewAEAD: chacha20poly1305.New,
+		KAT: KnownAnswer{
+			Source:         "RFC 8439, section 2.8.2",
+			Key:            "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+			Nonce:          "070000004041424344454647",
+			Plaintext:      "4c616469657320616e642047656e746c656d656e206f662074686520636c617373206f66202739393a204966204920636f756c64206f6666657220796f75206f6e6c79206f6e652074697020666f7220746865206675747572652c2073756e73637265656e20776f756c642062652069742e",
+			AdditionalData: "50515253c0c1c2c3c4c5c6c7",
+			Ciphertext:     "d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b61161ae10b594f09e26a7e902ecbd0600691",
+		},
+	},
+}
+
+// LookupAlgorithm возвращает алгоритм по идентификатору
+func LookupAlgorithm(name string) (Algorithm, bool) {
+	alg, ok := algorithms[name]
+	return alg, ok
+}
+
+// Algorithms возвращает отсортированный список идентификаторов зарегистрированных алгоритмов
+func Algorithms() []string {
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunKnownAnswerTest шифрует и расшифровывает тестовый вектор алгоритма
+// и сравнивает результат с опубликованным значением
+func RunKnownAnswerTest(alg Algorithm) error {
+	var key, nonce, plaintext, aad, want []byte
+	for _, f := range []struct {
+		dst *[]byte
+		src string
+	}{
+		{&key, alg.KAT.Key},
+		{&nonce, alg.KAT.Nonce},
+		{&plaintext, alg.KAT.Plaintext},
+		{&aad, alg.KAT.AdditionalData},
+		{&want, alg.KAT.Ciphertext},
+	} {
+		b, err := hex.DecodeString(f.src)
+		if err != nil {
+			return fmt.Errorf("invalid test vector: %w", err)
+		}
+		*f.dst = b
+	}
+	if len(want) == 0 {
+		return fmt.Errorf("no test vector for %s", alg.Name)
+	}
+
+	aead, err := alg.NewAEAD(key)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher: %w", err)
+	}
+	if got := aead.Seal(nil, nonce, plaintext, aad); !bytes.Equal(got, want) {
+		return fmt.Errorf("encryption mismatch: got %x, want %x", got, want)
+	}
+	got, err := aead.Open(nil, nonce, want, aad)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		return fmt.Errorf("decryption mismatch: got %x, want %x", got, plaintext)
+	}
+
+	// Измененный шифротекст должен отвергаться
+	tampered := append([]byte{}, want...)
+	tampered[0] ^= 0x01
+	if _, err := aead.Open(nil, nonce, tampered, aad); err == nil {
+		return fmt.Errorf("tampered ciphertext was accepted")
+	}
+	return nil
+}
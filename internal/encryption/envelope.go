@@ -0,0 +1,116 @@
+package encryption
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+)
+
+const (
+	envelopePrefix = "ENC["
+	envelopeSuffix = "]"
+)
+
+// Envelope разобранное зашифрованное значение вида ENC[ALG:payload]
+// или ENC[ALG;k1=v1;k2=v2:payload]. Атрибуты заголовка аутентифицируются
+// как дополнительные данные AEAD; значение без атрибутов совместимо с исходным форматом.
+type Envelope struct {
+	// Algorithm - идентификатор алгоритма (например, AES256)
+	Algorithm string
+	// Attrs - атрибуты заголовка (идентификатор ключа, арендатор и т.п.)
+	Attrs map[string]string
+	// Payload - данные в base64
+	Payload string
+}
+
+// IsEnvelope проверяет, что значение похоже на ENC[...]
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix) && strings.HasSuffix(value, envelopeSuffix)
+}
+
+// ParseEnvelope разбирает значение ENC[...]
+func ParseEnvelope(value string) (*Envelope, error) {
+	if !IsEnvelope(value) {
+		return nil, fmt.Errorf("%w: invalid encrypted data format", interfaces.ErrInvalidData)
+	}
+	body := value[len(envelopePrefix) : len(value)-len(envelopeSuffix)]
+	header, payload, ok := strings.Cut(body, ":")
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid encrypted data format", interfaces.ErrInvalidData)
+	}
+
+	fields := strings.Split(header, ";")
+	env := &Envelope{
+		Algorithm: fields[0],
+		Payload:   payload,
+	}
+	for _, f := range fields[1:] {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%w: invalid envelope attribute %q", interfaces.ErrInvalidData, f)
+		}
+		v, err := url.QueryUnescape(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid envelope attribute %q", interfaces.ErrInvalidData, f)
+		}
+		if env.Attrs == nil {
+			env.Attrs = make(map[string]string)
+		}
+		env.Attrs[k] = v
+	}
+	return env, nil
+}
+
+// Header возвращает заголовок в каноническом виде: атрибуты отсортированы по имени,
+// значения экранированы, чтобы не содержать разделителей ";", ":" и "]"
+func (e *Envelope) Header() string {
+	if len(e.Attrs) == 0 {
+		return e.Algorithm
+	}
+	keys := make([]string, 0, len(e.Attrs))
+	for k := range e.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(e.Algorithm)
+	for _, k := range keys {
+		fmt.Fprintf(&b, ";%s=%s", k, escapeAttr(e.Attrs[k]))
+	}
+	return b.String()
+}
+
+// AdditionalData возвращает данные, аутентифицируемые вместе с шифротекстом.
+// Для конвертов без атрибутов возвращается nil ради совместимости.
+func (e *Envelope) AdditionalData() []byte {
+	if len(e.Attrs) == 0 {
+		return nil
+	}
+	return []byte(e.Header())
+}
+
+// String возвращает значение в формате ENC[...]
+func (e *Envelope) String() string {
+	return envelopePrefix + e.Header() + ":" + e.Payload + envelopeSuffix
+}
+
+// escapeAttr экранирует в значении атрибута разделители конверта, "%", "+",
+// пробелы и не-ASCII символы; пути вида key/v1 остаются читаемыми.
+// Результат совместим с url.QueryUnescape, которым читались прежние конверты.
+func escapeAttr(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '%' || c == '+' || c == ';' || c == ':' || c == ']' || c <= ' ' || c >= 0x7f:
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -1,6 +1,8 @@
 package encryption
 
 import (
+	"context"
+
 	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
 	"github.com/JohnnyFes/go-encryptor/pkg/config"
 )
@@ -17,5 +19,36 @@ func NewEncryptorProvider() *EncryptorProvider {
 
 // ProvideEncryptor предоставляет новый экземпляр шифровальщика
 func (p *EncryptorProvider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
-	return NewEncryptor(cfg.Key)
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext предоставляет новый экземпляр шифровальщика с учетом контекста
+func (p *EncryptorProvider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+	var enc *AEADEncryptor
+	// Прежняя обработка ключа (дополнение нулями или хэширование) только по явному запросу
+	if cfg.LegacyKey {
+		var err error
+		if enc, err = NewEncryptorWithAlgorithm(cfg.Key, algorithm); err != nil {
+			return nil, err
+		}
+	} else {
+		key, err := cfg.KeyBytes()
+		if err != nil {
+			return nil, err
+		}
+		if enc, err = NewEncryptorFromKey(key, algorithm); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.EmbedKeyID {
+		enc.EmbedKeyID()
+	}
+	return enc, nil
 }
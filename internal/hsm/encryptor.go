@@ -0,0 +1,392 @@
+//go:build cgo
+
+package hsm
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sync"
+
+	p11 "github.com/miekg/pkcs11"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	gcmNonceSize = 12
+	gcmTagBits   = 128
+)
+
+// Encryptor реализует interfaces.Encryptor ключом, хранящимся в HSM.
+// В режиме gcm каждое значение шифруется CKM_AES_GCM внутри токена:
+// ENC[AES256;hsm=label:iv||ct]. В режиме wrap значения шифруются локально
+// ключом данных, завернутым ключом токена: ENC[AES256;dk=...;hsm=label:data].
+// Сессия PKCS#11 однопоточная, поэтому операции с токеном сериализуются.
+type Encryptor struct {
+	ctx      *p11.Ctx
+	session  p11.SessionHandle
+	key      p11.ObjectHandle
+	keyLabel string
+	mode     string
+	alg      encryption.Algorithm
+	// finalize - модуль инициализирован этим шифровальщиком и выгружается в Close
+	finalize bool
+
+	mu sync.Mutex
+	// current - ключ данных для шифрования в режиме wrap, создается при первом шифровании
+	current *dataKey
+	// unwrapped - развернутые ключи данных по их завернутой форме
+	unwrapped map[string]cipher.AEAD
+}
+
+// dataKey ключ данных и его завернутая ключом токена форма
+type dataKey struct {
+	aead    cipher.AEAD
+	wrapped string
+}
+
+// NewEncryptor загружает модуль PKCS#11, входит в токен и находит ключ по метке
+func NewEncryptor(cfg *config.PKCS11Config, algorithm string) (*Encryptor, error) {
+	if algorithm == "" {
+		algorithm = encryption.DefaultAlgorithm
+	}
+	alg, ok := encryption.LookupAlgorithm(algorithm)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidConfig, algorithm)
+	}
+	mode := cfg.Mode
+	if mode == "" {
+		mode = config.PKCS11ModeGCM
+	}
+	if mode == config.PKCS11ModeGCM && alg.Name != encryption.DefaultAlgorithm {
+		return nil, fmt.Errorf("%w: pkcs11 gcm mode supports only %s", interfaces.ErrInvalidConfig, encryption.DefaultAlgorithm)
+	}
+
+	ctx := p11.New(cfg.ModulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load pkcs11 module %s", cfg.ModulePath)
+	}
+	err := ctx.Initialize()
+	if err != nil && err != p11.Error(p11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialize pkcs11 module: %w", err)
+	}
+
+	e := &Encryptor{
+		ctx:       ctx,
+		finalize:  err == nil,
+		keyLabel:  cfg.KeyLabel,
+		mode:      mode,
+		alg:       alg,
+		unwrapped: make(map[string]cipher.AEAD),
+	}
+	if err := e.open(cfg); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// open открывает сессию в слоте токена, входит и находит ключ
+func (e *Encryptor) open(cfg *config.PKCS11Config) error {
+	slot, err := findSlot(e.ctx, cfg)
+	if err != nil {
+		return err
+	}
+	e.session, err = e.ctx.OpenSession(slot, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+	if err != nil {
+		return fmt.Errorf("failed to open pkcs11 session: %w", err)
+	}
+	if err := e.ctx.Login(e.session, p11.CKU_USER, cfg.PIN); err != nil && err != p11.Error(p11.CKR_USER_ALREADY_LOGGED_IN) {
+		return fmt.Errorf("pkcs11 login failed: %w", err)
+	}
+
+	if err := e.ctx.FindObjectsInit(e.session, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_SECRET_KEY),
+		p11.NewAttribute(p11.CKA_LABEL, cfg.KeyLabel),
+	}); err != nil {
+		return fmt.Errorf("failed to search pkcs11 key: %w", err)
+	}
+	objects, _, err := e.ctx.FindObjects(e.session, 2)
+	if finalErr := e.ctx.FindObjectsFinal(e.session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to search pkcs11 key: %w", err)
+	}
+	switch len(objects) {
+	case 0:
+		return fmt.Errorf("%w: pkcs11 key %q not found", interfaces.ErrInvalidConfig, cfg.KeyLabel)
+	case 1:
+		e.key = objects[0]
+		return nil
+	default:
+		return fmt.Errorf("%w: several pkcs11 keys labeled %q", interfaces.ErrInvalidConfig, cfg.KeyLabel)
+	}
+}
+
+// findSlot возвращает слот токена с меткой cfg.TokenLabel или cfg.Slot
+func findSlot(ctx *p11.Ctx, cfg *config.PKCS11Config) (uint, error) {
+	if cfg.TokenLabel == "" {
+		return cfg.Slot, nil
+	}
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pkcs11 slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err == nil && info.Label == cfg.TokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: pkcs11 token %q not found", interfaces.ErrInvalidConfig, cfg.TokenLabel)
+}
+
+// Close закрывает сессию и выгружает модуль PKCS#11
+func (e *Encryptor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ctx == nil {
+		return nil
+	}
+	var err error
+	if e.session != 0 {
+		err = e.ctx.CloseSession(e.session)
+	}
+	if e.finalize {
+		if finalizeErr := e.ctx.Finalize(); err == nil {
+			err = finalizeErr
+		}
+	}
+	e.ctx.Destroy()
+	e.ctx = nil
+	return err
+}
+
+// KeyID возвращает идентификатор ключа в токене
+func (e *Encryptor) KeyID() string {
+	return "pkcs11/" + e.keyLabel
+}
+
+// Encrypt шифрует данные
+func (e *Encryptor) Encrypt(text string) (string, error) {
+	return e.EncryptContext(context.Background(), text)
+}
+
+// Decrypt расшифровывает данные
+func (e *Encryptor) Decrypt(encrypted string) (string, error) {
+	return e.DecryptContext(context.Background(), encrypted)
+}
+
+// EncryptContext шифрует данные, если контекст еще не отменен
+func (e *Encryptor) EncryptContext(ctx context.Context, text string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	env := &encryption.Envelope{
+		Algorithm: e.alg.Name,
+		Attrs:     map[string]string{AttrKeyLabel: e.keyLabel},
+	}
+
+	if e.mode == config.PKCS11ModeWrap {
+		key, err := e.dataKey()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", interfaces.ErrEncryptionFailed, err)
+		}
+		env.Attrs[AttrDataKey] = key.wrapped
+		if err := encryption.SealEnvelope(env, key.aead, []byte(text)); err != nil {
+			return "", err
+		}
+		return env.String(), nil
+	}
+
+	ciphertext, err := e.encryptGCM([]byte(text), env.AdditionalData())
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", interfaces.ErrEncryptionFailed, err)
+	}
+	env.Payload = base64.StdEncoding.EncodeToString(ciphertext)
+	return env.String(), nil
+}
+
+// DecryptContext расшифровывает данные, если контекст еще не отменен
+func (e *Encryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	env, err := encryption.ParseEnvelope(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if label := env.Attrs[AttrKeyLabel]; label != e.keyLabel {
+		return "", fmt.Errorf("%w: value encrypted with pkcs11 key %q, encryptor uses %q",
+			interfaces.ErrDecryptionFailed, label, e.keyLabel)
+	}
+
+	var plaintext []byte
+	if wrapped, ok := env.Attrs[AttrDataKey]; ok {
+		aead, err := e.unwrap(env.Algorithm, wrapped)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", interfaces.ErrDecryptionFailed, err)
+		}
+		plaintext, err = encryption.OpenEnvelope(env, aead)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", interfaces.ErrDecryptionFailed, err)
+		}
+		return string(plaintext), nil
+	}
+
+	if env.Algorithm != encryption.DefaultAlgorithm {
+		return "", fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidData, env.Algorithm)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64: %w", err)
+	}
+	plaintext, err = e.decryptGCM(ciphertext, env.AdditionalData())
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", interfaces.ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptGCM шифрует данные CKM_AES_GCM в токене и возвращает iv||ciphertext
+func (e *Encryptor) encryptGCM(plaintext, aad []byte) ([]byte, error) {
+	iv := make([]byte, gcmNonceSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	params := p11.NewGCMParams(iv, aad, gcmTagBits)
+	defer params.Free()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.ctx.EncryptInit(e.session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_GCM, params)}, e.key); err != nil {
+		return nil, err
+	}
+	ciphertext, err := e.ctx.Encrypt(e.session, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	// Некоторые HSM игнорируют переданный IV и подставляют свой
+	if actual := params.IV(); len(actual) == gcmNonceSize {
+		iv = actual
+	}
+	return append(iv, ciphertext...), nil
+}
+
+// decryptGCM расшифровывает iv||ciphertext CKM_AES_GCM в токене
+func (e *Encryptor) decryptGCM(data, aad []byte) ([]byte, error) {
+	if len(data) < gcmNonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	params := p11.NewGCMParams(data[:gcmNonceSize], aad, gcmTagBits)
+	defer params.Free()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.ctx.DecryptInit(e.session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_GCM, params)}, e.key); err != nil {
+		return nil, err
+	}
+	return e.ctx.Decrypt(e.session, data[gcmNonceSize:])
+}
+
+// dataKey возвращает ключ данных, при первом вызове создавая его и заворачивая ключом токена
+func (e *Encryptor) dataKey() (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current != nil {
+		return e.current, nil
+	}
+
+	raw := make([]byte, e.alg.KeySize)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	defer wipe(raw)
+
+	// Ключ данных импортируется как временный объект сессии только для заворачивания
+	obj, err := e.ctx.CreateObject(e.session, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_SECRET_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_GENERIC_SECRET),
+		p11.NewAttribute(p11.CKA_TOKEN, false),
+		p11.NewAttribute(p11.CKA_EXTRACTABLE, true),
+		p11.NewAttribute(p11.CKA_VALUE, raw),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import data key: %w", err)
+	}
+	defer e.ctx.DestroyObject(e.session, obj)
+
+	wrapped, err := e.ctx.WrapKey(e.session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_KEY_WRAP_PAD, nil)}, e.key, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	aead, err := e.alg.NewAEAD(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	e.current = &dataKey{aead: aead, wrapped: base64.RawURLEncoding.EncodeToString(wrapped)}
+	e.unwrapped[e.current.wrapped] = aead
+	return e.current, nil
+}
+
+// unwrap разворачивает ключ данных ключом токена; результаты кэшируются
+func (e *Encryptor) unwrap(algorithm, wrapped string) (cipher.AEAD, error) {
+	alg, ok := encryption.LookupAlgorithm(algorithm)
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	blob, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if aead, ok := e.unwrapped[wrapped]; ok {
+		return aead, nil
+	}
+
+	obj, err := e.ctx.UnwrapKey(e.session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_KEY_WRAP_PAD, nil)}, e.key, blob, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_SECRET_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_GENERIC_SECRET),
+		p11.NewAttribute(p11.CKA_TOKEN, false),
+		p11.NewAttribute(p11.CKA_SENSITIVE, false),
+		p11.NewAttribute(p11.CKA_EXTRACTABLE, true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	defer e.ctx.DestroyObject(e.session, obj)
+
+	attrs, err := e.ctx.GetAttributeValue(e.session, obj, []*p11.Attribute{p11.NewAttribute(p11.CKA_VALUE, nil)})
+	if err != nil || len(attrs) != 1 {
+		return nil, fmt.Errorf("failed to read unwrapped data key: %v", err)
+	}
+	raw := attrs[0].Value
+	defer wipe(raw)
+	if len(raw) != alg.KeySize {
+		return nil, fmt.Errorf("data key has %d bytes, %s needs %d", len(raw), alg.Name, alg.KeySize)
+	}
+	aead, err := alg.NewAEAD(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	e.unwrapped[wrapped] = aead
+	return aead, nil
+}
+
+// wipe обнуляет открытую копию ключа
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
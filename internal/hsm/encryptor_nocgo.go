@@ -0,0 +1,15 @@
+//go:build !cgo
+
+package hsm
+
+import (
+	"errors"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// NewEncryptor без cgo недоступен: модули PKCS#11 загружаются через C API
+func NewEncryptor(cfg *config.PKCS11Config, algorithm string) (interfaces.Encryptor, error) {
+	return nil, errors.New("pkcs11 support requires a build with cgo enabled")
+}
@@ -0,0 +1,44 @@
+package hsm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// AttrKeyLabel атрибут конверта с меткой ключа в токене
+	AttrKeyLabel = "hsm"
+	// AttrDataKey атрибут конверта с ключом данных, завернутым ключом токена (режим wrap)
+	AttrDataKey = "dk"
+)
+
+// Provider реализует interfaces.EncryptorProvider для HSM с интерфейсом PKCS#11
+type Provider struct{}
+
+// NewProvider создает провайдер шифровальщиков PKCS#11
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// ProvideEncryptor предоставляет шифровальщик PKCS#11 по настройкам cfg.PKCS11
+func (p *Provider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext предоставляет шифровальщик PKCS#11 с учетом контекста
+func (p *Provider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cfg.PKCS11 == nil {
+		return nil, fmt.Errorf("%w: pkcs11 is not configured", interfaces.ErrInvalidConfig)
+	}
+	enc, err := NewEncryptor(cfg.PKCS11, cfg.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
@@ -1,6 +1,9 @@
 package interfaces
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrInvalidKey ошибка при неверном ключе
@@ -13,4 +16,42 @@ var (
 	ErrDecryptionFailed = errors.New("decryption failed")
 	// ErrInvalidConfig ошибка при неверной конфигурации
 	ErrInvalidConfig = errors.New("invalid configuration")
+	// ErrTenantMismatch ошибка при расшифровке данных другого арендатора
+	ErrTenantMismatch = errors.New("tenant mismatch")
+	// ErrKeyUsageExceeded ошибка при превышении допустимого числа шифрований одним ключом
+	ErrKeyUsageExceeded = errors.New("key usage limit exceeded")
+	// ErrKeyRevoked ошибка при расшифровке значения отозванным ключом
+	ErrKeyRevoked = errors.New("key revoked")
+	// ErrKeyExpired ошибка при шифровании ключом с истекшим сроком действия
+	ErrKeyExpired = errors.New("key expired")
+	// ErrNoActiveKey ошибка при шифровании, если в наборе нет активного ключа
+	ErrNoActiveKey = errors.New("no active key")
+	// ErrKeyMismatch ошибка при расшифровке значения, зашифрованного другим ключом
+	ErrKeyMismatch = errors.New("key mismatch")
+	// ErrUnsupportedField ошибка, если поле с тегом encrypted:"true" не может хранить шифротекст
+	ErrUnsupportedField = errors.New("unsupported encrypted field type")
 )
+
+// KeyMismatchError ошибка расшифровки значения, в конверте которого записан
+// отпечаток (или идентификатор) другого ключа. Она означает, что выбран не тот
+// ключ, а не что данные повреждены. errors.Is сопоставляет ее с ErrKeyMismatch
+// и ErrDecryptionFailed.
+type KeyMismatchError struct {
+	// Expected - ключ, которым зашифровано значение: идентификатор ключа набора
+	// (атрибут kid конверта) или отпечаток ключа config.KeyFingerprint (атрибут kfp)
+	Expected string
+	// Actual - отпечаток ключа шифровальщика (пусто, если ключей несколько)
+	Actual string
+}
+
+func (e *KeyMismatchError) Error() string {
+	if e.Actual == "" {
+		return fmt.Sprintf("%v: value was encrypted with key %s", ErrKeyMismatch, e.Expected)
+	}
+	return fmt.Sprintf("%v: value was encrypted with key %s, not %s", ErrKeyMismatch, e.Expected, e.Actual)
+}
+
+// Is сопоставляет ошибку с ErrKeyMismatch и ErrDecryptionFailed
+func (e *KeyMismatchError) Is(target error) bool {
+	return target == ErrKeyMismatch || target == ErrDecryptionFailed
+}
@@ -1,21 +1,49 @@
 package interfaces
 
 import (
+	"context"
+
 	"github.com/JohnnyFes/go-encryptor/pkg/config"
 )
 
-// Encryptor определяет интерфейс для шифрования данных
+// Encryptor определяет интерфейс для шифрования данных.
+// Варианты с context.Context позволяют удаленным реализациям соблюдать
+// дедлайны и отмену; Encrypt и Decrypt эквивалентны вызову с context.Background().
 type Encryptor interface {
 	Encrypt(text string) (string, error)
 	Decrypt(encrypted string) (string, error)
+	EncryptContext(ctx context.Context, text string) (string, error)
+	DecryptContext(ctx context.Context, encrypted string) (string, error)
+}
+
+// TenantEncryptor реализуется шифровальщиками, которые умеют получать
+// подключ арендатора из мастер-ключа
+type TenantEncryptor interface {
+	ForTenant(tenantID string) (Encryptor, error)
+}
+
+// KeyIdentifier реализуется шифровальщиками, которые могут назвать
+// стабильный идентификатор используемого ключа
+type KeyIdentifier interface {
+	KeyID() string
 }
 
 // EncryptorProvider определяет интерфейс для предоставления шифровальщиков
 type EncryptorProvider interface {
 	ProvideEncryptor(cfg *config.Config) (Encryptor, error)
+	ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (Encryptor, error)
 }
 
 // FieldEncryptor определяет интерфейс для шифрования полей в структурах
 type FieldEncryptor interface {
 	HandleFields(data interface{}, encrypt bool) error
+	HandleFieldsContext(ctx context.Context, data interface{}, encrypt bool) error
+}
+
+// FieldSealer реализуется типами полей, которые сами сериализуют и шифруют
+// свое значение (encryption.Encrypted[T]); обработчик полей вызывает их методы
+// вместо шифрования строки
+type FieldSealer interface {
+	SealField(ctx context.Context, enc Encryptor) error
+	OpenField(ctx context.Context, enc Encryptor) error
 }
@@ -0,0 +1,173 @@
+package keyhelper
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// ProtocolVersion версия протокола программ-помощников
+	ProtocolVersion = 1
+	// OperationGet запрос ключа по идентификатору
+	OperationGet = "get"
+	// OperationUnwrap запрос на разворачивание зашифрованного ключа
+	OperationUnwrap = "unwrap"
+	// maxStderr сколько байт stderr помощника включается в сообщение об ошибке
+	maxStderr = 512
+)
+
+// Request запрос, который помощник получает на stdin одним JSON-объектом
+type Request struct {
+	Version    int    `json:"version"`
+	Operation  string `json:"operation"`
+	KeyID      string `json:"key_id,omitempty"`
+	WrappedKey string `json:"wrapped_key,omitempty"`
+	Algorithm  string `json:"algorithm"`
+	KeySize    int    `json:"key_size"`
+}
+
+// Response ответ, который помощник пишет на stdout одним JSON-объектом.
+// При ошибке заполняется Error, а Key остается пустым.
+type Response struct {
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// FetchKey запускает помощника и возвращает ключ длиной size байт для алгоритма algorithm
+func FetchKey(ctx context.Context, cfg *config.KeyHelperConfig, algorithm string, size int) ([]byte, error) {
+	req := Request{
+		Version:   ProtocolVersion,
+		Operation: OperationGet,
+		KeyID:     cfg.KeyID,
+		Algorithm: algorithm,
+		KeySize:   size,
+	}
+	if cfg.WrappedKey != "" {
+		req.Operation = OperationUnwrap
+		req.WrappedKey = cfg.WrappedKey
+	}
+
+	resp, err := Run(ctx, cfg, &req)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(resp.Key)
+	if err != nil {
+		return nil, fmt.Errorf("key helper returned an invalid key: %w", err)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("key helper returned a %d-byte key, %s needs %d", len(key), algorithm, size)
+	}
+	return key, nil
+}
+
+// Run отправляет запрос помощнику и читает ответ с учетом таймаута
+func Run(ctx context.Context, cfg *config.KeyHelperConfig, req *Request) (*Response, error) {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = config.DefaultKeyHelperTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key helper request: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); errors.Is(ctxErr, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("key helper %s timed out after %s: %w", cfg.Command, timeout, ctxErr)
+		} else if ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("key helper %s failed: %w%s", cfg.Command, err, stderrSuffix(stderr.String()))
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("key helper %s returned invalid JSON: %w", cfg.Command, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("key helper %s: %s", cfg.Command, resp.Error)
+	}
+	return &resp, nil
+}
+
+// stderrSuffix возвращает начало stderr помощника для сообщения об ошибке
+func stderrSuffix(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > maxStderr {
+		stderr = stderr[:maxStderr] + "..."
+	}
+	return ": " + stderr
+}
+
+// Provider реализует interfaces.EncryptorProvider поверх программы-помощника
+type Provider struct{}
+
+// NewProvider создает провайдер шифровальщиков с ключом от программы-помощника
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// ProvideEncryptor предоставляет шифровальщик с ключом от помощника cfg.KeyHelper
+func (p *Provider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext предоставляет шифровальщик с учетом контекста
+func (p *Provider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cfg.KeyHelper == nil {
+		return nil, fmt.Errorf("%w: key helper is not configured", interfaces.ErrInvalidConfig)
+	}
+
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = encryption.DefaultAlgorithm
+	}
+	alg, ok := encryption.LookupAlgorithm(algorithm)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidConfig, algorithm)
+	}
+
+	key, err := FetchKey(ctx, cfg.KeyHelper, alg.Name, alg.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
+	enc, err := encryption.NewEncryptorFromKey(key, alg.Name)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.EmbedKeyID {
+		enc.EmbedKeyID()
+	}
+	return enc, nil
+}
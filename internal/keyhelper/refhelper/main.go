@@ -0,0 +1,108 @@
+// Программа refhelper - эталонная программа-помощник для протокола keyhelper.
+// Ключи берутся из JSON-файла вида {"keys": {"id": "base64"}, "kek": "base64"}:
+// операция get возвращает ключ по key_id (по умолчанию "default"), а операция
+// unwrap расшифровывает wrapped_key (nonce||ciphertext AES-256-GCM, AAD - key_id)
+// ключом kek. Используется в тестах и как образец для собственных помощников.
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/keyhelper"
+)
+
+type keyFile struct {
+	Keys map[string]string `json:"keys"`
+	KEK  string            `json:"kek"`
+}
+
+func main() {
+	keysPath := flag.String("keys", "", "path to the JSON key file")
+	delay := flag.Duration("delay", 0, "wait before answering (for timeout tests)")
+	flag.Parse()
+
+	var req keyhelper.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "refhelper: invalid request:", err)
+		os.Exit(2)
+	}
+	time.Sleep(*delay)
+
+	var resp keyhelper.Response
+	key, err := handle(*keysPath, &req)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Key = base64.StdEncoding.EncodeToString(key)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+		os.Exit(1)
+	}
+}
+
+func handle(keysPath string, req *keyhelper.Request) ([]byte, error) {
+	if req.Version != keyhelper.ProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d", req.Version)
+	}
+	data, err := os.ReadFile(keysPath)
+	if err != nil {
+		return nil, err
+	}
+	var keys keyFile
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid key file: %w", err)
+	}
+
+	keyID := req.KeyID
+	if keyID == "" {
+		keyID = "default"
+	}
+
+	switch req.Operation {
+	case keyhelper.OperationGet:
+		encoded, ok := keys.Keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found", keyID)
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	case keyhelper.OperationUnwrap:
+		kek, err := base64.StdEncoding.DecodeString(keys.KEK)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kek: %w", err)
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(req.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wrapped key: %w", err)
+		}
+		return unwrap(kek, wrapped, keyID)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", req.Operation)
+	}
+}
+
+func unwrap(kek, wrapped []byte, keyID string) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key too short")
+	}
+	key, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, errors.New("failed to unwrap key")
+	}
+	return key, nil
+}
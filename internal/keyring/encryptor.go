@@ -0,0 +1,255 @@
+package keyring
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// entry ключ набора с готовым AEAD
+type entry struct {
+	meta config.KeyringKey
+	alg  encryption.Algorithm
+	aead cipher.AEAD
+}
+
+// Encryptor реализует interfaces.Encryptor поверх набора ключей.
+// Шифрует только активный ключ, идентификатор ключа записывается в конверт:
+// ENC[AES256;kid=2024-01:...]. Расшифровка выбирает ключ по kid и отказывает
+// для отозванных ключей (interfaces.ErrKeyRevoked). Значения без kid
+// (зашифрованные до перехода на набор ключей) расшифровываются перебором
+// неотозванных ключей того же алгоритма. Значения с отпечатком ключа (kfp),
+// зашифрованные тем же ключом до его импорта в набор, расшифровываются ключом
+// с этим отпечатком.
+type Encryptor struct {
+	keys map[string]*entry
+	// fingerprints - ключи по отпечатку config.KeyFingerprint
+	fingerprints map[string]*entry
+	order        []*entry
+	active       *entry
+	now          func() time.Time
+}
+
+// NewEncryptor создает шифровальщик из набора ключей. Если задана политика,
+// алгоритм каждого ключа проверяется на соответствие ей.
+func NewEncryptor(ring *config.Keyring, policy *config.Policy) (*Encryptor, error) {
+	if err := ring.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", interfaces.ErrInvalidConfig, err)
+	}
+
+	// Ключ зашифрованного набора получен из парольной фразы Argon2id
+	if policy != nil && ring.Encrypted() {
+		if err := policy.CheckKDF(config.KDFArgon2id, 0); err != nil {
+			return nil, fmt.Errorf("keyring passphrase: %w", err)
+		}
+	}
+
+	e := &Encryptor{keys: make(map[string]*entry), fingerprints: make(map[string]*entry), now: time.Now}
+	for _, k := range ring.Keys {
+		if policy != nil {
+			if err := policy.CheckAlgorithm(k.Algorithm); err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.ID, err)
+			}
+		}
+		alg, ok := encryption.LookupAlgorithm(k.Algorithm)
+		if !ok {
+			return nil, fmt.Errorf("%w: key %q: unsupported algorithm %q", interfaces.ErrInvalidConfig, k.ID, k.Algorithm)
+		}
+		raw, err := base64.StdEncoding.DecodeString(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q is not base64", interfaces.ErrInvalidConfig, k.ID)
+		}
+		if len(raw) != alg.KeySize {
+			return nil, fmt.Errorf("%w: key %q is %d bytes, %s needs %d",
+				interfaces.ErrInvalidConfig, k.ID, len(raw), alg.Name, alg.KeySize)
+		}
+		aead, err := alg.NewAEAD(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+
+		ent := &entry{meta: k, alg: alg, aead: aead}
+		e.keys[k.ID] = ent
+		e.fingerprints[config.KeyFingerprint(raw)] = ent
+		if k.State == config.KeyStateActive {
+			e.active = ent
+			// Активный ключ перебирается первым
+			e.order = append([]*entry{ent}, e.order...)
+		} else {
+			e.order = append(e.order, ent)
+		}
+	}
+	return e, nil
+}
+
+// RequireActive проверяет, что ключ id - активный ключ набора. Шифрует только
+// активный ключ: ключ только для расшифровки (выведенный или еще не продвинутый)
+// или отозванный ключ отклоняется, чтобы устаревший key_id не продолжал
+// шифровать новые значения.
+func (e *Encryptor) RequireActive(id string) error {
+	ent, ok := e.keys[id]
+	if !ok {
+		return fmt.Errorf("%w: key %q is not in the keyring", interfaces.ErrInvalidConfig, id)
+	}
+	if ent.meta.State == config.KeyStateRevoked {
+		return fmt.Errorf("%w: key %q", interfaces.ErrKeyRevoked, id)
+	}
+	if ent != e.active {
+		return fmt.Errorf("%w: key %q is %s, only the active key encrypts new values", interfaces.ErrNoActiveKey, id, ent.meta.State)
+	}
+	return nil
+}
+
+// KeyID возвращает идентификатор активного ключа
+func (e *Encryptor) KeyID() string {
+	if e.active == nil {
+		return ""
+	}
+	return "keyring/" + e.active.meta.ID
+}
+
+// Encrypt шифрует данные
+func (e *Encryptor) Encrypt(text string) (string, error) {
+	return e.EncryptContext(context.Background(), text)
+}
+
+// Decrypt расшифровывает данные
+func (e *Encryptor) Decrypt(encrypted string) (string, error) {
+	return e.DecryptContext(context.Background(), encrypted)
+}
+
+// EncryptContext шифрует данные активным ключом
+func (e *Encryptor) EncryptContext(ctx context.Context, text string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if e.active == nil {
+		return "", fmt.Errorf("%w: keyring has no active key", interfaces.ErrNoActiveKey)
+	}
+	if e.active.meta.Expired(e.now()) {
+		return "", fmt.Errorf("%w: key %q expired at %s", interfaces.ErrKeyExpired,
+			e.active.meta.ID, e.active.meta.Expires.Format(time.RFC3339))
+	}
+
+	env := &encryption.Envelope{
+		Algorithm: e.active.alg.Name,
+		Attrs:     map[string]string{encryption.AttrKeyID: e.active.meta.ID},
+	}
+	if err := encryption.SealEnvelope(env, e.active.aead, []byte(text)); err != nil {
+		return "", err
+	}
+	return env.String(), nil
+}
+
+// DecryptContext расшифровывает данные ключом из конверта
+func (e *Encryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	env, err := encryption.ParseEnvelope(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	ent, err := e.entryFor(env)
+	if err != nil {
+		return "", err
+	}
+	if ent == nil {
+		return e.decryptLegacy(env)
+	}
+	id := ent.meta.ID
+	if ent.meta.State == config.KeyStateRevoked {
+		return "", fmt.Errorf("%w: key %q", interfaces.ErrKeyRevoked, id)
+	}
+	if ent.alg.Name != env.Algorithm {
+		return "", fmt.Errorf("%w: key %q is %s, value is %s", interfaces.ErrInvalidData, id, ent.alg.Name, env.Algorithm)
+	}
+	plaintext, err := encryption.OpenEnvelope(env, ent.aead)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", interfaces.ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+// entryFor выбирает ключ конверта по kid или отпечатку kfp. Для конверта
+// без обоих атрибутов возвращает nil без ошибки.
+func (e *Encryptor) entryFor(env *encryption.Envelope) (*entry, error) {
+	if kid, ok := env.Attrs[encryption.AttrKeyID]; ok {
+		if ent, ok := e.keys[kid]; ok {
+			return ent, nil
+		}
+		// Версии до атрибута kfp записывали отпечаток ключа в kid
+		if ent, ok := e.fingerprints[kid]; ok {
+			return ent, nil
+		}
+		return nil, &interfaces.KeyMismatchError{Expected: kid}
+	}
+	if kfp, ok := env.Attrs[encryption.AttrKeyFingerprint]; ok {
+		if ent, ok := e.fingerprints[kfp]; ok {
+			return ent, nil
+		}
+		return nil, &interfaces.KeyMismatchError{Expected: kfp}
+	}
+	return nil, nil
+}
+
+// decryptLegacy расшифровывает конверт без kid, перебирая неотозванные ключи
+func (e *Encryptor) decryptLegacy(env *encryption.Envelope) (string, error) {
+	for _, ent := range e.order {
+		if ent.meta.State == config.KeyStateRevoked || ent.alg.Name != env.Algorithm {
+			continue
+		}
+		if plaintext, err := encryption.OpenEnvelope(env, ent.aead); err == nil {
+			return string(plaintext), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no keyring key decrypts the value", interfaces.ErrDecryptionFailed)
+}
+
+// Provider реализует interfaces.EncryptorProvider для набора ключей
+type Provider struct{}
+
+// NewProvider создает провайдер шифровальщиков на наборе ключей
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// ProvideEncryptor читает набор ключей cfg.KeyringPath и создает шифровальщик
+func (p *Provider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext читает набор ключей с учетом контекста
+func (p *Provider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cfg.KeyringPath == "" {
+		return nil, fmt.Errorf("%w: keyring is not configured", interfaces.ErrInvalidConfig)
+	}
+	var opts []config.KeyringOption
+	if cfg.KeyringPassphrase != nil {
+		opts = append(opts, config.WithPassphraseFunc(cfg.KeyringPassphrase))
+	}
+	ring, err := config.LoadKeyring(cfg.KeyringPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := NewEncryptor(ring, cfg.Policy)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.KeyringKeyID != "" {
+		if err := enc.RequireActive(cfg.KeyringKeyID); err != nil {
+			return nil, err
+		}
+	}
+	return enc, nil
+}
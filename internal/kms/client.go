@@ -0,0 +1,146 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// serviceName имя сервиса в области подписи SigV4
+	serviceName    = "kms"
+	defaultTimeout = 30 * time.Second
+)
+
+// APIError ошибка, которую вернул KMS
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kms returned %d: %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// Client клиент JSON API AWS KMS с подписью запросов SigV4
+type Client struct {
+	endpoint string
+	region   string
+	creds    Credentials
+	http     *http.Client
+	now      func() time.Time
+}
+
+// NewClient создает клиента KMS по настройкам
+func NewClient(cfg *config.KMSConfig) *Client {
+	c := &Client{
+		endpoint: strings.TrimRight(cfg.Endpoint, "/"),
+		region:   cfg.Region,
+		creds: Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			SessionToken:    cfg.SessionToken,
+		},
+		http: cfg.HTTPClient,
+		now:  time.Now,
+	}
+	if c.endpoint == "" {
+		c.endpoint = "https://kms." + cfg.Region + ".amazonaws.com"
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: defaultTimeout}
+	}
+	return c
+}
+
+// GenerateDataKey создает ключ данных длиной size байт и возвращает его
+// открытую и зашифрованную мастер-ключом keyID копии
+func (c *Client) GenerateDataKey(ctx context.Context, keyID string, size int, encCtx map[string]string) (plaintext, wrapped []byte, err error) {
+	var resp struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+		Plaintext      []byte `json:"Plaintext"`
+	}
+	err = c.call(ctx, "GenerateDataKey", struct {
+		KeyID             string            `json:"KeyId"`
+		NumberOfBytes     int               `json:"NumberOfBytes"`
+		EncryptionContext map[string]string `json:"EncryptionContext,omitempty"`
+	}{keyID, size, encCtx}, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resp.Plaintext) != size || len(resp.CiphertextBlob) == 0 {
+		return nil, nil, fmt.Errorf("kms returned a malformed data key")
+	}
+	return resp.Plaintext, resp.CiphertextBlob, nil
+}
+
+// Decrypt расшифровывает ключ данных мастер-ключом keyID
+func (c *Client) Decrypt(ctx context.Context, keyID string, wrapped []byte, encCtx map[string]string) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	err := c.call(ctx, "Decrypt", struct {
+		KeyID             string            `json:"KeyId"`
+		CiphertextBlob    []byte            `json:"CiphertextBlob"`
+		EncryptionContext map[string]string `json:"EncryptionContext,omitempty"`
+	}{keyID, wrapped, encCtx}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+// call выполняет подписанный вызов операции KMS
+func (c *Client) call(ctx context.Context, operation string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+operation)
+	SignRequest(req, payload, c.creds, c.region, serviceName, c.now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errResp struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &errResp) == nil {
+			// __type может содержать пространство имен: com.amazonaws.kms#NotFoundException
+			if i := strings.LastIndex(errResp.Type, "#"); i >= 0 {
+				errResp.Type = errResp.Type[i+1:]
+			}
+			apiErr.Type = errResp.Type
+			apiErr.Message = errResp.Message
+		}
+		return apiErr
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse kms response: %w", err)
+	}
+	return nil
+}
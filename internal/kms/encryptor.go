@@ -0,0 +1,284 @@
+package kms
+
+import (
+	"container/list"
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// AttrDataKey атрибут конверта с ключом данных, зашифрованным KMS
+const AttrDataKey = "dk"
+
+// dataKey открытый ключ данных и его зашифрованная KMS копия
+type dataKey struct {
+	id      dataKeyID
+	aead    cipher.AEAD
+	expires time.Time
+}
+
+// dataKeyID ключ кэша: зашифрованный ключ данных и алгоритм, для которого
+// создан AEAD. Тот же ключ данных в конверте другого алгоритма расшифровывается заново.
+type dataKeyID struct {
+	alg     string
+	wrapped string
+}
+
+// Encryptor реализует interfaces.Encryptor поверх ключей данных KMS.
+// Значение шифруется локально ключом данных, а зашифрованный ключ данных
+// записывается в конверт: ENC[AES256;dk=...:data]. Ключ данных для шифрования
+// обновляется по истечении TTL, расшифрованные ключи кэшируются на то же время
+// в LRU-кэше ограниченного размера.
+type Encryptor struct {
+	client *Client
+	keyID  string
+	encCtx map[string]string
+	alg    encryption.Algorithm
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	current *dataKey
+	cache   *dataKeyCache
+}
+
+// NewEncryptor создает шифровальщик KMS для алгоритма algorithm
+func NewEncryptor(cfg *config.KMSConfig, algorithm string) (*Encryptor, error) {
+	if algorithm == "" {
+		algorithm = encryption.DefaultAlgorithm
+	}
+	alg, ok := encryption.LookupAlgorithm(algorithm)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidConfig, algorithm)
+	}
+	ttl := cfg.DataKeyTTL
+	if ttl == 0 {
+		ttl = config.DefaultDataKeyTTL
+	}
+	size := cfg.DataKeyCacheSize
+	if size <= 0 {
+		size = config.DefaultDataKeyCacheSize
+	}
+	return &Encryptor{
+		client: NewClient(cfg),
+		keyID:  cfg.KeyID,
+		encCtx: cfg.EncryptionContext,
+		alg:    alg,
+		ttl:    ttl,
+		now:    time.Now,
+		cache:  newDataKeyCache(size),
+	}, nil
+}
+
+// KeyID возвращает идентификатор мастер-ключа KMS
+func (e *Encryptor) KeyID() string {
+	return "kms/" + e.keyID
+}
+
+// Encrypt шифрует данные
+func (e *Encryptor) Encrypt(text string) (string, error) {
+	return e.EncryptContext(context.Background(), text)
+}
+
+// Decrypt расшифровывает данные
+func (e *Encryptor) Decrypt(encrypted string) (string, error) {
+	return e.DecryptContext(context.Background(), encrypted)
+}
+
+// EncryptContext шифрует данные текущим ключом данных
+func (e *Encryptor) EncryptContext(ctx context.Context, text string) (string, error) {
+	key, err := e.encryptionKey(ctx)
+	if err != nil {
+		return "", wrapError(ctx, interfaces.ErrEncryptionFailed, err)
+	}
+	env := &encryption.Envelope{
+		Algorithm: e.alg.Name,
+		Attrs:     map[string]string{AttrDataKey: key.id.wrapped},
+	}
+	if err := encryption.SealEnvelope(env, key.aead, []byte(text)); err != nil {
+		return "", err
+	}
+	return env.String(), nil
+}
+
+// DecryptContext расшифровывает данные ключом данных из конверта
+func (e *Encryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	env, err := encryption.ParseEnvelope(encrypted)
+	if err != nil {
+		return "", err
+	}
+	wrapped := env.Attrs[AttrDataKey]
+	if wrapped == "" {
+		return "", fmt.Errorf("%w: missing kms data key", interfaces.ErrInvalidData)
+	}
+	alg, ok := encryption.LookupAlgorithm(env.Algorithm)
+	if !ok {
+		return "", fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidData, env.Algorithm)
+	}
+
+	key, err := e.decryptionKey(ctx, alg, wrapped)
+	if err != nil {
+		return "", wrapError(ctx, interfaces.ErrDecryptionFailed, err)
+	}
+	plaintext, err := encryption.OpenEnvelope(env, key.aead)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", interfaces.ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptionKey возвращает текущий ключ данных, запрашивая новый по истечении TTL
+func (e *Encryptor) encryptionKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	if e.current != nil && now.Before(e.current.expires) {
+		return e.current, nil
+	}
+
+	plaintext, wrapped, err := e.client.GenerateDataKey(ctx, e.keyID, e.alg.KeySize, e.encCtx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.newDataKey(e.alg, plaintext, base64.RawURLEncoding.EncodeToString(wrapped), now)
+	if err != nil {
+		return nil, err
+	}
+	e.current = key
+	e.cache.add(key, now)
+	return key, nil
+}
+
+// decryptionKey возвращает ключ данных из кэша или расшифровывает его через KMS
+func (e *Encryptor) decryptionKey(ctx context.Context, alg encryption.Algorithm, wrapped string) (*dataKey, error) {
+	now := e.now()
+	e.mu.Lock()
+	key, ok := e.cache.get(dataKeyID{alg: alg.Name, wrapped: wrapped}, now)
+	e.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	blob, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kms data key: %w", err)
+	}
+	plaintext, err := e.client.Decrypt(ctx, e.keyID, blob, e.encCtx)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) != alg.KeySize {
+		return nil, fmt.Errorf("kms data key has %d bytes, %s needs %d", len(plaintext), alg.Name, alg.KeySize)
+	}
+	key, err = e.newDataKey(alg, plaintext, wrapped, now)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cache.add(key, now)
+	return key, nil
+}
+
+// newDataKey создает AEAD на ключе данных и обнуляет открытую копию ключа
+func (e *Encryptor) newDataKey(alg encryption.Algorithm, plaintext []byte, wrapped string, now time.Time) (*dataKey, error) {
+	aead, err := alg.NewAEAD(plaintext)
+	for i := range plaintext {
+		plaintext[i] = 0
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &dataKey{id: dataKeyID{alg: alg.Name, wrapped: wrapped}, aead: aead, expires: now.Add(e.ttl)}, nil
+}
+
+// dataKeyCache LRU-кэш ключей данных; доступ защищает Encryptor.mu
+type dataKeyCache struct {
+	size  int
+	order *list.List
+	items map[dataKeyID]*list.Element
+}
+
+func newDataKeyCache(size int) *dataKeyCache {
+	return &dataKeyCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[dataKeyID]*list.Element),
+	}
+}
+
+// get возвращает ключ с неистекшим TTL; истекший ключ удаляется из кэша
+func (c *dataKeyCache) get(id dataKeyID, now time.Time) (*dataKey, bool) {
+	el, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	key := el.Value.(*dataKey)
+	if !now.Before(key.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return key, true
+}
+
+// add добавляет ключ, удаляя ключи с истекшим TTL и давно не использованные сверх размера кэша
+func (c *dataKeyCache) add(key *dataKey, now time.Time) {
+	if el, ok := c.items[key.id]; ok {
+		c.remove(el)
+	}
+	c.items[key.id] = c.order.PushFront(key)
+	for el := c.order.Back(); el != nil; {
+		prev := el.Prev()
+		if c.order.Len() > c.size || !now.Before(el.Value.(*dataKey).expires) {
+			c.remove(el)
+		}
+		el = prev
+	}
+}
+
+func (c *dataKeyCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*dataKey).id)
+}
+
+// wrapError оборачивает ошибку KMS в sentinel; отмена контекста возвращается как есть
+func wrapError(ctx context.Context, sentinel, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("%w: %v", sentinel, err)
+}
+
+// Provider реализует interfaces.EncryptorProvider для AWS KMS
+type Provider struct{}
+
+// NewProvider создает провайдер шифровальщиков KMS
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// ProvideEncryptor предоставляет шифровальщик KMS по настройкам cfg.KMS
+func (p *Provider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext предоставляет шифровальщик KMS с учетом контекста
+func (p *Provider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cfg.KMS == nil {
+		return nil, fmt.Errorf("%w: aws kms is not configured", interfaces.ErrInvalidConfig)
+	}
+	return NewEncryptor(cfg.KMS, cfg.Algorithm)
+}
@@ -0,0 +1,189 @@
+// Package kmstest содержит совместимый с AWS KMS тестовый HTTP-сервер
+package kmstest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/JohnnyFes/go-encryptor/internal/kms"
+)
+
+// Server заглушка KMS, реализующая GenerateDataKey и Decrypt JSON API
+// с проверкой подписи SigV4
+type Server struct {
+	*httptest.Server
+
+	creds kms.Credentials
+
+	mu    sync.Mutex
+	keys  map[string][]byte
+	calls map[string]int
+}
+
+// NewServer запускает заглушку KMS, принимающую запросы, подписанные creds
+func NewServer(creds kms.Credentials) *Server {
+	s := &Server{
+		creds: creds,
+		keys:  make(map[string][]byte),
+		calls: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddKey создает мастер-ключ с идентификатором keyID
+func (s *Server) AddKey(keyID string) {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[keyID] = key
+}
+
+// Calls возвращает число успешных вызовов операции (например, "GenerateDataKey")
+func (s *Server) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[operation]
+}
+
+type request struct {
+	KeyID             string            `json:"KeyId"`
+	NumberOfBytes     int               `json:"NumberOfBytes"`
+	CiphertextBlob    []byte            `json:"CiphertextBlob"`
+	EncryptionContext map[string]string `json:"EncryptionContext"`
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := kms.VerifyRequest(r, body, s.creds); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidSignatureException", err.Error())
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "TrentService.")
+	var ok bool
+	switch operation {
+	case "GenerateDataKey":
+		ok = s.generateDataKey(w, req)
+	case "Decrypt":
+		ok = s.decrypt(w, req)
+	default:
+		writeError(w, http.StatusBadRequest, "UnknownOperationException", operation)
+	}
+	if ok {
+		s.calls[operation]++
+	}
+}
+
+func (s *Server) generateDataKey(w http.ResponseWriter, req request) bool {
+	master, ok := s.keys[req.KeyID]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "NotFoundException", "key "+req.KeyID+" does not exist")
+		return false
+	}
+	if req.NumberOfBytes < 1 || req.NumberOfBytes > 1024 {
+		writeError(w, http.StatusBadRequest, "ValidationException", "invalid NumberOfBytes")
+		return false
+	}
+
+	plaintext := make([]byte, req.NumberOfBytes)
+	_, _ = rand.Read(plaintext)
+
+	// Блоб: идентификатор ключа, 0, nonce и шифротекст; контекст аутентифицируется
+	aead := newAEAD(master)
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	blob := append([]byte(req.KeyID), 0)
+	blob = append(blob, nonce...)
+	blob = aead.Seal(blob, nonce, plaintext, contextAAD(req.EncryptionContext))
+
+	writeJSON(w, map[string]interface{}{
+		"KeyId":          req.KeyID,
+		"Plaintext":      plaintext,
+		"CiphertextBlob": blob,
+	})
+	return true
+}
+
+func (s *Server) decrypt(w http.ResponseWriter, req request) bool {
+	keyID, rest, ok := bytes.Cut(req.CiphertextBlob, []byte{0})
+	master, known := s.keys[string(keyID)]
+	if !ok || !known {
+		writeError(w, http.StatusBadRequest, "InvalidCiphertextException", "invalid ciphertext")
+		return false
+	}
+	if req.KeyID != "" && req.KeyID != string(keyID) {
+		writeError(w, http.StatusBadRequest, "IncorrectKeyException", "ciphertext was encrypted with a different key")
+		return false
+	}
+
+	aead := newAEAD(master)
+	if len(rest) < aead.NonceSize() {
+		writeError(w, http.StatusBadRequest, "InvalidCiphertextException", "invalid ciphertext")
+		return false
+	}
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], contextAAD(req.EncryptionContext))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidCiphertextException", "invalid ciphertext or encryption context")
+		return false
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"KeyId":     string(keyID),
+		"Plaintext": plaintext,
+	})
+	return true
+}
+
+// contextAAD сериализует контекст шифрования в детерминированном порядке
+func contextAAD(encCtx map[string]string) []byte {
+	keys := make([]string, 0, len(encCtx))
+	for k := range encCtx {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString(k + "=" + encCtx[k] + "\n")
+	}
+	return b.Bytes()
+}
+
+func newAEAD(key []byte) cipher.AEAD {
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	return aead
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, errType, msg string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"__type":  "com.amazonaws.kms#" + errType,
+		"message": msg,
+	})
+}
@@ -1,6 +1,10 @@
 package sensitive
 
 import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
 	"reflect"
 
 	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
@@ -20,41 +24,326 @@ func NewFieldEncryptor(encryptor interfaces.Encryptor) *FieldEncryptor {
 
 // HandleFields обрабатывает поля структуры, шифруя или расшифровывая их
 func (h *FieldEncryptor) HandleFields(data interface{}, encrypt bool) error {
+	return h.HandleFieldsContext(context.Background(), data, encrypt)
+}
+
+// HandleFieldsContext обрабатывает поля структуры с учетом контекста:
+// при отмене контекста обработка прерывается перед следующим полем.
+// Вложенные структуры, указатели на структуры, встроенные структуры и интерфейсы
+// со структурами обходятся рекурсивно; неэкспортируемые поля пропускаются.
+// В помеченных полях-срезах, массивах и картах обрабатываются все строковые
+// элементы и значения; структуры внутри них обходятся так же, как поля.
+// Поле []byte заменяется конвертом ENC[...] в виде байтов, поля типов,
+// реализующих interfaces.FieldSealer, шифруют себя сами. Для других типов
+// (числа, time.Time) тег encrypted:"true" дает ошибку ErrUnsupportedField.
+func (h *FieldEncryptor) HandleFieldsContext(ctx context.Context, data interface{}, encrypt bool) error {
 	val := reflect.ValueOf(data)
 	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
 		return interfaces.ErrInvalidData
 	}
 
-	val = val.Elem()
+	w := &fieldWalker{
+		ctx:     ctx,
+		encrypt: encrypt,
+		handler: h,
+		visited: make(map[visit]bool),
+		leaves:  make(map[reflect.Type]bool),
+	}
+	return w.walk(val, "", false)
+}
+
+var (
+	sealerType          = reflect.TypeOf((*interfaces.FieldSealer)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// visit указатель, срез или карта, уже обойденные при обработке: защищает
+// от циклов и от повторного шифрования значения, доступного по нескольким путям.
+// tagged входит в ключ, если от тега зависит обработка: *string, достигнутый
+// через поле без тега, не обрабатывается и не должен скрыть тот же указатель
+// в помеченном поле.
+type visit struct {
+	ptr    uintptr
+	len    int
+	typ    reflect.Type
+	tagged bool
+}
+
+// fieldWalker обходит значение и обрабатывает помеченные строковые поля
+type fieldWalker struct {
+	ctx     context.Context
+	encrypt bool
+	handler *FieldEncryptor
+	visited map[visit]bool
+	// leaves - кэш hasTaggedLeaves по типам
+	leaves map[reflect.Type]bool
+}
+
+// seen отмечает указатель, срез или карту как обойденные и сообщает, встречались ли они раньше
+func (w *fieldWalker) seen(val reflect.Value, tagged bool) bool {
+	v := visit{ptr: val.Pointer(), typ: val.Type(), tagged: tagged && w.hasTaggedLeaves(val.Type())}
+	if val.Kind() == reflect.Slice {
+		v.len = val.Len()
+	}
+	if w.visited[v] {
+		return true
+	}
+	w.visited[v] = true
+	return false
+}
+
+// walk обходит значение: структуры, указатели, интерфейсы, срезы, массивы и карты.
+// tagged - значение находится в поле с тегом encrypted:"true", и его строки
+// и байты обрабатываются. path - путь к значению для сообщений об ошибках
+// (например, Database.Password или Secrets["api"])
+func (w *fieldWalker) walk(val reflect.Value, path string, tagged bool) error {
+	if val.CanAddr() && val.Addr().Type().Implements(sealerType) {
+		return w.handleSealer(val.Addr().Interface().(interfaces.FieldSealer), path)
+	}
+
+	switch val.Kind() {
+	case reflect.String:
+		if tagged {
+			return w.handleString(val, path)
+		}
+		return nil
+
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		if tagged {
+			return unsupported(val.Type(), path)
+		}
+		return nil
+
+	case reflect.Ptr:
+		if val.IsNil() || w.seen(val, tagged) {
+			return nil
+		}
+		return w.walk(val.Elem(), path, tagged)
+
+	case reflect.Interface:
+		if val.IsNil() {
+			return nil
+		}
+		elem := val.Elem()
+		switch elem.Kind() {
+		case reflect.Struct, reflect.Array, reflect.String, reflect.Slice:
+			// Значение в интерфейсе неизменяемо: обрабатываем копию и записываем ее обратно
+			if !val.CanSet() {
+				return nil
+			}
+			copied := reflect.New(elem.Type()).Elem()
+			copied.Set(elem)
+			if err := w.walk(copied, path, tagged); err != nil {
+				return err
+			}
+			val.Set(copied)
+			return nil
+		}
+		return w.walk(elem, path, tagged)
+
+	case reflect.Struct:
+		if tagged && selfMarshaling(val.Type()) {
+			return unsupported(val.Type(), path)
+		}
+		return w.walkStruct(val, path)
+
+	case reflect.Slice:
+		if tagged && val.Type().Elem().Kind() == reflect.Uint8 {
+			return w.handleBytes(val, path)
+		}
+		if val.IsNil() || !mayContain(val.Type().Elem(), tagged) || w.seen(val, tagged) {
+			return nil
+		}
+		return w.walkElems(val, path, tagged)
+
+	case reflect.Array:
+		if !mayContain(val.Type().Elem(), tagged) {
+			return nil
+		}
+		return w.walkElems(val, path, tagged)
+
+	case reflect.Map:
+		if val.IsNil() || !mayContain(val.Type().Elem(), tagged) || w.seen(val, tagged) {
+			return nil
+		}
+		return w.walkMap(val, path, tagged)
+	}
+	return nil
+}
+
+// walkElems обходит элементы среза или массива
+func (w *fieldWalker) walkElems(val reflect.Value, path string, tagged bool) error {
+	for i := 0; i < val.Len(); i++ {
+		if err := w.walk(val.Index(i), fmt.Sprintf("%s[%d]", path, i), tagged); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkMap обходит значения карты; ключи не шифруются. Значения карты
+// неизменяемы, поэтому обрабатывается копия, которая записывается обратно.
+func (w *fieldWalker) walkMap(val reflect.Value, path string, tagged bool) error {
+	iter := val.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		keyPath := fmt.Sprintf("%s[%v]", path, key)
+		if key.Kind() == reflect.String {
+			keyPath = fmt.Sprintf("%s[%q]", path, key.String())
+		}
+
+		copied := reflect.New(val.Type().Elem()).Elem()
+		copied.Set(iter.Value())
+		if err := w.walk(copied, keyPath, tagged); err != nil {
+			return err
+		}
+		val.SetMapIndex(key, copied)
+	}
+	return nil
+}
+
+// hasTaggedLeaves сообщает, зависит ли обработка значений типа typ от тега поля:
+// тег действует на строки, байты и скаляры, до которых можно дойти, не заходя
+// в структуру (у полей структуры свои теги)
+func (w *fieldWalker) hasTaggedLeaves(typ reflect.Type) bool {
+	if v, ok := w.leaves[typ]; ok {
+		return v
+	}
+	// Для рекурсивных типов (type list []list) считаем, что тег не важен
+	w.leaves[typ] = false
+	var v bool
+	switch typ.Kind() {
+	case reflect.Struct:
+		v = false
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		v = w.hasTaggedLeaves(typ.Elem())
+	default:
+		v = true
+	}
+	w.leaves[typ] = v
+	return v
+}
+
+// mayContain сообщает, могут ли значения типа typ содержать что-то для обработки:
+// значения в помеченном поле или структуры с помеченными полями
+func mayContain(typ reflect.Type, tagged bool) bool {
+	if tagged {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// walkStruct обрабатывает поля структуры
+func (w *fieldWalker) walkStruct(val reflect.Value, path string) error {
 	typ := val.Type()
 
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
 		fieldType := typ.Field(i)
 
+		// Неэкспортируемые поля пропускаются; у встроенной неэкспортируемой
+		// структуры обрабатываются ее экспортируемые поля, как в encoding/json
+		if !fieldType.IsExported() && !(fieldType.Anonymous && field.Kind() == reflect.Struct) {
+			continue
+		}
+
+		fieldPath := fieldType.Name
+		if path != "" {
+			fieldPath = path + "." + fieldType.Name
+		}
+
 		// Проверяем тег encrypted
-		if fieldType.Tag.Get("encrypted") == "true" {
-			if field.Kind() != reflect.String {
-				continue
-			}
+		if err := w.walk(field, fieldPath, fieldType.Tag.Get("encrypted") == "true"); err != nil {
+			return err
+		}
+	}
 
-			value := field.String()
-			var result string
-			var err error
+	return nil
+}
 
-			if encrypt {
-				result, err = h.encryptor.Encrypt(value)
-			} else {
-				result, err = h.encryptor.Decrypt(value)
-			}
+// selfMarshaling сообщает, что структура сериализует себя сама (как time.Time):
+// это значение, а не набор полей, и шифротекст в ней не сохранить
+func selfMarshaling(typ reflect.Type) bool {
+	ptr := reflect.PointerTo(typ)
+	return ptr.Implements(jsonMarshalerType) || ptr.Implements(textMarshalerType) || ptr.Implements(binaryMarshalerType)
+}
 
-			if err != nil {
-				return err
-			}
+// unsupported возвращает ошибку для помеченного значения, которое не может хранить шифротекст
+func unsupported(typ reflect.Type, path string) error {
+	return fmt.Errorf("field %s: %w %s: use encryption.Encrypted[%s] or []byte", path, interfaces.ErrUnsupportedField, typ, typ)
+}
 
-			field.SetString(result)
-		}
+// handleSealer шифрует или расшифровывает поле, которое делает это само
+func (w *fieldWalker) handleSealer(sealer interfaces.FieldSealer, path string) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	if w.encrypt {
+		err = sealer.SealField(w.ctx, w.handler.encryptor)
+	} else {
+		err = sealer.OpenField(w.ctx, w.handler.encryptor)
+	}
+	if err != nil {
+		return fmt.Errorf("field %s: %w", path, err)
+	}
+	return nil
+}
+
+// handleBytes заменяет байты конвертом ENC[...] (и обратно). Байты шифруются
+// как есть, без кодирования; пустой срез (и nil) не меняется: сериализация
+// часто превращает nil в пустой срез, и расшифровать его было бы нельзя.
+func (w *fieldWalker) handleBytes(field reflect.Value, path string) error {
+	if field.Len() == 0 || !field.CanSet() {
+		return nil
+	}
+	result, err := w.transform(string(field.Bytes()), path)
+	if err != nil {
+		return err
 	}
+	field.SetBytes([]byte(result))
+	return nil
+}
 
+// handleString шифрует или расшифровывает строку
+func (w *fieldWalker) handleString(field reflect.Value, path string) error {
+	if !field.CanSet() {
+		return nil
+	}
+	result, err := w.transform(field.String(), path)
+	if err != nil {
+		return err
+	}
+	field.SetString(result)
 	return nil
 }
+
+// transform шифрует или расшифровывает значение поля
+func (w *fieldWalker) transform(value, path string) (string, error) {
+	if err := w.ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var result string
+	var err error
+
+	if w.encrypt {
+		result, err = w.handler.encryptor.EncryptContext(w.ctx, value)
+	} else {
+		result, err = w.handler.encryptor.DecryptContext(w.ctx, value)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("field %s: %w", path, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,250 @@
+package sshagent
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// Namespace пространство имен подписи (как в ssh-keygen -Y sign -n), чтобы
+	// подпись запроса нельзя было выдать за подпись для другой цели
+	Namespace = "go-encryptor"
+	// Challenge подписываемое сообщение
+	Challenge = "go-encryptor/ssh-agent-key/v1"
+	// hkdfInfoPrefix контекст HKDF для ключа, полученного из подписи
+	hkdfInfoPrefix = "go-encryptor/ssh-agent/"
+)
+
+// SignedData возвращает данные, которые подписывает агент: структура SSHSIG
+// (PROTOCOL.sshsig) с пространством имен Namespace и SHA-512 от Challenge
+func SignedData() []byte {
+	hash := sha512.Sum512([]byte(Challenge))
+	return append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		Hash      string
+		Message   []byte
+	}{Namespace, "", "sha512", hash[:]})...)
+}
+
+// DeriveKey получает ключ длиной size байт для алгоритма algorithm из подписи
+// агента ключом key. Принимаются только ключи Ed25519: их подпись детерминирована.
+func DeriveKey(signer agent.ExtendedAgent, key ssh.PublicKey, algorithm string, size int) ([]byte, error) {
+	if key.Type() != ssh.KeyAlgoED25519 {
+		return nil, fmt.Errorf("%w: ssh key %s is %s, only %s signatures are deterministic",
+			interfaces.ErrInvalidConfig, ssh.FingerprintSHA256(key), key.Type(), ssh.KeyAlgoED25519)
+	}
+	data := SignedData()
+	sig, err := signer.Sign(key, data)
+	if err != nil {
+		return nil, fmt.Errorf("ssh-agent failed to sign with %s: %w", ssh.FingerprintSHA256(key), err)
+	}
+	if err := key.Verify(data, sig); err != nil {
+		return nil, fmt.Errorf("ssh-agent returned an invalid signature: %w", err)
+	}
+
+	out := make([]byte, size)
+	r := hkdf.New(sha256.New, sig.Blob, key.Marshal(), []byte(hkdfInfoPrefix+algorithm))
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return out, nil
+}
+
+// Encryptor реализует interfaces.Encryptor с ключом из подписи ssh-agent.
+// Отпечаток открытого ключа SSH записывается в конверт как kid:
+// ENC[AES256;kid=SHA256%3A...:...]. При расшифровке ключ выбирается по kid
+// среди ключей агента.
+type Encryptor struct {
+	conn   net.Conn
+	client agent.ExtendedAgent
+	alg    encryption.Algorithm
+	active string
+
+	mu    sync.Mutex
+	aeads map[string]cipher.AEAD
+}
+
+// NewEncryptor подключается к ssh-agent и получает ключ шифрования
+func NewEncryptor(cfg *config.SSHAgentConfig, algorithm string) (*Encryptor, error) {
+	alg, ok := encryption.LookupAlgorithm(algorithm)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidConfig, algorithm)
+	}
+	conn, err := net.Dial("unix", cfg.Socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+	e := &Encryptor{
+		conn:   conn,
+		client: agent.NewClient(conn),
+		alg:    alg,
+		aeads:  make(map[string]cipher.AEAD),
+	}
+
+	key, err := e.selectKey(cfg.Fingerprint)
+	if err == nil {
+		e.active = ssh.FingerprintSHA256(key)
+		_, err = e.aeadFor(e.active)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// selectKey выбирает ключ агента по отпечатку или первый ключ Ed25519
+func (e *Encryptor) selectKey(fingerprint string) (ssh.PublicKey, error) {
+	keys, err := e.client.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ssh-agent keys: %w", err)
+	}
+	for _, k := range keys {
+		if fingerprint == "" && k.Type() == ssh.KeyAlgoED25519 || fingerprint != "" && ssh.FingerprintSHA256(k) == fingerprint {
+			return k, nil
+		}
+	}
+	if fingerprint != "" {
+		return nil, fmt.Errorf("%w: ssh-agent has no key %s", interfaces.ErrInvalidConfig, fingerprint)
+	}
+	return nil, fmt.Errorf("%w: ssh-agent has no %s key", interfaces.ErrInvalidConfig, ssh.KeyAlgoED25519)
+}
+
+// aeadFor возвращает AEAD ключа с отпечатком fingerprint, получая ключ при первом обращении
+func (e *Encryptor) aeadFor(fingerprint string) (cipher.AEAD, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if aead, ok := e.aeads[fingerprint]; ok {
+		return aead, nil
+	}
+
+	key, err := e.selectKey(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := DeriveKey(e.client, key, e.alg.Name, e.alg.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for i := range raw {
+			raw[i] = 0
+		}
+	}()
+	aead, err := e.alg.NewAEAD(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	e.aeads[fingerprint] = aead
+	return aead, nil
+}
+
+// KeyID возвращает отпечаток ключа SSH, которым шифруются значения
+func (e *Encryptor) KeyID() string {
+	return e.active
+}
+
+// Close закрывает соединение с ssh-agent
+func (e *Encryptor) Close() error {
+	return e.conn.Close()
+}
+
+// Encrypt шифрует данные
+func (e *Encryptor) Encrypt(text string) (string, error) {
+	return e.EncryptContext(context.Background(), text)
+}
+
+// Decrypt расшифровывает данные
+func (e *Encryptor) Decrypt(encrypted string) (string, error) {
+	return e.DecryptContext(context.Background(), encrypted)
+}
+
+// EncryptContext шифрует данные ключом, полученным из подписи агента
+func (e *Encryptor) EncryptContext(ctx context.Context, text string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	aead, err := e.aeadFor(e.active)
+	if err != nil {
+		return "", err
+	}
+	env := &encryption.Envelope{
+		Algorithm: e.alg.Name,
+		Attrs:     map[string]string{encryption.AttrKeyID: e.active},
+	}
+	if err := encryption.SealEnvelope(env, aead, []byte(text)); err != nil {
+		return "", err
+	}
+	return env.String(), nil
+}
+
+// DecryptContext расшифровывает данные ключом SSH, отпечаток которого записан в конверте
+func (e *Encryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	env, err := encryption.ParseEnvelope(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if env.Algorithm != e.alg.Name {
+		return "", fmt.Errorf("%w: value is %s, ssh-agent key is used with %s", interfaces.ErrInvalidData, env.Algorithm, e.alg.Name)
+	}
+
+	fingerprint := e.active
+	if kid, ok := env.Attrs[encryption.AttrKeyID]; ok {
+		fingerprint = kid
+	}
+	aead, err := e.aeadFor(fingerprint)
+	if err != nil {
+		return "", fmt.Errorf("%w: value was encrypted with ssh key %s: %v", interfaces.ErrDecryptionFailed, fingerprint, err)
+	}
+	plaintext, err := encryption.OpenEnvelope(env, aead)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", interfaces.ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+// Provider реализует interfaces.EncryptorProvider для ключа из ssh-agent
+type Provider struct{}
+
+// NewProvider создает провайдер шифровальщиков с ключом из ssh-agent
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// ProvideEncryptor предоставляет шифровальщик по настройкам cfg.SSHAgent
+func (p *Provider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext предоставляет шифровальщик с учетом контекста
+func (p *Provider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cfg.SSHAgent == nil {
+		return nil, fmt.Errorf("%w: ssh-agent is not configured", interfaces.ErrInvalidConfig)
+	}
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = encryption.DefaultAlgorithm
+	}
+	return NewEncryptor(cfg.SSHAgent, algorithm)
+}
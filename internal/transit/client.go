@@ -0,0 +1,227 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	defaultMount        = "transit"
+	defaultAppRoleMount = "approle"
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 100 * time.Millisecond
+	defaultTimeout      = 30 * time.Second
+)
+
+// APIError ошибка, которую вернул Vault
+type APIError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("vault returned %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// Client клиент HTTP API Vault для движка Transit
+type Client struct {
+	cfg  config.TransitConfig
+	http *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewClient создает клиента Vault по настройкам Transit
+func NewClient(cfg *config.TransitConfig) *Client {
+	c := &Client{
+		cfg:   *cfg,
+		http:  cfg.HTTPClient,
+		token: cfg.Token,
+	}
+	c.cfg.Address = strings.TrimRight(c.cfg.Address, "/")
+	if c.cfg.Mount == "" {
+		c.cfg.Mount = defaultMount
+	}
+	if c.cfg.AppRoleMount == "" {
+		c.cfg.AppRoleMount = defaultAppRoleMount
+	}
+	if c.cfg.MaxRetries == 0 {
+		c.cfg.MaxRetries = defaultMaxRetries
+	}
+	if c.cfg.RetryBackoff == 0 {
+		c.cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: defaultTimeout}
+	}
+	return c
+}
+
+// Encrypt шифрует данные ключом Transit и возвращает шифротекст вида vault:vN:...
+func (c *Client) Encrypt(ctx context.Context, plaintext []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	err := c.call(ctx, "/"+c.cfg.Mount+"/encrypt/"+c.cfg.KeyName, map[string]interface{}{
+		"plaintext": plaintext,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+// Decrypt расшифровывает шифротекст Transit вида vault:vN:...
+func (c *Client) Decrypt(ctx context.Context, ciphertext string) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext []byte `json:"plaintext"`
+		} `json:"data"`
+	}
+	err := c.call(ctx, "/"+c.cfg.Mount+"/decrypt/"+c.cfg.KeyName, map[string]interface{}{
+		"ciphertext": ciphertext,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.Plaintext, nil
+}
+
+// call выполняет запрос к Vault с авторизацией и повторами.
+// При входе через AppRole истекший токен (403) обновляется один раз.
+func (c *Client) call(ctx context.Context, path string, body, out interface{}) error {
+	token, err := c.authToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.do(ctx, path, token, body, out)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden && c.cfg.RoleID != "" {
+		c.mu.Lock()
+		if c.token == token {
+			c.token = ""
+		}
+		c.mu.Unlock()
+		if token, err = c.authToken(ctx); err != nil {
+			return err
+		}
+		err = c.do(ctx, path, token, body, out)
+	}
+	return err
+}
+
+// authToken возвращает токен, при необходимости выполняя вход через AppRole
+func (c *Client) authToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	err := c.do(ctx, "/auth/"+c.cfg.AppRoleMount+"/login", "", map[string]string{
+		"role_id":   c.cfg.RoleID,
+		"secret_id": c.cfg.SecretID,
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("approle login failed: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", errors.New("approle login returned no token")
+	}
+	c.token = resp.Auth.ClientToken
+	return c.token, nil
+}
+
+// do отправляет POST-запрос и повторяет его при сетевых ошибках и ответах 5xx/429
+func (c *Client) do(ctx context.Context, path, token string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	backoff := c.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = c.doOnce(ctx, path, token, payload, out)
+		if err == nil || attempt >= c.cfg.MaxRetries || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) doOnce(ctx context.Context, path, token string, payload []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Address+"/v1"+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.cfg.Namespace)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &errResp) == nil {
+			apiErr.Errors = errResp.Errors
+		}
+		return apiErr
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	return nil
+}
+
+// retryable сообщает, имеет ли смысл повторить запрос
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	// Сетевые ошибки повторяем
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,128 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// AttrKeyID атрибут конверта с идентификатором ключа Transit вида имя/vN
+	AttrKeyID = encryption.AttrKeyID
+	// vaultPrefix префикс шифротекста Transit
+	vaultPrefix = "vault:"
+)
+
+// Encryptor реализует interfaces.Encryptor, делегируя шифрование Vault Transit.
+// Шифротекст vault:vN:data хранится в конверте ENC[TRANSIT;kid=key/vN:data],
+// так что версия ключа Vault становится частью идентификатора ключа.
+type Encryptor struct {
+	client  *Client
+	keyName string
+}
+
+// NewEncryptor создает шифровальщик Transit
+func NewEncryptor(cfg *config.TransitConfig) *Encryptor {
+	return &Encryptor{
+		client:  NewClient(cfg),
+		keyName: cfg.KeyName,
+	}
+}
+
+// KeyID возвращает идентификатор ключа Transit
+func (e *Encryptor) KeyID() string {
+	return "transit/" + e.keyName
+}
+
+// Encrypt шифрует данные
+func (e *Encryptor) Encrypt(text string) (string, error) {
+	return e.EncryptContext(context.Background(), text)
+}
+
+// Decrypt расшифровывает данные
+func (e *Encryptor) Decrypt(encrypted string) (string, error) {
+	return e.DecryptContext(context.Background(), encrypted)
+}
+
+// EncryptContext шифрует данные через Vault
+func (e *Encryptor) EncryptContext(ctx context.Context, text string) (string, error) {
+	ciphertext, err := e.client.Encrypt(ctx, []byte(text))
+	if err != nil {
+		return "", wrapError(ctx, interfaces.ErrEncryptionFailed, err)
+	}
+
+	// vault:v3:data -> версия v3 и данные
+	parts := strings.SplitN(strings.TrimPrefix(ciphertext, vaultPrefix), ":", 2)
+	if !strings.HasPrefix(ciphertext, vaultPrefix) || len(parts) != 2 {
+		return "", fmt.Errorf("%w: unexpected transit ciphertext format", interfaces.ErrEncryptionFailed)
+	}
+
+	env := &encryption.Envelope{
+		Algorithm: config.AlgorithmTransit,
+		Attrs:     map[string]string{AttrKeyID: e.keyName + "/" + parts[0]},
+		Payload:   parts[1],
+	}
+	return env.String(), nil
+}
+
+// DecryptContext расшифровывает данные через Vault
+func (e *Encryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	env, err := encryption.ParseEnvelope(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if env.Algorithm != config.AlgorithmTransit {
+		return "", fmt.Errorf("%w: unsupported algorithm %q", interfaces.ErrInvalidData, env.Algorithm)
+	}
+
+	keyName, version, ok := strings.Cut(env.Attrs[AttrKeyID], "/")
+	if !ok || !strings.HasPrefix(version, "v") {
+		return "", fmt.Errorf("%w: missing transit key version", interfaces.ErrInvalidData)
+	}
+	if keyName != e.keyName {
+		return "", fmt.Errorf("%w: value encrypted with transit key %q, encryptor uses %q",
+			interfaces.ErrDecryptionFailed, keyName, e.keyName)
+	}
+
+	plaintext, err := e.client.Decrypt(ctx, vaultPrefix+version+":"+env.Payload)
+	if err != nil {
+		return "", wrapError(ctx, interfaces.ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+// wrapError оборачивает ошибку Vault в sentinel; отмена контекста возвращается как есть
+func wrapError(ctx context.Context, sentinel, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("%w: %v", sentinel, err)
+}
+
+// Provider реализует interfaces.EncryptorProvider для Vault Transit
+type Provider struct{}
+
+// NewProvider создает провайдер шифровальщиков Transit
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// ProvideEncryptor предоставляет шифровальщик Transit по настройкам cfg.Transit
+func (p *Provider) ProvideEncryptor(cfg *config.Config) (interfaces.Encryptor, error) {
+	return p.ProvideEncryptorContext(context.Background(), cfg)
+}
+
+// ProvideEncryptorContext предоставляет шифровальщик Transit с учетом контекста
+func (p *Provider) ProvideEncryptorContext(ctx context.Context, cfg *config.Config) (interfaces.Encryptor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cfg.Transit == nil {
+		return nil, fmt.Errorf("%w: vault transit is not configured", interfaces.ErrInvalidConfig)
+	}
+	return NewEncryptor(cfg.Transit), nil
+}
@@ -0,0 +1,210 @@
+// Package transittest содержит поддельный HTTP-сервер Vault Transit для тестов
+package transittest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server поддельный Vault, реализующий encrypt/decrypt движка Transit,
+// ротацию ключей и вход через AppRole
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	tokens   map[string]bool
+	approles map[string]string
+	keys     map[string][][]byte
+	failNext int
+	requests int
+}
+
+// NewServer запускает поддельный Vault, принимающий токен rootToken
+func NewServer(rootToken string) *Server {
+	s := &Server{
+		tokens:   map[string]bool{rootToken: true},
+		approles: make(map[string]string),
+		keys:     make(map[string][][]byte),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddAppRole регистрирует пару role_id/secret_id для входа через AppRole
+func (s *Server) AddAppRole(roleID, secretID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.approles[roleID] = secretID
+}
+
+// RevokeTokens отзывает все токены, выданные через AppRole, и rootToken
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+// RotateKey добавляет новую версию ключа; старые версии продолжают расшифровывать
+func (s *Server) RotateKey(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[name] = append(s.keys[name], newKey())
+}
+
+// FailNext заставляет следующие n запросов вернуть 503
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+}
+
+// Requests возвращает число обработанных запросов
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if s.failNext > 0 {
+		s.failNext--
+		writeError(w, http.StatusServiceUnavailable, "vault is sealed")
+		return
+	}
+
+	var body map[string]string
+	data, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(data, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == "auth/approle/login" {
+		s.login(w, body)
+		return
+	}
+
+	if !s.tokens[r.Header.Get("X-Vault-Token")] {
+		writeError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 || parts[0] != "transit" {
+		writeError(w, http.StatusNotFound, "no handler for route")
+		return
+	}
+	switch parts[1] {
+	case "encrypt":
+		s.encrypt(w, parts[2], body["plaintext"])
+	case "decrypt":
+		s.decrypt(w, parts[2], body["ciphertext"])
+	default:
+		writeError(w, http.StatusNotFound, "no handler for route")
+	}
+}
+
+func (s *Server) login(w http.ResponseWriter, body map[string]string) {
+	secret, ok := s.approles[body["role_id"]]
+	if !ok || secret != body["secret_id"] {
+		writeError(w, http.StatusBadRequest, "invalid role or secret ID")
+		return
+	}
+	token := "s." + base64.RawURLEncoding.EncodeToString(newKey()[:12])
+	s.tokens[token] = true
+	writeJSON(w, map[string]interface{}{
+		"auth": map[string]string{"client_token": token},
+	})
+}
+
+func (s *Server) encrypt(w http.ResponseWriter, name, plaintext string) {
+	raw, err := base64.StdEncoding.DecodeString(plaintext)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "plaintext must be base64")
+		return
+	}
+	// Как и Vault, создаем ключ при первом использовании
+	if len(s.keys[name]) == 0 {
+		s.keys[name] = [][]byte{newKey()}
+	}
+	version := len(s.keys[name])
+	aead := newAEAD(s.keys[name][version-1])
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	ct := aead.Seal(nonce, nonce, raw, nil)
+
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]string{
+			"ciphertext": fmt.Sprintf("vault:v%d:%s", version, base64.StdEncoding.EncodeToString(ct)),
+		},
+	})
+}
+
+func (s *Server) decrypt(w http.ResponseWriter, name, ciphertext string) {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		writeError(w, http.StatusBadRequest, "invalid ciphertext")
+		return
+	}
+	version, err := strconv.Atoi(parts[1][1:])
+	if err != nil || version < 1 || version > len(s.keys[name]) {
+		writeError(w, http.StatusBadRequest, "invalid key version")
+		return
+	}
+	ct, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid ciphertext")
+		return
+	}
+	aead := newAEAD(s.keys[name][version-1])
+	if len(ct) < aead.NonceSize() {
+		writeError(w, http.StatusBadRequest, "invalid ciphertext")
+		return
+	}
+	plaintext, err := aead.Open(nil, ct[:aead.NonceSize()], ct[aead.NonceSize():], nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "cipher: message authentication failed")
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)},
+	})
+}
+
+func newKey() []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}
+
+func newAEAD(key []byte) cipher.AEAD {
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	return aead
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {msg}})
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+const (
+	// AgentSockEnv переменная окружения с путем к сокету агента ключей
+	AgentSockEnv = "ENCRYPTOR_AGENT_SOCK"
+	// DefaultAgentTimeout время ожидания ответа агента по умолчанию
+	DefaultAgentTimeout = 5 * time.Second
+)
+
+var (
+	// ErrAgentUnavailable ошибка, если агент ключей не запущен или его сокет небезопасен
+	ErrAgentUnavailable = errors.New("key agent is not available")
+	// ErrAgentLocked ошибка, если агент ключей заблокирован по таймауту
+	ErrAgentLocked = errors.New("key agent is locked")
+)
+
+// AgentConfig настройки агента ключей: процесса, который один раз разблокировал
+// ключи и выполняет шифрование по запросам через Unix-сокет
+type AgentConfig struct {
+	// Socket - путь к сокету агента (по умолчанию из AgentSockEnv)
+	Socket string
+	// Timeout - время ожидания ответа (по умолчанию DefaultAgentTimeout)
+	Timeout time.Duration
+}
+
+// NewAgentConfig создает конфигурацию, в которой шифрование выполняет агент ключей.
+// Пустой socket означает сокет из переменной окружения AgentSockEnv.
+func NewAgentConfig(socket string, opts ...Option) (*Config, error) {
+	if socket == "" {
+		socket = os.Getenv(AgentSockEnv)
+	}
+	if socket == "" {
+		return nil, errors.New("agent socket is required: set " + AgentSockEnv)
+	}
+
+	cfg := &Config{
+		KeyLength: DefaultKeyLength,
+		Algorithm: AlgorithmAES256GCM,
+		Agent:     &AgentConfig{Socket: socket},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
+
+// AgentSocket возвращает сокет агента из переменной окружения AgentSockEnv
+func AgentSocket() (string, bool) {
+	socket := os.Getenv(AgentSockEnv)
+	return socket, socket != ""
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MinKeyComponents минимальное число компонент ключа: ключ из одной компоненты
+// известен одному хранителю, и раздельного знания нет
+const MinKeyComponents = 2
+
+var (
+	// ErrKeyComponent ошибка, если компонента ключа отсутствует или неверна
+	ErrKeyComponent = errors.New("invalid key component")
+	// ErrKeyCheckValue ошибка, если контрольное значение (KCV) не совпадает
+	ErrKeyCheckValue = errors.New("key check value mismatch")
+)
+
+// KeyComponent компонента ключа у одного хранителя
+type KeyComponent struct {
+	// Name - название компоненты для сообщений (например, имя хранителя)
+	Name string
+	// Source - источник компоненты: файл, переменная окружения или запрос в терминале
+	Source KeySource
+	// CheckValue - KCV компоненты (KeyCheckValue); если задан, сверяется при загрузке
+	CheckValue string
+}
+
+// ComponentKeySource собирает ключ из компонент разных хранителей: ключ равен
+// XOR всех компонент (раздельное знание и двойной контроль). Компоненты
+// кодируются как ключ (base64 или с префиксом hex:/raw:) и должны быть одной длины.
+// Если какая-то компонента отсутствует или ее KCV не совпадает, ключ не собирается.
+type ComponentKeySource struct {
+	// Components - компоненты ключа, не меньше MinKeyComponents
+	Components []KeyComponent
+	// CheckValue - KCV собранного ключа; если задан, сверяется
+	CheckValue string
+}
+
+// LoadKey загружает компоненты и возвращает собранный ключ в base64
+func (s *ComponentKeySource) LoadKey() (string, error) {
+	if len(s.Components) < MinKeyComponents {
+		return "", fmt.Errorf("%w: %d component(s) given, split key needs at least %d", ErrKeyComponent, len(s.Components), MinKeyComponents)
+	}
+
+	var key []byte
+	defer func() { zero(key) }()
+	for i, c := range s.Components {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		part, err := loadComponent(c)
+		if err != nil {
+			return "", fmt.Errorf("%w %s: %w", ErrKeyComponent, name, err)
+		}
+		if key == nil {
+			key = part
+			continue
+		}
+		if len(part) != len(key) {
+			zero(part)
+			return "", fmt.Errorf("%w %s: %d bytes, other components are %d bytes", ErrKeyComponent, name, len(part), len(key))
+		}
+		subtle.XORBytes(key, key, part)
+		zero(part)
+	}
+
+	if err := checkValue(key, s.CheckValue, "assembled key"); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// loadComponent загружает, декодирует и проверяет одну компоненту
+func loadComponent(c KeyComponent) ([]byte, error) {
+	if c.Source == nil {
+		return nil, errors.New("component is missing")
+	}
+	value, err := c.Source.LoadKey()
+	if err != nil {
+		return nil, err
+	}
+	part, err := ParseKey(value, KeyEncodingBase64)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkValue(part, c.CheckValue, "component"); err != nil {
+		zero(part)
+		return nil, err
+	}
+	return part, nil
+}
+
+// checkValue сверяет KCV ключа с ожидаемым, если он задан
+func checkValue(key []byte, want, what string) error {
+	if want == "" {
+		return nil
+	}
+	if got := KeyCheckValue(key); !strings.EqualFold(got, want) {
+		return fmt.Errorf("%w: %s has KCV %s, expected %s", ErrKeyCheckValue, what, got, strings.ToUpper(want))
+	}
+	return nil
+}
+
+// GenerateKeyComponents создает n случайных компонент для ключа алгоритма alg.
+// Ключ - XOR всех компонент; каждая компонента по отдельности о нем ничего не говорит.
+func GenerateKeyComponents(alg string, n int) ([][]byte, error) {
+	if n < MinKeyComponents {
+		return nil, fmt.Errorf("%w: split key needs at least %d components, got %d", ErrKeyComponent, MinKeyComponents, n)
+	}
+	size, ok := keySizes[alg]
+	if !ok {
+		return nil, fmt.Errorf("cannot generate key for unsupported algorithm %q", alg)
+	}
+	components := make([][]byte, n)
+	for i := range components {
+		components[i] = make([]byte, size)
+		if _, err := rand.Read(components[i]); err != nil {
+			return nil, fmt.Errorf("failed to generate key component: %w", err)
+		}
+	}
+	return components, nil
+}
+
+// CombineKeyComponents возвращает ключ - XOR компонент одной длины
+func CombineKeyComponents(components [][]byte) ([]byte, error) {
+	if len(components) < MinKeyComponents {
+		return nil, fmt.Errorf("%w: split key needs at least %d components, got %d", ErrKeyComponent, MinKeyComponents, len(components))
+	}
+	key := make([]byte, len(components[0]))
+	for i, c := range components {
+		if len(c) != len(key) {
+			return nil, fmt.Errorf("%w %d: %d bytes, expected %d", ErrKeyComponent, i+1, len(c), len(key))
+		}
+		subtle.XORBytes(key, key, c)
+	}
+	return key, nil
+}
+
+// PromptKeySource запрашивает ключ (например, компоненту ключа у хранителя)
+// функцией Prompt, которая обычно читает его из терминала без эха
+type PromptKeySource struct {
+	// Label - что запрашивается (выводится в подсказке)
+	Label string
+	// Prompt - функция запроса
+	Prompt func(label string) (string, error)
+}
+
+// LoadKey запрашивает ключ
+func (s *PromptKeySource) LoadKey() (string, error) {
+	if s.Prompt == nil {
+		return "", fmt.Errorf("no prompt for %s", s.Label)
+	}
+	value, err := s.Prompt(s.Label)
+	if err != nil {
+		return "", err
+	}
+	return trimKey(value, s.Label)
+}
+
+// zero затирает ключевой материал
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
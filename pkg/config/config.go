@@ -1,8 +1,8 @@
 package config
 
 import (
-	"encoding/base64"
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -20,8 +20,37 @@ var (
 type Config struct {
 	// Key - ключ шифрования
 	Key string
-	// KeyLength - требуемая длина ключа
+	// KeyLength - требуемая длина ключа в байтах после декодирования
 	KeyLength int
+	// KeyEncoding - кодировка ключа без префикса (по умолчанию base64)
+	KeyEncoding KeyEncoding
+	// LegacyKey - прежняя обработка ключа с дополнением и хэшированием (см. WithLegacyKey)
+	LegacyKey bool
+	// Algorithm - алгоритм шифрования
+	Algorithm string
+	// EmbedKeyID - записывать отпечаток ключа в конверт (см. WithEmbedKeyID)
+	EmbedKeyID bool
+	// Policy - политика, ограничивающая алгоритмы и ключи (nil - без ограничений)
+	Policy *Policy
+	// Transit - настройки Vault Transit; если заданы, Key не используется
+	Transit *TransitConfig
+	// KMS - настройки AWS KMS; если заданы, Key не используется
+	KMS *KMSConfig
+	// PKCS11 - настройки HSM через PKCS#11; если заданы, Key не используется
+	PKCS11 *PKCS11Config
+	// KeyHelper - внешняя программа, выдающая ключ; если задана, Key не используется
+	KeyHelper *KeyHelperConfig
+	// SSHAgent - ключ из подписи ssh-agent; если задан, Key не используется
+	SSHAgent *SSHAgentConfig
+	// KeyringPath - файл набора ключей; если задан, Key не используется
+	KeyringPath string
+	// KeyringPassphrase - запрос парольной фразы зашифрованного набора ключей
+	// (nil - из переменной окружения KeyringPassphraseEnv)
+	KeyringPassphrase func() ([]byte, error)
+	// KeyringKeyID - ожидаемый активный ключ набора (см. WithKeyringKeyID)
+	KeyringKeyID string
+	// Agent - агент ключей; если задан, шифрование выполняет агент, а Key не используется
+	Agent *AgentConfig
 }
 
 // Option функция для настройки конфигурации
@@ -34,11 +63,28 @@ func WithKeyLength(length int) Option {
 	}
 }
 
+// WithEmbedKeyID записывает в конверт отпечаток ключа (ENC[AES256;kid=...:...]).
+// Тогда значение, которое расшифровывают другим ключом, дает ErrKeyMismatch
+// с отпечатком нужного ключа, а не общую ошибку расшифровки.
+func WithEmbedKeyID() Option {
+	return func(c *Config) {
+		c.EmbedKeyID = true
+	}
+}
+
+// WithAlgorithm устанавливает алгоритм шифрования
+func WithAlgorithm(alg string) Option {
+	return func(c *Config) {
+		c.Algorithm = alg
+	}
+}
+
 // NewConfig создает новую конфигурацию
 func NewConfig(key string, opts ...Option) (*Config, error) {
 	cfg := &Config{
 		Key:       key,
 		KeyLength: DefaultKeyLength,
+		Algorithm: AlgorithmAES256GCM,
 	}
 
 	// Применяем опции
@@ -46,20 +92,23 @@ func NewConfig(key string, opts ...Option) (*Config, error) {
 		opt(cfg)
 	}
 
-	// Проверяем длину ключа
-	if len(key) < cfg.KeyLength {
-		return nil, ErrInvalidKeyLength
-	}
-
 	// Проверяем, что ключ не зашифрован
 	if strings.HasPrefix(key, "ENC[") {
 		return nil, errors.New("encrypted key is not allowed")
 	}
 
-	// Проверяем, что ключ в base64
-	if _, err := base64.StdEncoding.DecodeString(key); err == nil {
+	// В режиме совместимости длина проверяется по строке, ключ дополняется при создании шифратора
+	if cfg.LegacyKey {
+		if len(key) < cfg.KeyLength {
+			return nil, fmt.Errorf("%w: key is %d characters, expected at least %d", ErrInvalidKeyLength, len(key), cfg.KeyLength)
+		}
 		return cfg, nil
 	}
 
+	// Проверяем декодированный ключ: кодировку, длину и энтропию
+	if _, err := cfg.KeyBytes(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyEncoding кодировка ключа шифрования в строке конфигурации
+type KeyEncoding string
+
+const (
+	// KeyEncodingBase64 ключ в стандартном base64 (по умолчанию)
+	KeyEncodingBase64 KeyEncoding = "base64"
+	// KeyEncodingHex ключ в hex
+	KeyEncodingHex KeyEncoding = "hex"
+	// KeyEncodingRaw байты строки используются как ключ без декодирования
+	KeyEncodingRaw KeyEncoding = "raw"
+)
+
+var (
+	// ErrInvalidKeyEncoding ошибка, если ключ не декодируется в заявленной кодировке
+	ErrInvalidKeyEncoding = errors.New("invalid key encoding")
+	// ErrWeakKey ошибка, если в ключе слишком мало различных байтов
+	ErrWeakKey = errors.New("key has too little entropy")
+)
+
+// WithKeyEncoding задает кодировку ключей без префикса (base64:, hex:, raw:).
+// Префикс в самом ключе имеет приоритет.
+func WithKeyEncoding(enc KeyEncoding) Option {
+	return func(c *Config) {
+		c.KeyEncoding = enc
+	}
+}
+
+// WithLegacyKey включает совместимость с прежней обработкой ключа: длина проверяется
+// по строке, ключ декодируется из base64 (или берется как есть), а затем
+// дополняется нулями или хэшируется SHA-256 до размера ключа алгоритма.
+// Используйте только для значений, зашифрованных такими ключами.
+func WithLegacyKey() Option {
+	return func(c *Config) {
+		c.LegacyKey = true
+	}
+}
+
+// ParseKey декодирует ключ. Префикс base64:, hex: или raw: задает кодировку явно,
+// ключ без префикса декодируется в кодировке enc (пустая - base64).
+func ParseKey(key string, enc KeyEncoding) ([]byte, error) {
+	for _, prefixed := range []KeyEncoding{KeyEncodingBase64, KeyEncodingHex, KeyEncodingRaw} {
+		if rest, ok := strings.CutPrefix(key, string(prefixed)+":"); ok {
+			key, enc = rest, prefixed
+			break
+		}
+	}
+
+	switch enc {
+	case "", KeyEncodingBase64:
+		b, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key is not valid base64 (use the hex: or raw: prefix for other encodings)", ErrInvalidKeyEncoding)
+		}
+		return b, nil
+	case KeyEncodingHex:
+		b, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key is not valid hex", ErrInvalidKeyEncoding)
+		}
+		return b, nil
+	case KeyEncodingRaw:
+		return []byte(key), nil
+	}
+	return nil, fmt.Errorf("%w: unknown key encoding %q", ErrInvalidKeyEncoding, enc)
+}
+
+// KeyBytes возвращает декодированный ключ. Ключ должен быть ровно KeyLength байт
+// и не быть вырожденным (ErrWeakKey). В режиме совместимости (WithLegacyKey)
+// возвращается ключ из base64 или байты строки без проверок.
+func (c *Config) KeyBytes() ([]byte, error) {
+	if c.LegacyKey {
+		if b, err := base64.StdEncoding.DecodeString(c.Key); err == nil {
+			return b, nil
+		}
+		return []byte(c.Key), nil
+	}
+
+	key, err := ParseKey(c.Key, c.KeyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != c.KeyLength {
+		return nil, fmt.Errorf("%w: key decodes to %d bytes, expected exactly %d", ErrInvalidKeyLength, len(key), c.KeyLength)
+	}
+	if err := checkKeyEntropy(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// checkKeyEntropy отклоняет ключи, в которых меньше половины байтов различны.
+// У случайного 32-байтного ключа их в среднем около 30, а у ключей вроде
+// "1234567890..." или заполненных одним байтом - единицы.
+func checkKeyEntropy(key []byte) error {
+	var seen [256]bool
+	distinct := 0
+	for _, b := range key {
+		if !seen[b] {
+			seen[b] = true
+			distinct++
+		}
+	}
+	if distinct < len(key)/2 {
+		return fmt.Errorf("%w: only %d distinct byte values in a %d-byte key, generate a random key with keygen",
+			ErrWeakKey, distinct, len(key))
+	}
+	return nil
+}
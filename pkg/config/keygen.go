@@ -0,0 +1,95 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// keyFingerprintLabel метка HMAC для вычисления отпечатка ключа
+const keyFingerprintLabel = "go-encryptor/key-id"
+
+// keySizes размеры ключей алгоритмов, для которых можно сгенерировать ключ.
+// pkg/config не может импортировать реестр алгоритмов internal/encryption
+// (он сам импортирует pkg/config), поэтому совпадение таблиц проверяет
+// тест TestGenerateKey_MatchesRegistry.
+var keySizes = map[string]int{
+	AlgorithmAES256GCM:        32,
+	AlgorithmChaCha20Poly1305: 32,
+}
+
+// GenerateKey создает случайный ключ нужного алгоритму размера из crypto/rand
+func GenerateKey(alg string) ([]byte, error) {
+	size, ok := keySizes[alg]
+	if !ok {
+		return nil, fmt.Errorf("cannot generate key for unsupported algorithm %q", alg)
+	}
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// KeyFingerprint возвращает отпечаток ключа: первые 8 байт HMAC-SHA256(key, метка) в hex.
+// По отпечатку нельзя восстановить ключ, но он стабилен и совпадает с KeyID шифратора.
+func KeyFingerprint(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(keyFingerprintLabel))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// KeyCheckValue возвращает контрольное значение ключа (KCV): первые 3 байта
+// шифрования нулевого блока AES этим ключом в hex, как его показывают HSM
+// и системы управления ключами. Для ключей не AES-размера возвращает пустую строку.
+func KeyCheckValue(key []byte) string {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return ""
+	}
+	out := make([]byte, aes.BlockSize)
+	block.Encrypt(out, make([]byte, aes.BlockSize))
+	return strings.ToUpper(hex.EncodeToString(out[:3]))
+}
+
+// NewKeyringKey создает запись набора ключей со случайным ключом алгоритма alg.
+// Состояние не задается: его выбирает Keyring.Add.
+func NewKeyringKey(id, alg string) (KeyringKey, error) {
+	key, err := GenerateKey(alg)
+	if err != nil {
+		return KeyringKey{}, err
+	}
+	return KeyringKey{
+		ID:          id,
+		Algorithm:   alg,
+		Key:         base64.StdEncoding.EncodeToString(key),
+		Fingerprint: KeyFingerprint(key),
+		Created:     time.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// WriteKeyFile создает файл ключа с правами 0600. Существующий файл
+// не перезаписывается, чтобы случайно не потерять ключ.
+func WriteKeyFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
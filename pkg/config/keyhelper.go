@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultKeyHelperTimeout время ожидания ответа программы-помощника по умолчанию
+const DefaultKeyHelperTimeout = 10 * time.Second
+
+// KeyHelperConfig настройки внешней программы-помощника, выдающей ключ шифрования.
+// Программа получает JSON-запрос на stdin и отвечает JSON на stdout
+// (по аналогии с git credential helpers).
+type KeyHelperConfig struct {
+	// Command - путь к исполняемому файлу
+	Command string
+	// Args - аргументы командной строки
+	Args []string
+	// KeyID - идентификатор запрашиваемого ключа (необязательно)
+	KeyID string
+	// WrappedKey - зашифрованный ключ в base64; если задан, помощник его разворачивает
+	WrappedKey string
+	// Timeout - время ожидания ответа (по умолчанию DefaultKeyHelperTimeout)
+	Timeout time.Duration
+}
+
+// NewKeyHelperConfig создает конфигурацию, в которой ключ выдает программа-помощник
+func NewKeyHelperConfig(helper *KeyHelperConfig, opts ...Option) (*Config, error) {
+	if helper == nil || strings.TrimSpace(helper.Command) == "" {
+		return nil, errors.New("key helper command is required")
+	}
+
+	cfg := &Config{
+		KeyLength: DefaultKeyLength,
+		Algorithm: AlgorithmAES256GCM,
+		KeyHelper: helper,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,350 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyringVersion текущая версия формата файла ключей
+const KeyringVersion = 1
+
+// KeyState состояние ключа в жизненном цикле
+type KeyState string
+
+const (
+	// KeyStateActive ключ шифрует и расшифровывает; активный ключ в наборе один
+	KeyStateActive KeyState = "active"
+	// KeyStateDecryptOnly ключ только расшифровывает (новый ключ до продвижения или выведенный)
+	KeyStateDecryptOnly KeyState = "decrypt-only"
+	// KeyStateRevoked ключ отозван и не расшифровывает
+	KeyStateRevoked KeyState = "revoked"
+)
+
+// KeyringKey ключ в наборе ключей с метаданными
+type KeyringKey struct {
+	// ID - идентификатор ключа, записывается в конверт (kid)
+	ID string `json:"id" yaml:"id"`
+	// Algorithm - алгоритм шифрования (например, AES256)
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	// Key - ключ в base64
+	Key string `json:"key" yaml:"key"`
+	// Fingerprint - отпечаток ключа (KeyFingerprint); если задан, сверяется с ключом
+	Fingerprint string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	// State - состояние ключа
+	State KeyState `json:"state" yaml:"state"`
+	// Created - время создания
+	Created time.Time `json:"created" yaml:"created"`
+	// Expires - время, после которого ключ не шифрует (nil - бессрочно)
+	Expires *time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+}
+
+// Expired сообщает, истек ли срок действия ключа к моменту now
+func (k *KeyringKey) Expired(now time.Time) bool {
+	return k.Expires != nil && !now.Before(*k.Expires)
+}
+
+// Keyring версионированный набор ключей. Хранится в JSON или YAML
+// (по расширению .yaml/.yml) с правами не шире 0600, открытым текстом
+// или зашифрованным под парольной фразой (см. SetPassphrase).
+type Keyring struct {
+	Version int          `json:"version" yaml:"version"`
+	Keys    []KeyringKey `json:"keys" yaml:"keys"`
+
+	// passphrase - парольная фраза, под которой набор ключей сохраняется
+	passphrase []byte
+}
+
+// NewKeyring создает пустой набор ключей
+func NewKeyring() *Keyring {
+	return &Keyring{Version: KeyringVersion}
+}
+
+// LoadKeyring читает набор ключей из файла. Файл, доступный группе
+// или остальным пользователям, отклоняется (ErrInsecureKeyFile).
+// Для зашифрованного набора ключей парольная фраза берется из опций
+// WithPassphrase/WithPassphraseFunc, а без них - из KeyringPassphraseEnv.
+func LoadKeyring(path string, opts ...KeyringOption) (*Keyring, error) {
+	o := keyringOptions{passphrase: EnvPassphrase}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyring: %w", err)
+		}
+		if perm := info.Mode().Perm(); perm&0o077 != 0 {
+			return nil, fmt.Errorf("%w: %s has mode %04o, expected 0600 or stricter", ErrInsecureKeyFile, path, perm)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var file struct {
+		Keyring `yaml:",inline"`
+		Sealed  *sealedKeyring `json:"sealed" yaml:"sealed"`
+	}
+	if isYAMLPath(path) {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: %w", path, err)
+	}
+
+	ring := &file.Keyring
+	if file.Sealed != nil {
+		if file.Version != KeyringVersion {
+			return nil, fmt.Errorf("invalid keyring %s: unsupported keyring version %d", path, file.Version)
+		}
+		passphrase, err := o.passphrase()
+		if err != nil {
+			return nil, err
+		}
+		if ring, err = file.Sealed.open(passphrase); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := ring.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid keyring %s: %w", path, err)
+	}
+	return ring, nil
+}
+
+// Save атомарно записывает набор ключей в файл с правами 0600.
+// Если задана парольная фраза, ключи шифруются (Argon2id + AES-256-GCM).
+func (r *Keyring) Save(path string) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	var content interface{} = r
+	if r.Encrypted() {
+		plaintext, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to marshal keyring: %w", err)
+		}
+		sealed, err := r.seal(plaintext)
+		if err != nil {
+			return err
+		}
+		content = &sealedKeyringFile{Version: r.Version, Sealed: sealed}
+	}
+
+	var data []byte
+	var err error
+	if isYAMLPath(path) {
+		data, err = yaml.Marshal(content)
+	} else {
+		data, err = json.MarshalIndent(content, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal keyring: %w", err)
+	}
+	return writeFileAtomic(path, data, 0o600)
+}
+
+// Validate проверяет версию, уникальность идентификаторов, состояния и ключи
+func (r *Keyring) Validate() error {
+	if r.Version != KeyringVersion {
+		return fmt.Errorf("unsupported keyring version %d", r.Version)
+	}
+	seen := make(map[string]bool)
+	active := 0
+	for _, k := range r.Keys {
+		if k.ID == "" {
+			return errors.New("keyring key without id")
+		}
+		if seen[k.ID] {
+			return fmt.Errorf("duplicate keyring key id %q", k.ID)
+		}
+		seen[k.ID] = true
+		if k.Algorithm == "" {
+			return fmt.Errorf("key %q: algorithm is required", k.ID)
+		}
+		switch k.State {
+		case KeyStateActive:
+			active++
+		case KeyStateDecryptOnly, KeyStateRevoked:
+		default:
+			return fmt.Errorf("key %q: unknown state %q", k.ID, k.State)
+		}
+		raw, err := base64.StdEncoding.DecodeString(k.Key)
+		if err != nil || k.Key == "" {
+			return fmt.Errorf("key %q: key must be base64", k.ID)
+		}
+		if k.Fingerprint != "" && k.Fingerprint != KeyFingerprint(raw) {
+			return fmt.Errorf("key %q: fingerprint %s does not match the key", k.ID, k.Fingerprint)
+		}
+	}
+	if active > 1 {
+		return fmt.Errorf("keyring has %d active keys, at most one is allowed", active)
+	}
+	return nil
+}
+
+// Lookup возвращает ключ по идентификатору
+func (r *Keyring) Lookup(id string) (*KeyringKey, bool) {
+	for i := range r.Keys {
+		if r.Keys[i].ID == id {
+			return &r.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// Active возвращает активный ключ, если он есть
+func (r *Keyring) Active() (*KeyringKey, bool) {
+	for i := range r.Keys {
+		if r.Keys[i].State == KeyStateActive {
+			return &r.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// Add добавляет ключ. Если состояние не задано, первый ключ становится активным,
+// а следующие добавляются только для расшифровки до явного продвижения (Promote).
+// Набор проверяется до изменения: при ошибке он остается прежним.
+func (r *Keyring) Add(key KeyringKey) error {
+	if _, ok := r.Lookup(key.ID); ok {
+		return fmt.Errorf("key %q already exists", key.ID)
+	}
+	if key.Created.IsZero() {
+		key.Created = time.Now().UTC()
+	}
+	if key.State == "" {
+		key.State = KeyStateDecryptOnly
+		if _, ok := r.Active(); !ok {
+			key.State = KeyStateActive
+		}
+	}
+	next := Keyring{Version: r.Version, Keys: append(append([]KeyringKey(nil), r.Keys...), key)}
+	if key.State == KeyStateActive {
+		for i := range next.Keys[:len(r.Keys)] {
+			if next.Keys[i].State == KeyStateActive {
+				next.Keys[i].State = KeyStateDecryptOnly
+			}
+		}
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	r.Keys = next.Keys
+	return nil
+}
+
+// Promote делает ключ активным; прежний активный ключ остается для расшифровки
+func (r *Keyring) Promote(id string) error {
+	key, ok := r.Lookup(id)
+	if !ok {
+		return fmt.Errorf("key %q not found", id)
+	}
+	if key.State == KeyStateRevoked {
+		return fmt.Errorf("key %q is revoked and cannot be promoted", id)
+	}
+	if active, ok := r.Active(); ok {
+		active.State = KeyStateDecryptOnly
+	}
+	key.State = KeyStateActive
+	return nil
+}
+
+// Retire переводит ключ в режим только расшифровки
+func (r *Keyring) Retire(id string) error {
+	return r.setState(id, KeyStateDecryptOnly)
+}
+
+// Revoke отзывает ключ: значения, зашифрованные им, больше не расшифровываются
+func (r *Keyring) Revoke(id string) error {
+	return r.setState(id, KeyStateRevoked)
+}
+
+func (r *Keyring) setState(id string, state KeyState) error {
+	key, ok := r.Lookup(id)
+	if !ok {
+		return fmt.Errorf("key %q not found", id)
+	}
+	key.State = state
+	return nil
+}
+
+// WithKeyringFile задает файл набора ключей; ключ Key при этом не используется
+func WithKeyringFile(path string) Option {
+	return func(c *Config) {
+		c.KeyringPath = path
+	}
+}
+
+// NewKeyringConfig создает конфигурацию, в которой ключи берутся из файла набора ключей.
+// Файл читается при создании шифратора.
+func NewKeyringConfig(path string, opts ...Option) (*Config, error) {
+	if path == "" {
+		return nil, errors.New("keyring path is required")
+	}
+	cfg := &Config{
+		KeyLength:   DefaultKeyLength,
+		Algorithm:   AlgorithmAES256GCM,
+		KeyringPath: path,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
+
+// WithKeyringKeyID задает ключ набора, которым должны шифроваться новые значения.
+// Шифрует только активный ключ: если ключ id не активен (только для расшифровки
+// или отозван), создание шифратора завершается ошибкой interfaces.ErrNoActiveKey
+// (interfaces.ErrKeyRevoked), а не шифрует устаревшим ключом.
+func WithKeyringKeyID(id string) Option {
+	return func(c *Config) {
+		c.KeyringKeyID = id
+	}
+}
+
+func isYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// writeFileAtomic записывает файл через временный файл и rename
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KeyringPassphraseEnv переменная окружения с парольной фразой набора ключей (для CI)
+const KeyringPassphraseEnv = "ENCRYPTOR_KEYRING_PASSPHRASE"
+
+// Параметры Argon2id для новых наборов ключей (RFC 9106, второй рекомендуемый вариант)
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	// argon2MaxMemory ограничивает память из файла, чтобы испорченный файл не исчерпал ее
+	argon2MaxMemory = 4 * 1024 * 1024
+	// argon2MaxTime ограничивает число проходов из файла
+	argon2MaxTime = 64
+)
+
+// keyringSealAAD связывает шифротекст с форматом набора ключей
+const keyringSealAAD = "go-encryptor/keyring/v1"
+
+var (
+	// ErrKeyringLocked ошибка, если набор ключей зашифрован, а парольная фраза не задана
+	ErrKeyringLocked = errors.New("keyring is encrypted with a passphrase")
+	// ErrKeyringPassphrase ошибка при неверной парольной фразе или поврежденном наборе ключей
+	ErrKeyringPassphrase = errors.New("wrong keyring passphrase or corrupted keyring")
+)
+
+// sealedKeyring набор ключей, зашифрованный AES-256-GCM ключом из парольной фразы (Argon2id)
+type sealedKeyring struct {
+	KDF        string `json:"kdf" yaml:"kdf"`
+	Salt       string `json:"salt" yaml:"salt"`
+	Time       uint32 `json:"time" yaml:"time"`
+	Memory     uint32 `json:"memory" yaml:"memory"`
+	Threads    uint8  `json:"threads" yaml:"threads"`
+	Cipher     string `json:"cipher" yaml:"cipher"`
+	Nonce      string `json:"nonce" yaml:"nonce"`
+	Ciphertext string `json:"ciphertext" yaml:"ciphertext"`
+}
+
+// sealedKeyringFile содержимое файла зашифрованного набора ключей
+type sealedKeyringFile struct {
+	Version int            `json:"version" yaml:"version"`
+	Sealed  *sealedKeyring `json:"sealed" yaml:"sealed"`
+}
+
+// KeyringOption настраивает чтение набора ключей
+type KeyringOption func(*keyringOptions)
+
+type keyringOptions struct {
+	passphrase func() ([]byte, error)
+}
+
+// WithPassphrase задает парольную фразу зашифрованного набора ключей
+func WithPassphrase(passphrase []byte) KeyringOption {
+	return func(o *keyringOptions) {
+		o.passphrase = func() ([]byte, error) { return passphrase, nil }
+	}
+}
+
+// WithPassphraseFunc задает функцию, которая запрашивает парольную фразу.
+// Она вызывается только для зашифрованного набора ключей.
+func WithPassphraseFunc(fn func() ([]byte, error)) KeyringOption {
+	return func(o *keyringOptions) {
+		o.passphrase = fn
+	}
+}
+
+// WithKeyringPassphrase задает функцию, запрашивающую парольную фразу набора ключей cfg.KeyringPath
+func WithKeyringPassphrase(fn func() ([]byte, error)) Option {
+	return func(c *Config) {
+		c.KeyringPassphrase = fn
+	}
+}
+
+// EnvPassphrase возвращает парольную фразу из переменной окружения KeyringPassphraseEnv
+func EnvPassphrase() ([]byte, error) {
+	value, ok := os.LookupEnv(KeyringPassphraseEnv)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%w: set %s or enter the passphrase interactively", ErrKeyringLocked, KeyringPassphraseEnv)
+	}
+	return []byte(value), nil
+}
+
+// SetPassphrase задает парольную фразу, под которой Save зашифрует набор ключей.
+// Пустая фраза сохраняет набор ключей открытым текстом.
+func (r *Keyring) SetPassphrase(passphrase []byte) {
+	r.passphrase = append([]byte(nil), passphrase...)
+}
+
+// Encrypted сообщает, сохраняется ли набор ключей под парольной фразой
+func (r *Keyring) Encrypted() bool {
+	return len(r.passphrase) > 0
+}
+
+// seal шифрует набор ключей парольной фразой
+func (r *Keyring) seal(plaintext []byte) (*sealedKeyring, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	s := &sealedKeyring{
+		KDF:     KDFArgon2id,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Time:    argon2Time,
+		Memory:  argon2Memory,
+		Threads: argon2Threads,
+		Cipher:  AlgorithmAES256GCM,
+	}
+	aead, err := s.aead(r.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	s.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	s.Ciphertext = base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, []byte(keyringSealAAD)))
+	return s, nil
+}
+
+// open расшифровывает набор ключей парольной фразой
+func (s *sealedKeyring) open(passphrase []byte) (*Keyring, error) {
+	aead, err := s.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(s.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrKeyringPassphrase)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(s.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ciphertext", ErrKeyringPassphrase)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keyringSealAAD))
+	if err != nil {
+		return nil, ErrKeyringPassphrase
+	}
+
+	ring := &Keyring{}
+	if err := json.Unmarshal(plaintext, ring); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted keyring: %w", err)
+	}
+	ring.SetPassphrase(passphrase)
+	return ring, nil
+}
+
+// aead получает ключ из парольной фразы Argon2id и создает AES-256-GCM
+func (s *sealedKeyring) aead(passphrase []byte) (cipher.AEAD, error) {
+	if s.KDF != KDFArgon2id || s.Cipher != AlgorithmAES256GCM {
+		return nil, fmt.Errorf("unsupported keyring encryption %s/%s", s.KDF, s.Cipher)
+	}
+	if s.Time == 0 || s.Time > argon2MaxTime || s.Memory == 0 || s.Memory > argon2MaxMemory || s.Threads == 0 {
+		return nil, fmt.Errorf("invalid Argon2id parameters: time=%d memory=%d threads=%d", s.Time, s.Memory, s.Threads)
+	}
+	salt, err := base64.StdEncoding.DecodeString(s.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("invalid keyring salt")
+	}
+
+	key := argon2.IDKey(passphrase, salt, s.Time, s.Memory, s.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+var (
+	// ErrEmptyKey ошибка, если источник вернул пустой ключ
+	ErrEmptyKey = errors.New("encryption key is empty")
+	// ErrInsecureKeyFile ошибка, если файл ключа доступен группе или остальным пользователям
+	ErrInsecureKeyFile = errors.New("key file is accessible by group or others")
+)
+
+// KeySource источник ключа шифрования
+type KeySource interface {
+	// LoadKey возвращает ключ шифрования
+	LoadKey() (string, error)
+}
+
+// EnvKeySource читает ключ из переменной окружения
+type EnvKeySource struct {
+	// Name - имя переменной окружения
+	Name string
+}
+
+// LoadKey возвращает значение переменной окружения
+func (s *EnvKeySource) LoadKey() (string, error) {
+	value, ok := os.LookupEnv(s.Name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", s.Name)
+	}
+	return trimKey(value, "environment variable "+s.Name)
+}
+
+// FileKeySource читает ключ из файла. Файл, доступный группе или остальным
+// пользователям, отклоняется, если не указан AllowInsecurePermissions.
+type FileKeySource struct {
+	// Path - путь к файлу ключа
+	Path string
+	// AllowInsecurePermissions - не проверять права доступа к файлу
+	AllowInsecurePermissions bool
+}
+
+// LoadKey возвращает содержимое файла без завершающих пробелов и переводов строки
+func (s *FileKeySource) LoadKey() (string, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open key file: %w", err)
+	}
+	defer f.Close()
+
+	if !s.AllowInsecurePermissions && runtime.GOOS != "windows" {
+		info, err := f.Stat()
+		if err != nil {
+			return "", fmt.Errorf("failed to stat key file: %w", err)
+		}
+		if perm := info.Mode().Perm(); perm&0o077 != 0 {
+			return "", fmt.Errorf("%w: %s has mode %04o, expected 0600 or stricter", ErrInsecureKeyFile, s.Path, perm)
+		}
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file: %w", err)
+	}
+	return trimKey(string(data), "key file "+s.Path)
+}
+
+// ReaderKeySource читает ключ из потока (например, stdin) до EOF
+type ReaderKeySource struct {
+	// Reader - поток с ключом
+	Reader io.Reader
+	// Name - название источника для сообщений об ошибках
+	Name string
+}
+
+// StdinKeySource возвращает источник, читающий ключ из стандартного ввода
+func StdinKeySource() *ReaderKeySource {
+	return &ReaderKeySource{Reader: os.Stdin, Name: "stdin"}
+}
+
+// LoadKey читает ключ из потока
+func (s *ReaderKeySource) LoadKey() (string, error) {
+	data, err := io.ReadAll(s.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key from %s: %w", s.Name, err)
+	}
+	return trimKey(string(data), s.Name)
+}
+
+// FDKeySource читает ключ из унаследованного файлового дескриптора
+// (например, bash: 3< <(vault read ...)). Дескриптор закрывается после чтения.
+type FDKeySource struct {
+	// FD - номер файлового дескриптора
+	FD uintptr
+}
+
+// LoadKey читает ключ из файлового дескриптора
+func (s *FDKeySource) LoadKey() (string, error) {
+	f := os.NewFile(s.FD, fmt.Sprintf("fd%d", s.FD))
+	if f == nil {
+		return "", fmt.Errorf("invalid file descriptor %d", s.FD)
+	}
+	defer f.Close()
+	return (&ReaderKeySource{Reader: f, Name: f.Name()}).LoadKey()
+}
+
+// NewConfigFromSource создает конфигурацию с ключом из источника
+func NewConfigFromSource(src KeySource, opts ...Option) (*Config, error) {
+	key, err := src.LoadKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewConfig(key, opts...)
+}
+
+// trimKey удаляет завершающие пробелы и переводы строки и проверяет, что ключ не пуст
+func trimKey(key, source string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("%w: %s", ErrEmptyKey, source)
+	}
+	return key, nil
+}
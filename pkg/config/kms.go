@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// DefaultDataKeyTTL время, в течение которого используется и кэшируется ключ данных KMS
+const DefaultDataKeyTTL = 5 * time.Minute
+
+// DefaultDataKeyCacheSize сколько расшифрованных ключей данных KMS хранится в кэше по умолчанию
+const DefaultDataKeyCacheSize = 1024
+
+// KMSConfig настройки шифрования ключами данных AWS KMS (envelope encryption).
+// Мастер-ключ хранится в KMS, локально используются ключи данных,
+// а их зашифрованная KMS копия записывается в конверт.
+type KMSConfig struct {
+	// KeyID - идентификатор, ARN или псевдоним (alias/...) ключа KMS
+	KeyID string
+	// Region - регион AWS (по умолчанию AWS_REGION или AWS_DEFAULT_REGION)
+	Region string
+	// Endpoint - адрес KMS (по умолчанию https://kms.<region>.amazonaws.com)
+	Endpoint string
+	// AccessKeyID, SecretAccessKey и SessionToken - учетные данные AWS
+	// (по умолчанию из переменных окружения AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN)
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// EncryptionContext - контекст шифрования KMS, привязанный к ключам данных
+	EncryptionContext map[string]string
+	// DataKeyTTL - время жизни ключа данных в кэше (по умолчанию DefaultDataKeyTTL)
+	DataKeyTTL time.Duration
+	// DataKeyCacheSize - сколько расшифрованных ключей данных хранится в LRU-кэше
+	// (по умолчанию DefaultDataKeyCacheSize)
+	DataKeyCacheSize int
+	// HTTPClient - HTTP-клиент (по умолчанию http.Client с таймаутом 30s)
+	HTTPClient *http.Client
+}
+
+// NewKMSConfig создает конфигурацию, в которой ключи данных выдает AWS KMS.
+// Незаданные регион и учетные данные берутся из стандартных переменных окружения AWS.
+// kms не изменяется: значения из окружения записываются в копию.
+func NewKMSConfig(kms *KMSConfig, opts ...Option) (*Config, error) {
+	if kms == nil || kms.KeyID == "" {
+		return nil, errors.New("kms key ID is required")
+	}
+	c := *kms
+	if c.Region == "" {
+		c.Region = firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	}
+	if c.AccessKeyID == "" && c.SecretAccessKey == "" {
+		c.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if c.Region == "" {
+		return nil, errors.New("kms region is required")
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return nil, errors.New("kms requires AWS access key ID and secret access key")
+	}
+
+	cfg := &Config{
+		KeyLength: DefaultKeyLength,
+		Algorithm: AlgorithmAES256GCM,
+		KMS:       &c,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
+
+// firstEnv возвращает первое непустое значение переменных окружения
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
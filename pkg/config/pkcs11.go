@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// PKCS11ModeGCM каждое значение шифруется AES-GCM внутри токена
+	PKCS11ModeGCM = "gcm"
+	// PKCS11ModeWrap значения шифруются локально ключом данных, который
+	// заворачивается и разворачивается ключом токена (CKM_AES_KEY_WRAP_PAD)
+	PKCS11ModeWrap = "wrap"
+)
+
+// PKCS11Config настройки шифрования ключом, хранящимся в HSM (PKCS#11).
+// Ключ не покидает токен; требуется сборка с cgo.
+type PKCS11Config struct {
+	// ModulePath - путь к библиотеке PKCS#11, например /usr/lib/softhsm/libsofthsm2.so
+	ModulePath string
+	// TokenLabel - метка токена; если пуста, используется Slot
+	TokenLabel string
+	// Slot - идентификатор слота
+	Slot uint
+	// PIN - PIN пользователя токена
+	PIN string
+	// KeyLabel - метка (CKA_LABEL) секретного AES-ключа в токене
+	KeyLabel string
+	// Mode - режим работы: PKCS11ModeGCM (по умолчанию) или PKCS11ModeWrap
+	Mode string
+}
+
+// NewPKCS11Config создает конфигурацию, в которой шифрование выполняет HSM
+func NewPKCS11Config(p *PKCS11Config, opts ...Option) (*Config, error) {
+	if p == nil || p.ModulePath == "" || p.KeyLabel == "" {
+		return nil, errors.New("pkcs11 module path and key label are required")
+	}
+	switch p.Mode {
+	case "":
+		p.Mode = PKCS11ModeGCM
+	case PKCS11ModeGCM, PKCS11ModeWrap:
+	default:
+		return nil, fmt.Errorf("unsupported pkcs11 mode %q", p.Mode)
+	}
+
+	cfg := &Config{
+		KeyLength: DefaultKeyLength,
+		Algorithm: AlgorithmAES256GCM,
+		PKCS11:    p,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Идентификаторы алгоритмов шифрования (совпадают с заголовком ENC[...])
+const (
+	// AlgorithmAES256GCM AES-256 в режиме GCM
+	AlgorithmAES256GCM = "AES256"
+	// AlgorithmChaCha20Poly1305 ChaCha20-Poly1305
+	AlgorithmChaCha20Poly1305 = "CHACHA20"
+	// AlgorithmAES256CBC AES-256-CBC (формат openssl enc)
+	AlgorithmAES256CBC = "AES256-CBC"
+	// AlgorithmAES256CTRHMAC AES-256-CTR с HMAC-SHA256 (формат Ansible Vault)
+	AlgorithmAES256CTRHMAC = "AES256-CTR-HMAC"
+)
+
+// Идентификаторы функций получения ключа
+const (
+	KDFHKDFSHA256    = "HKDF-SHA256"
+	KDFPBKDF2SHA256  = "PBKDF2-SHA256"
+	KDFPBKDF2SHA512  = "PBKDF2-SHA512"
+	KDFEVPBytesToKey = "EVP_BytesToKey"
+	KDFArgon2id      = "Argon2id"
+)
+
+// Идентификаторы кодов аутентификации сообщений
+const (
+	MACHMACSHA256 = "HMAC-SHA256"
+	MACHMACSHA384 = "HMAC-SHA384"
+	MACHMACSHA512 = "HMAC-SHA512"
+)
+
+var (
+	// ErrPolicyViolation ошибка при нарушении политики
+	ErrPolicyViolation = errors.New("policy violation")
+)
+
+// Policy ограничивает допустимые алгоритмы и параметры работы с ключами
+type Policy struct {
+	// Name - название политики
+	Name string
+	// Algorithms - разрешенные алгоритмы шифрования
+	Algorithms []string
+	// KDFs - разрешенные функции получения ключа
+	KDFs []string
+	// MACs - разрешенные коды аутентификации сообщений
+	MACs []string
+	// MinPBKDF2Iterations - минимальное число итераций PBKDF2
+	MinPBKDF2Iterations int
+	// ExactKeyLength - ключ должен иметь ровно KeyLength байт после декодирования,
+	// без дополнения нулями или хэширования
+	ExactKeyLength bool
+}
+
+// FIPSPolicy возвращает политику, разрешающую только одобренные алгоритмы:
+// AES-GCM, HKDF/PBKDF2 и HMAC-SHA2
+func FIPSPolicy() *Policy {
+	return &Policy{
+		Name:                "fips",
+		Algorithms:          []string{AlgorithmAES256GCM},
+		KDFs:                []string{KDFHKDFSHA256, KDFPBKDF2SHA256, KDFPBKDF2SHA512},
+		MACs:                []string{MACHMACSHA256, MACHMACSHA384, MACHMACSHA512},
+		MinPBKDF2Iterations: 100000,
+		ExactKeyLength:      true,
+	}
+}
+
+// LookupPolicy возвращает встроенную политику по имени
+func LookupPolicy(name string) (*Policy, error) {
+	switch name {
+	case "fips":
+		return FIPSPolicy(), nil
+	}
+	return nil, fmt.Errorf("unknown policy %q", name)
+}
+
+// WithPolicy устанавливает политику, которую соблюдает шифратор
+func WithPolicy(p *Policy) Option {
+	return func(c *Config) {
+		c.Policy = p
+	}
+}
+
+// CheckAlgorithm проверяет, что алгоритм шифрования разрешен политикой
+func (p *Policy) CheckAlgorithm(alg string) error {
+	if !contains(p.Algorithms, alg) {
+		return fmt.Errorf("%w: algorithm %s is not approved by policy %s", ErrPolicyViolation, alg, p.Name)
+	}
+	return nil
+}
+
+// CheckKDF проверяет функцию получения ключа и число итераций (для PBKDF2)
+func (p *Policy) CheckKDF(kdf string, iterations int) error {
+	if !contains(p.KDFs, kdf) {
+		return fmt.Errorf("%w: key derivation %s is not approved by policy %s", ErrPolicyViolation, kdf, p.Name)
+	}
+	if (kdf == KDFPBKDF2SHA256 || kdf == KDFPBKDF2SHA512) && iterations < p.MinPBKDF2Iterations {
+		return fmt.Errorf("%w: %s with %d iterations, policy %s requires at least %d",
+			ErrPolicyViolation, kdf, iterations, p.Name, p.MinPBKDF2Iterations)
+	}
+	return nil
+}
+
+// CheckMAC проверяет, что код аутентификации сообщений разрешен политикой
+func (p *Policy) CheckMAC(mac string) error {
+	if !contains(p.MACs, mac) {
+		return fmt.Errorf("%w: MAC %s is not approved by policy %s", ErrPolicyViolation, mac, p.Name)
+	}
+	return nil
+}
+
+// CheckConfig проверяет алгоритм, получение ключа и ключ конфигурации.
+// Функция получения ключа зашифрованного набора ключей (Argon2id) проверяется
+// при его чтении, а HKDF подключей арендаторов - при их создании.
+func (p *Policy) CheckConfig(c *Config) error {
+	if err := p.CheckAlgorithm(c.Algorithm); err != nil {
+		return err
+	}
+	// Ключ ssh-agent получается из подписи через HKDF-SHA256
+	if c.SSHAgent != nil {
+		if err := p.CheckKDF(KDFHKDFSHA256, 0); err != nil {
+			return err
+		}
+	}
+	// Ключ удаленного провайдера не проверяется локально
+	if !p.ExactKeyLength || c.Key == "" {
+		return nil
+	}
+	// Ключ не должен проходить через дополнение нулями или хэширование
+	keyBytes, err := c.KeyBytes()
+	if err != nil {
+		return err
+	}
+	if len(keyBytes) != c.KeyLength {
+		return fmt.Errorf("%w: key is %d bytes, policy %s requires exactly %d",
+			ErrPolicyViolation, len(keyBytes), p.Name, c.KeyLength)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName имя файла настроек проекта; ищется от рабочего каталога вверх
+const ProjectFileName = ".encryptor.yaml"
+
+var (
+	// ErrNoProjectConfig ошибка, если файл настроек проекта не найден
+	ErrNoProjectConfig = errors.New(ProjectFileName + " not found")
+	// ErrNoCreationRule ошибка, если ни одно правило не подходит к файлу
+	ErrNoCreationRule = errors.New("no creation rule matches the file")
+)
+
+// ProjectConfig настройки проекта: правила, по которым выбираются ключ,
+// алгоритм и шифруемые поля для конфигурационных файлов (как creation_rules в .sops.yaml)
+type ProjectConfig struct {
+	// Path - путь к файлу настроек; пути в правилах отсчитываются от его каталога
+	Path string `yaml:"-"`
+	// CreationRules - правила; к файлу применяется первое подходящее
+	CreationRules []CreationRule `yaml:"creation_rules"`
+}
+
+// CreationRule правило для файлов, подходящих под шаблон PathGlob.
+// Ключ задается одним из полей Keyring (с необязательным KeyID), KeyFile,
+// KeyEnv или SSHKey; без них ключ берется из флагов CLI или параметров вызова.
+type CreationRule struct {
+	// PathGlob - шаблон пути относительно каталога файла настроек
+	// ("*" - часть имени, "**" - любое число каталогов); пусто - любой файл
+	PathGlob string `yaml:"path_glob"`
+	// Keyring - файл набора ключей
+	Keyring string `yaml:"keyring"`
+	// KeyID - ключ набора, которым шифруются значения (по умолчанию активный)
+	KeyID string `yaml:"key_id"`
+	// KeyFile - файл с ключом
+	KeyFile string `yaml:"key_file"`
+	// KeyEnv - переменная окружения с ключом
+	KeyEnv string `yaml:"key_env"`
+	// SSHKey - получатель: отпечаток ключа Ed25519 в ssh-agent (SHA256:...)
+	SSHKey string `yaml:"ssh_key"`
+	// Algorithm - алгоритм шифрования (по умолчанию AES256)
+	Algorithm string `yaml:"algorithm"`
+	// Policy - политика (например, fips)
+	Policy string `yaml:"policy"`
+	// EncryptedFields - пути полей вида "db.password" или "tokens[0]";
+	// путь без индекса охватывает и вложенные значения
+	EncryptedFields []string `yaml:"encrypted_fields"`
+	// EncryptedRegex - регулярное выражение для имен ключей, значения которых шифруются
+	EncryptedRegex string `yaml:"encrypted_regex"`
+
+	encryptedRegex *regexp.Regexp
+}
+
+// FindProjectConfig ищет ProjectFileName в каталоге dir и выше и загружает его
+func FindProjectConfig(dir string) (*ProjectConfig, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(p); err == nil {
+			return LoadProjectConfig(p)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNoProjectConfig
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectConfig читает и проверяет файл настроек проекта.
+// Относительные пути к ключам в правилах разрешаются от каталога файла.
+func LoadProjectConfig(p string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	project := &ProjectConfig{Path: abs}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(project); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+
+	dir := filepath.Dir(abs)
+	for i := range project.CreationRules {
+		r := &project.CreationRules[i]
+		if err := r.init(dir); err != nil {
+			return nil, fmt.Errorf("%s: creation rule %d: %w", p, i+1, err)
+		}
+	}
+	return project, nil
+}
+
+// init проверяет правило и разрешает относительные пути
+func (r *CreationRule) init(dir string) error {
+	if r.PathGlob != "" {
+		if _, err := path.Match(strings.ReplaceAll(r.PathGlob, "**", "*"), ""); err != nil {
+			return fmt.Errorf("invalid path_glob %q: %w", r.PathGlob, err)
+		}
+	}
+	sources := 0
+	for _, s := range []string{r.Keyring, r.KeyFile, r.KeyEnv, r.SSHKey} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of keyring, key_file, key_env and ssh_key may be set")
+	}
+	if r.KeyID != "" && r.Keyring == "" {
+		return errors.New("key_id requires keyring")
+	}
+	if r.Policy != "" {
+		if _, err := LookupPolicy(r.Policy); err != nil {
+			return err
+		}
+	}
+	if r.EncryptedRegex != "" {
+		re, err := regexp.Compile(r.EncryptedRegex)
+		if err != nil {
+			return fmt.Errorf("invalid encrypted_regex: %w", err)
+		}
+		r.encryptedRegex = re
+	}
+	for _, p := range []*string{&r.Keyring, &r.KeyFile} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return nil
+}
+
+// Match возвращает первое правило, подходящее к файлу file
+func (p *ProjectConfig) Match(file string) (*CreationRule, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(filepath.Dir(p.Path), abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%w: %s is outside the project %s", ErrNoCreationRule, file, filepath.Dir(p.Path))
+	}
+	rel = filepath.ToSlash(rel)
+	for i := range p.CreationRules {
+		r := &p.CreationRules[i]
+		if r.PathGlob == "" || matchGlob(r.PathGlob, rel) {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoCreationRule, rel)
+}
+
+// matchGlob сопоставляет путь с шаблоном по сегментам; "**" - любое число сегментов
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// HasKey сообщает, задает ли правило источник ключа
+func (r *CreationRule) HasKey() bool {
+	return r.Keyring != "" || r.KeyFile != "" || r.KeyEnv != "" || r.SSHKey != ""
+}
+
+// HasFields сообщает, задает ли правило шифруемые поля
+func (r *CreationRule) HasFields() bool {
+	return len(r.EncryptedFields) > 0 || r.EncryptedRegex != ""
+}
+
+// Options возвращает опции конфигурации правила: алгоритм и политику
+func (r *CreationRule) Options() ([]Option, error) {
+	var opts []Option
+	if r.Algorithm != "" {
+		opts = append(opts, WithAlgorithm(r.Algorithm))
+	}
+	if r.Policy != "" {
+		policy, err := LookupPolicy(r.Policy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithPolicy(policy))
+	}
+	return opts, nil
+}
+
+// NewConfig создает конфигурацию с ключом и опциями правила; opts применяются после них
+func (r *CreationRule) NewConfig(opts ...Option) (*Config, error) {
+	ruleOpts, err := r.Options()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(ruleOpts, opts...)
+
+	switch {
+	case r.Keyring != "":
+		if r.KeyID != "" {
+			opts = append(opts, WithKeyringKeyID(r.KeyID))
+		}
+		return NewKeyringConfig(r.Keyring, opts...)
+	case r.KeyFile != "":
+		return NewConfigFromSource(&FileKeySource{Path: r.KeyFile}, opts...)
+	case r.KeyEnv != "":
+		return NewConfigFromSource(&EnvKeySource{Name: r.KeyEnv}, opts...)
+	case r.SSHKey != "":
+		return NewSSHAgentConfig(&SSHAgentConfig{Fingerprint: r.SSHKey}, opts...)
+	}
+	return nil, errors.New("creation rule does not set a key: use keyring, key_file, key_env or ssh_key")
+}
+
+// ShouldEncrypt сообщает, нужно ли шифровать значение по пути field вида "a.b[0].c"
+func (r *CreationRule) ShouldEncrypt(field string) bool {
+	for _, f := range r.EncryptedFields {
+		if field == f || strings.HasPrefix(field, f+".") || strings.HasPrefix(field, f+"[") {
+			return true
+		}
+	}
+	if r.encryptedRegex == nil {
+		return false
+	}
+	for _, name := range fieldKeys(field) {
+		if r.encryptedRegex.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldKeys возвращает имена ключей map на пути "a.b[0].c" без индексов: a, b, c.
+// Значение шифруется, если под выражение подходит любой ключ на пути к нему.
+func fieldKeys(field string) []string {
+	parts := strings.Split(field, ".")
+	for i, p := range parts {
+		if j := strings.IndexByte(p, '['); j >= 0 {
+			parts[i] = p[:j]
+		}
+	}
+	return parts
+}
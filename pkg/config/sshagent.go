@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// SSHAuthSockEnv переменная окружения с сокетом ssh-agent
+const SSHAuthSockEnv = "SSH_AUTH_SOCK"
+
+// SSHAgentConfig настройки получения ключа из подписи ssh-agent.
+// Агент подписывает фиксированный запрос ключом Ed25519; подпись Ed25519
+// детерминирована, поэтому из нее через HKDF каждый раз получается тот же ключ.
+type SSHAgentConfig struct {
+	// Socket - сокет ssh-agent (по умолчанию из SSH_AUTH_SOCK)
+	Socket string
+	// Fingerprint - отпечаток ключа в формате ssh-keygen -l (SHA256:...);
+	// если пуст, используется первый ключ Ed25519 агента
+	Fingerprint string
+}
+
+// NewSSHAgentConfig создает конфигурацию, в которой ключ получается из подписи ssh-agent
+func NewSSHAgentConfig(sshAgent *SSHAgentConfig, opts ...Option) (*Config, error) {
+	if sshAgent == nil {
+		sshAgent = &SSHAgentConfig{}
+	}
+	if sshAgent.Socket == "" {
+		sshAgent.Socket = os.Getenv(SSHAuthSockEnv)
+	}
+	if sshAgent.Socket == "" {
+		return nil, errors.New("ssh-agent is not running: " + SSHAuthSockEnv + " is not set")
+	}
+
+	cfg := &Config{
+		KeyLength: DefaultKeyLength,
+		Algorithm: AlgorithmAES256GCM,
+		SSHAgent:  sshAgent,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// AlgorithmTransit идентификатор конвертов, зашифрованных HashiCorp Vault Transit
+const AlgorithmTransit = "TRANSIT"
+
+// TransitConfig настройки шифрования через движок Transit HashiCorp Vault.
+// Ключ хранится в Vault, локально ключ шифрования не нужен.
+type TransitConfig struct {
+	// Address - адрес Vault, например https://vault.example.com:8200
+	Address string
+	// Mount - путь монтирования движка Transit (по умолчанию "transit")
+	Mount string
+	// KeyName - имя ключа в Transit
+	KeyName string
+	// Namespace - пространство имен Vault Enterprise (необязательно)
+	Namespace string
+	// Token - токен Vault; если пуст, используется вход через AppRole
+	Token string
+	// RoleID и SecretID - учетные данные AppRole
+	RoleID   string
+	SecretID string
+	// AppRoleMount - путь монтирования AppRole (по умолчанию "approle")
+	AppRoleMount string
+	// MaxRetries - число повторов при сетевых ошибках и ответах 5xx/429 (по умолчанию 3)
+	MaxRetries int
+	// RetryBackoff - начальная пауза между повторами, удваивается (по умолчанию 100ms)
+	RetryBackoff time.Duration
+	// HTTPClient - HTTP-клиент (по умолчанию http.Client с таймаутом 30s)
+	HTTPClient *http.Client
+}
+
+// NewTransitConfig создает конфигурацию, в которой шифрование выполняет Vault Transit
+func NewTransitConfig(transit *TransitConfig, opts ...Option) (*Config, error) {
+	if transit == nil || transit.Address == "" || transit.KeyName == "" {
+		return nil, errors.New("vault transit address and key name are required")
+	}
+	if transit.Token == "" && (transit.RoleID == "" || transit.SecretID == "") {
+		return nil, errors.New("vault transit requires a token or AppRole role_id and secret_id")
+	}
+
+	cfg := &Config{
+		KeyLength: DefaultKeyLength,
+		Algorithm: AlgorithmTransit,
+		Transit:   transit,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
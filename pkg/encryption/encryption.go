@@ -1,9 +1,20 @@
 package encryption
 
 import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/JohnnyFes/go-encryptor/internal/agent"
 	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/hsm"
 	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/internal/keyhelper"
+	"github.com/JohnnyFes/go-encryptor/internal/keyring"
+	"github.com/JohnnyFes/go-encryptor/internal/kms"
 	"github.com/JohnnyFes/go-encryptor/internal/sensitive"
+	"github.com/JohnnyFes/go-encryptor/internal/sshagent"
+	"github.com/JohnnyFes/go-encryptor/internal/transit"
 	"github.com/JohnnyFes/go-encryptor/pkg/config"
 )
 
@@ -11,40 +22,218 @@ import (
 type Encryptor struct {
 	encryptor interfaces.Encryptor
 	handler   interfaces.FieldEncryptor
+	policy    *config.Policy
+	tenants   *tenantCache
+	usage     *UsageTracker
+}
+
+// options параметры создания Encryptor
+type options struct {
+	selfTest        bool
+	tenantCacheSize int
+	usage           *UsageTracker
+}
+
+// Option функция для настройки Encryptor
+type Option func(*options)
+
+// WithSelfTest запускает SelfTest при создании; при провале NewEncryptor возвращает ошибку
+func WithSelfTest() Option {
+	return func(o *options) {
+		o.selfTest = true
+	}
 }
 
 // NewEncryptor создает новый экземпляр Encryptor
-func NewEncryptor(cfg *config.Config) (*Encryptor, error) {
-	provider := encryption.NewEncryptorProvider()
-	enc, err := provider.ProvideEncryptor(cfg)
+func NewEncryptor(cfg *config.Config, opts ...Option) (*Encryptor, error) {
+	return NewEncryptorContext(context.Background(), cfg, opts...)
+}
+
+// NewEncryptorContext создает новый экземпляр Encryptor с учетом контекста
+func NewEncryptorContext(ctx context.Context, cfg *config.Config, opts ...Option) (*Encryptor, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Проверяем конфигурацию на соответствие политике
+	if cfg.Policy != nil {
+		if err := cfg.Policy.CheckConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	enc, err := providerFor(cfg).ProvideEncryptorContext(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	handler := sensitive.NewFieldEncryptor(enc)
-
-	return &Encryptor{
+	e := &Encryptor{
 		encryptor: enc,
-		handler:   handler,
-	}, nil
+		policy:    cfg.Policy,
+		tenants:   newTenantCache(o.tenantCacheSize),
+		usage:     o.usage,
+	}
+	// Поля структур шифруются через e, чтобы на них тоже распространялась политика
+	e.handler = sensitive.NewFieldEncryptor(e)
+
+	if o.selfTest {
+		if _, err := e.SelfTest(); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// providerFor выбирает провайдер шифровальщика по конфигурации:
+// удаленный сервис, если он настроен, иначе локальный ключ. Набор ключей
+// обслуживает агент, если задан config.AgentSockEnv и агент держит этот набор.
+func providerFor(cfg *config.Config) interfaces.EncryptorProvider {
+	switch {
+	case cfg.Agent != nil:
+		return agent.NewProvider()
+	case cfg.Transit != nil:
+		return transit.NewProvider()
+	case cfg.KMS != nil:
+		return kms.NewProvider()
+	case cfg.PKCS11 != nil:
+		return hsm.NewProvider()
+	case cfg.KeyHelper != nil:
+		return keyhelper.NewProvider()
+	case cfg.SSHAgent != nil:
+		return sshagent.NewProvider()
+	case cfg.KeyringPath != "":
+		if _, ok := config.AgentSocket(); ok {
+			return agent.NewFallbackProvider(keyring.NewProvider())
+		}
+		return keyring.NewProvider()
+	}
+	return encryption.NewEncryptorProvider()
+}
+
+// Close освобождает ресурсы шифровальщика (например, сессию PKCS#11 или соединение с ssh-agent)
+func (e *Encryptor) Close() error {
+	if closer, ok := e.encryptor.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // EncryptString шифрует строку
 func (e *Encryptor) EncryptString(data string) (string, error) {
-	return e.encryptor.Encrypt(data)
+	return e.EncryptStringContext(context.Background(), data)
+}
+
+// EncryptStringContext шифрует строку с учетом контекста
+func (e *Encryptor) EncryptStringContext(ctx context.Context, data string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := e.recordUsage(); err != nil {
+		return "", err
+	}
+	return e.encryptor.EncryptContext(ctx, data)
 }
 
 // DecryptString расшифровывает строку
 func (e *Encryptor) DecryptString(data string) (string, error) {
-	return e.encryptor.Decrypt(data)
+	return e.DecryptStringContext(context.Background(), data)
+}
+
+// DecryptStringContext расшифровывает строку с учетом контекста
+func (e *Encryptor) DecryptStringContext(ctx context.Context, data string) (string, error) {
+	if err := e.checkEnvelope(data); err != nil {
+		return "", err
+	}
+	return e.encryptor.DecryptContext(ctx, data)
 }
 
 // EncryptFields шифрует поля в структуре, помеченные тегом encrypted:"true"
 func (e *Encryptor) EncryptFields(data interface{}) error {
-	return e.handler.HandleFields(data, true)
+	return e.EncryptFieldsContext(context.Background(), data)
+}
+
+// EncryptFieldsContext шифрует поля в структуре с учетом контекста.
+// При отмене контекста обработка прерывается, и часть полей остается открытой.
+func (e *Encryptor) EncryptFieldsContext(ctx context.Context, data interface{}) error {
+	return e.handler.HandleFieldsContext(ctx, data, true)
 }
 
 // DecryptFields расшифровывает поля в структуре, помеченные тегом encrypted:"true"
 func (e *Encryptor) DecryptFields(data interface{}) error {
-	return e.handler.HandleFields(data, false)
+	return e.DecryptFieldsContext(context.Background(), data)
+}
+
+// DecryptFieldsContext расшифровывает поля в структуре с учетом контекста
+func (e *Encryptor) DecryptFieldsContext(ctx context.Context, data interface{}) error {
+	return e.handler.HandleFieldsContext(ctx, data, false)
+}
+
+// EncryptBatch шифрует несколько строк
+func (e *Encryptor) EncryptBatch(data []string) ([]string, error) {
+	return e.EncryptBatchContext(context.Background(), data)
+}
+
+// EncryptBatchContext шифрует несколько строк, останавливаясь при отмене контекста
+func (e *Encryptor) EncryptBatchContext(ctx context.Context, data []string) ([]string, error) {
+	return e.batch(ctx, data, e.EncryptStringContext)
+}
+
+// DecryptBatch расшифровывает несколько строк
+func (e *Encryptor) DecryptBatch(data []string) ([]string, error) {
+	return e.DecryptBatchContext(context.Background(), data)
+}
+
+// DecryptBatchContext расшифровывает несколько строк, останавливаясь при отмене контекста
+func (e *Encryptor) DecryptBatchContext(ctx context.Context, data []string) ([]string, error) {
+	return e.batch(ctx, data, e.DecryptStringContext)
+}
+
+// Encrypt реализует interfaces.Encryptor
+func (e *Encryptor) Encrypt(text string) (string, error) {
+	return e.EncryptString(text)
+}
+
+// Decrypt реализует interfaces.Encryptor
+func (e *Encryptor) Decrypt(encrypted string) (string, error) {
+	return e.DecryptString(encrypted)
+}
+
+// EncryptContext реализует interfaces.Encryptor
+func (e *Encryptor) EncryptContext(ctx context.Context, text string) (string, error) {
+	return e.EncryptStringContext(ctx, text)
+}
+
+// DecryptContext реализует interfaces.Encryptor
+func (e *Encryptor) DecryptContext(ctx context.Context, encrypted string) (string, error) {
+	return e.DecryptStringContext(ctx, encrypted)
+}
+
+// batch применяет fn к каждой строке, проверяя контекст перед каждым элементом
+func (e *Encryptor) batch(ctx context.Context, data []string, fn func(context.Context, string) (string, error)) ([]string, error) {
+	result := make([]string, len(data))
+	for i, v := range data {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		out, err := fn(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		result[i] = out
+	}
+	return result, nil
+}
+
+// checkEnvelope отклоняет конверты с алгоритмами, запрещенными политикой
+func (e *Encryptor) checkEnvelope(data string) error {
+	if e.policy == nil {
+		return nil
+	}
+	env, err := encryption.ParseEnvelope(data)
+	if err != nil {
+		return err
+	}
+	return e.policy.CheckAlgorithm(env.Algorithm)
 }
@@ -0,0 +1,132 @@
+package encryption
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+)
+
+// Encrypted поле структуры со значением любого типа (int, time.Time, собственные
+// типы), которое нельзя зашифровать на месте. EncryptFields сериализует Value
+// (encoding.BinaryMarshaler, если тип его реализует, иначе JSON), записывает
+// конверт ENC[...] в Ciphertext и обнуляет Value; DecryptFields восстанавливает
+// Value. В JSON и YAML зашифрованное поле записывается строкой ENC[...],
+// открытое - как само значение.
+//
+//	type Account struct {
+//		Balance encryption.Encrypted[int]
+//		Expires encryption.Encrypted[time.Time]
+//	}
+type Encrypted[T any] struct {
+	// Value - открытое значение
+	Value T
+	// Ciphertext - конверт ENC[...] с сериализованным значением; пусто, пока поле не зашифровано
+	Ciphertext string
+}
+
+// IsSealed сообщает, что значение зашифровано
+func (e Encrypted[T]) IsSealed() bool {
+	return e.Ciphertext != ""
+}
+
+// SealField шифрует Value; зашифрованное поле не меняется.
+// Реализует interfaces.FieldSealer.
+func (e *Encrypted[T]) SealField(ctx context.Context, enc interfaces.Encryptor) error {
+	if e.IsSealed() {
+		return nil
+	}
+	data, err := e.marshal()
+	if err != nil {
+		return fmt.Errorf("failed to serialize %T: %w", e.Value, err)
+	}
+	ciphertext, err := enc.EncryptContext(ctx, string(data))
+	if err != nil {
+		return err
+	}
+	var zero T
+	e.Value, e.Ciphertext = zero, ciphertext
+	return nil
+}
+
+// OpenField расшифровывает Ciphertext в Value; открытое поле не меняется.
+// Реализует interfaces.FieldSealer.
+func (e *Encrypted[T]) OpenField(ctx context.Context, enc interfaces.Encryptor) error {
+	if !e.IsSealed() {
+		return nil
+	}
+	data, err := enc.DecryptContext(ctx, e.Ciphertext)
+	if err != nil {
+		return err
+	}
+	var value T
+	if err := unmarshalValue(&value, []byte(data)); err != nil {
+		return fmt.Errorf("failed to deserialize %T: %w", value, err)
+	}
+	e.Value, e.Ciphertext = value, ""
+	return nil
+}
+
+// marshal сериализует Value
+func (e *Encrypted[T]) marshal() ([]byte, error) {
+	if m, ok := any(&e.Value).(encoding.BinaryMarshaler); ok {
+		if _, ok := any(&e.Value).(encoding.BinaryUnmarshaler); ok {
+			return m.MarshalBinary()
+		}
+	}
+	return json.Marshal(e.Value)
+}
+
+// unmarshalValue восстанавливает значение, сериализованное marshal
+func unmarshalValue[T any](value *T, data []byte) error {
+	if u, ok := any(value).(encoding.BinaryUnmarshaler); ok {
+		if _, ok := any(value).(encoding.BinaryMarshaler); ok {
+			return u.UnmarshalBinary(data)
+		}
+	}
+	return json.Unmarshal(data, value)
+}
+
+// MarshalJSON записывает конверт ENC[...] или открытое значение
+func (e Encrypted[T]) MarshalJSON() ([]byte, error) {
+	if e.Ciphertext != "" {
+		return json.Marshal(e.Ciphertext)
+	}
+	return json.Marshal(e.Value)
+}
+
+// UnmarshalJSON читает конверт ENC[...] или открытое значение
+func (e *Encrypted[T]) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil && encryption.IsEnvelope(s) {
+		var zero T
+		e.Value, e.Ciphertext = zero, s
+		return nil
+	}
+	e.Ciphertext = ""
+	return json.Unmarshal(data, &e.Value)
+}
+
+// MarshalYAML записывает конверт ENC[...] или открытое значение
+func (e Encrypted[T]) MarshalYAML() (interface{}, error) {
+	if e.Ciphertext != "" {
+		return e.Ciphertext, nil
+	}
+	return e.Value, nil
+}
+
+// UnmarshalYAML читает конверт ENC[...] или открытое значение
+func (e *Encrypted[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && encryption.IsEnvelope(node.Value) {
+		var zero T
+		e.Value, e.Ciphertext = zero, node.Value
+		return nil
+	}
+	e.Ciphertext = ""
+	return node.Decode(&e.Value)
+}
@@ -0,0 +1,256 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const (
+	// openSSLMagic заголовок формата openssl enc с солью
+	openSSLMagic = "Salted__"
+	// openSSLSaltLength длина соли openssl enc
+	openSSLSaltLength = 8
+	// DefaultOpenSSLIterations число итераций PBKDF2 по умолчанию в openssl enc -pbkdf2
+	DefaultOpenSSLIterations = 10000
+	// openSSLLineWidth ширина строк base64 в выводе openssl enc -a
+	openSSLLineWidth = 64
+)
+
+// openSSLParams параметры получения ключа, совместимые с openssl enc -aes-256-cbc
+type openSSLParams struct {
+	digest     string
+	iterations int
+	legacy     bool
+	singleLine bool
+}
+
+// OpenSSLOption функция для настройки совместимости с openssl enc
+type OpenSSLOption func(*openSSLParams)
+
+// WithOpenSSLDigest задает хэш-функцию (-md): md5, sha1, sha256, sha384, sha512
+func WithOpenSSLDigest(name string) OpenSSLOption {
+	return func(p *openSSLParams) {
+		p.digest = name
+	}
+}
+
+// WithOpenSSLIterations задает число итераций PBKDF2 (-iter)
+func WithOpenSSLIterations(n int) OpenSSLOption {
+	return func(p *openSSLParams) {
+		p.iterations = n
+	}
+}
+
+// WithOpenSSLLegacyKDF включает устаревшее получение ключа EVP_BytesToKey (openssl enc без -pbkdf2)
+func WithOpenSSLLegacyKDF() OpenSSLOption {
+	return func(p *openSSLParams) {
+		p.legacy = true
+	}
+}
+
+// WithOpenSSLSingleLine записывает base64 одной строкой (openssl enc -a -A), а не строками
+// по 64 символа: так значение помещается в строку YAML или переменную окружения
+func WithOpenSSLSingleLine() OpenSSLOption {
+	return func(p *openSSLParams) {
+		p.singleLine = true
+	}
+}
+
+// newOpenSSLParams возвращает параметры openssl enc по умолчанию с примененными opts
+func newOpenSSLParams(opts []OpenSSLOption) openSSLParams {
+	params := openSSLParams{
+		digest:     "sha256",
+		iterations: DefaultOpenSSLIterations,
+	}
+	for _, opt := range opts {
+		opt(&params)
+	}
+	return params
+}
+
+// CheckOpenSSLPolicy проверяет, что шифрование в формате openssl enc с параметрами
+// opts допускается политикой: алгоритм AES-256-CBC и функция получения ключа
+// (PBKDF2 с хэшем -md и числом итераций или EVP_BytesToKey)
+func CheckOpenSSLPolicy(policy *config.Policy, opts ...OpenSSLOption) error {
+	if err := policy.CheckAlgorithm(config.AlgorithmAES256CBC); err != nil {
+		return err
+	}
+	params := newOpenSSLParams(opts)
+	if params.legacy {
+		return policy.CheckKDF(config.KDFEVPBytesToKey, 0)
+	}
+	return policy.CheckKDF("PBKDF2-"+strings.ToUpper(params.digest), params.iterations)
+}
+
+// OpenSSLEncrypt шифрует данные в двоичный формат Salted__,
+// совместимый с openssl enc -aes-256-cbc -pbkdf2
+func OpenSSLEncrypt(passphrase string, plaintext []byte, opts ...OpenSSLOption) ([]byte, error) {
+	salt := make([]byte, openSSLSaltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, iv, err := openSSLDeriveKey(passphrase, salt, opts)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	// Дополнение PKCS#7
+	n := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(n)}, n)...)
+
+	out := make([]byte, len(openSSLMagic)+len(salt)+len(padded))
+	copy(out, openSSLMagic)
+	copy(out[len(openSSLMagic):], salt)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out[len(openSSLMagic)+len(salt):], padded)
+	return out, nil
+}
+
+// OpenSSLDecrypt расшифровывает данные формата Salted__.
+// Принимает как двоичные данные, так и base64 (вывод openssl enc -a).
+func OpenSSLDecrypt(passphrase string, data []byte, opts ...OpenSSLOption) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(openSSLMagic)) {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to decode base64: %v", interfaces.ErrInvalidData, err)
+		}
+		data = decoded
+	}
+	if !bytes.HasPrefix(data, []byte(openSSLMagic)) || len(data) < len(openSSLMagic)+openSSLSaltLength {
+		return nil, fmt.Errorf("%w: missing Salted__ header", interfaces.ErrInvalidData)
+	}
+
+	salt := data[len(openSSLMagic) : len(openSSLMagic)+openSSLSaltLength]
+	ciphertext := data[len(openSSLMagic)+openSSLSaltLength:]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: ciphertext is not a multiple of the block size", interfaces.ErrInvalidData)
+	}
+
+	key, iv, err := openSSLDeriveKey(passphrase, salt, opts)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	// Неверная парольная фраза почти всегда проявляется как неверное дополнение
+	n := int(plaintext[len(plaintext)-1])
+	if n == 0 || n > aes.BlockSize {
+		return nil, fmt.Errorf("%w: bad padding", interfaces.ErrDecryptionFailed)
+	}
+	for _, b := range plaintext[len(plaintext)-n:] {
+		if int(b) != n {
+			return nil, fmt.Errorf("%w: bad padding", interfaces.ErrDecryptionFailed)
+		}
+	}
+	return plaintext[:len(plaintext)-n], nil
+}
+
+// OpenSSLEncryptString шифрует строку и возвращает base64 в формате openssl enc -a
+// (строки по 64 символа; одной строкой с WithOpenSSLSingleLine)
+func OpenSSLEncryptString(passphrase, plaintext string, opts ...OpenSSLOption) (string, error) {
+	data, err := OpenSSLEncrypt(passphrase, []byte(plaintext), opts...)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if newOpenSSLParams(opts).singleLine {
+		return encoded, nil
+	}
+	var out strings.Builder
+	for len(encoded) > openSSLLineWidth {
+		out.WriteString(encoded[:openSSLLineWidth])
+		out.WriteByte('\n')
+		encoded = encoded[openSSLLineWidth:]
+	}
+	out.WriteString(encoded)
+	return out.String(), nil
+}
+
+// OpenSSLDecryptString расшифровывает строку base64 в формате openssl enc -a
+func OpenSSLDecryptString(passphrase, encoded string, opts ...OpenSSLOption) (string, error) {
+	plaintext, err := OpenSSLDecrypt(passphrase, []byte(encoded), opts...)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// openSSLDeriveKey получает ключ AES-256 и IV так же, как openssl enc
+func openSSLDeriveKey(passphrase string, salt []byte, opts []OpenSSLOption) (key, iv []byte, err error) {
+	params := newOpenSSLParams(opts)
+
+	newHash, err := openSSLDigest(params.digest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	const keyLen, ivLen = 32, aes.BlockSize
+	var derived []byte
+	if params.legacy {
+		derived = evpBytesToKey(newHash, []byte(passphrase), salt, keyLen+ivLen)
+	} else {
+		if params.iterations <= 0 {
+			return nil, nil, fmt.Errorf("%w: invalid PBKDF2 iteration count %d", interfaces.ErrInvalidConfig, params.iterations)
+		}
+		derived = pbkdf2.Key([]byte(passphrase), salt, params.iterations, keyLen+ivLen, newHash)
+	}
+	return derived[:keyLen], derived[keyLen:], nil
+}
+
+// evpBytesToKey реализует EVP_BytesToKey с одной итерацией: D_i = H(D_{i-1} || pass || salt)
+func evpBytesToKey(newHash func() hash.Hash, passphrase, salt []byte, length int) []byte {
+	var derived, prev []byte
+	for len(derived) < length {
+		h := newHash()
+		h.Write(prev)
+		h.Write(passphrase)
+		h.Write(salt)
+		prev = h.Sum(nil)
+		derived = append(derived, prev...)
+	}
+	return derived[:length]
+}
+
+// openSSLDigest возвращает хэш-функцию по имени, как в параметре -md
+func openSSLDigest(name string) (func() hash.Hash, error) {
+	switch strings.ToLower(name) {
+	case "md5":
+		return md5.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha384":
+		return sha512.New384, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported digest %q", interfaces.ErrInvalidConfig, name)
+}
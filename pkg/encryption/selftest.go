@@ -0,0 +1,126 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/encryption"
+)
+
+var (
+	// ErrSelfTestFailed ошибка при провале самопроверки
+	ErrSelfTestFailed = errors.New("encryption self-test failed")
+)
+
+// SelfTestResult результат одной проверки
+type SelfTestResult struct {
+	// Name - название проверки (например, "KAT AES256" или "pairwise")
+	Name string
+	// Source - происхождение тестового вектора
+	Source string
+	// Err - ошибка проверки, nil если проверка пройдена
+	Err error
+	// Duration - время выполнения проверки
+	Duration time.Duration
+}
+
+// Passed сообщает, пройдена ли проверка
+func (r SelfTestResult) Passed() bool {
+	return r.Err == nil
+}
+
+// SelfTestReport подробный отчет самопроверки
+type SelfTestReport struct {
+	Results []SelfTestResult
+}
+
+// Passed сообщает, пройдены ли все проверки
+func (r *SelfTestReport) Passed() bool {
+	for _, res := range r.Results {
+		if !res.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// String возвращает отчет в читаемом виде, по одной строке на проверку
+func (r *SelfTestReport) String() string {
+	var b strings.Builder
+	for _, res := range r.Results {
+		status := "PASS"
+		if !res.Passed() {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s %s (%s)", status, res.Name, res.Duration)
+		if res.Source != "" {
+			fmt.Fprintf(&b, " [%s]", res.Source)
+		}
+		if res.Err != nil {
+			fmt.Fprintf(&b, ": %v", res.Err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SelfTest выполняет тесты с известным ответом для каждого зарегистрированного алгоритма
+// и проверку согласованности шифрования и расшифровки текущим ключом.
+// Если хотя бы одна проверка не пройдена, возвращается ErrSelfTestFailed вместе с отчетом.
+func (e *Encryptor) SelfTest() (*SelfTestReport, error) {
+	report := &SelfTestReport{}
+
+	for _, name := range encryption.Algorithms() {
+		alg, _ := encryption.LookupAlgorithm(name)
+		start := time.Now()
+		err := encryption.RunKnownAnswerTest(alg)
+		report.Results = append(report.Results, SelfTestResult{
+			Name:     "KAT " + name,
+			Source:   alg.KAT.Source,
+			Err:      err,
+			Duration: time.Since(start),
+		})
+	}
+
+	start := time.Now()
+	err := e.pairwiseTest()
+	report.Results = append(report.Results, SelfTestResult{
+		Name:     "pairwise",
+		Err:      err,
+		Duration: time.Since(start),
+	})
+
+	if !report.Passed() {
+		return report, fmt.Errorf("%w:\n%s", ErrSelfTestFailed, report)
+	}
+	return report, nil
+}
+
+// pairwiseTest шифрует и расшифровывает случайное значение текущим шифровальщиком
+func (e *Encryptor) pairwiseTest() error {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("failed to generate test data: %w", err)
+	}
+	plaintext := base64.StdEncoding.EncodeToString(buf)
+
+	encrypted, err := e.encryptor.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	if strings.Contains(encrypted, plaintext) {
+		return errors.New("ciphertext contains plaintext")
+	}
+	decrypted, err := e.encryptor.Decrypt(encrypted)
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+	if decrypted != plaintext {
+		return errors.New("decrypted value does not match plaintext")
+	}
+	return nil
+}
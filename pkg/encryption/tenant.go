@@ -0,0 +1,129 @@
+package encryption
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/internal/sensitive"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+// DefaultTenantCacheSize размер кэша шифраторов арендаторов по умолчанию
+const DefaultTenantCacheSize = 1024
+
+// WithTenantCacheSize задает, сколько шифраторов арендаторов хранится в LRU-кэше
+func WithTenantCacheSize(size int) Option {
+	return func(o *options) {
+		o.tenantCacheSize = size
+	}
+}
+
+// ForTenant возвращает шифратор с ключом арендатора, полученным из мастер-ключа
+// через HKDF-SHA256. Идентификатор арендатора записывается в конверт, и значение
+// другого арендатора не расшифруется (interfaces.ErrTenantMismatch).
+// Шифраторы кэшируются в LRU-кэше ограниченного размера.
+func (e *Encryptor) ForTenant(tenantID string) *Encryptor {
+	if cached, ok := e.tenants.get(tenantID); ok {
+		return cached
+	}
+
+	tenant := &Encryptor{
+		policy:  e.policy,
+		tenants: e.tenants,
+		usage:   e.usage,
+	}
+	tenant.handler = sensitive.NewFieldEncryptor(tenant)
+
+	enc, err := e.deriveTenant(tenantID)
+	if err != nil {
+		// Ошибка возвращается при каждой операции и не кэшируется
+		tenant.encryptor = failingEncryptor{err: err}
+		return tenant
+	}
+	tenant.encryptor = enc
+
+	e.tenants.add(tenantID, tenant)
+	return tenant
+}
+
+// deriveTenant получает шифровальщик арендатора, если реализация это поддерживает
+func (e *Encryptor) deriveTenant(tenantID string) (interfaces.Encryptor, error) {
+	deriver, ok := e.encryptor.(interfaces.TenantEncryptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: encryptor does not support tenant keys", interfaces.ErrInvalidConfig)
+	}
+	if e.policy != nil {
+		if err := e.policy.CheckKDF(config.KDFHKDFSHA256, 0); err != nil {
+			return nil, err
+		}
+	}
+	return deriver.ForTenant(tenantID)
+}
+
+// tenantCache LRU-кэш шифраторов арендаторов, безопасный для конкурентного доступа
+type tenantCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type tenantCacheEntry struct {
+	tenantID  string
+	encryptor *Encryptor
+}
+
+func newTenantCache(size int) *tenantCache {
+	if size <= 0 {
+		size = DefaultTenantCacheSize
+	}
+	return &tenantCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *tenantCache) get(tenantID string) (*Encryptor, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[tenantID]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*tenantCacheEntry).encryptor, true
+}
+
+func (c *tenantCache) add(tenantID string, encryptor *Encryptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[tenantID]; ok {
+		c.order.MoveToFront(el)
+		el.Value.(*tenantCacheEntry).encryptor = encryptor
+		return
+	}
+	c.items[tenantID] = c.order.PushFront(&tenantCacheEntry{tenantID: tenantID, encryptor: encryptor})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*tenantCacheEntry).tenantID)
+	}
+}
+
+// failingEncryptor возвращает одну и ту же ошибку для любой операции
+type failingEncryptor struct {
+	err error
+}
+
+func (f failingEncryptor) Encrypt(string) (string, error) { return "", f.err }
+func (f failingEncryptor) Decrypt(string) (string, error) { return "", f.err }
+func (f failingEncryptor) EncryptContext(context.Context, string) (string, error) {
+	return "", f.err
+}
+func (f failingEncryptor) DecryptContext(context.Context, string) (string, error) {
+	return "", f.err
+}
@@ -0,0 +1,465 @@
+package encryption
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+)
+
+const (
+	// DefaultUsageSoftLimit число шифрований, после которого ключ пора ротировать (2^31)
+	DefaultUsageSoftLimit uint64 = 1 << 31
+	// DefaultUsageHardLimit предел шифрований одним ключом AES-GCM со случайным nonce (2^32)
+	DefaultUsageHardLimit uint64 = 1 << 32
+	// DefaultUsageReservation сколько шифрований FileUsageStore резервирует
+	// в файле за одну запись
+	DefaultUsageReservation uint64 = 1000
+)
+
+// UsageStore хранит счетчики шифрований по идентификаторам ключей
+type UsageStore interface {
+	// Add увеличивает счетчик ключа на n и возвращает новое значение. Если новое
+	// значение превысило бы limit, счетчик не меняется: Add возвращает текущее
+	// значение и interfaces.ErrKeyUsageExceeded. Проверка и увеличение должны
+	// быть одной операцией, иначе конкурентные вызовы превысят limit.
+	Add(keyID string, n, limit uint64) (uint64, error)
+	// Load возвращает текущее значение счетчика
+	Load(keyID string) (uint64, error)
+}
+
+// UsageStatus состояние использования ключа
+type UsageStatus struct {
+	// KeyID - идентификатор ключа
+	KeyID string
+	// Count - число выполненных шифрований
+	Count uint64
+	// SoftLimit и HardLimit - пороги предупреждения и отказа
+	SoftLimit uint64
+	HardLimit uint64
+	// RotationDue - ключ достиг мягкого порога и его пора ротировать
+	RotationDue bool
+}
+
+// UsageTracker считает шифрования по ключам, предупреждает после мягкого порога
+// и отказывает в шифровании после жесткого
+type UsageTracker struct {
+	store     UsageStore
+	soft      uint64
+	hard      uint64
+	onWarning func(UsageStatus)
+
+	mu sync.Mutex
+	// exhausted - ключи, достигшие жесткого порога: в шифровании отказывается
+	// без обращения к хранилищу
+	exhausted map[string]bool
+}
+
+// UsageOption функция для настройки UsageTracker
+type UsageOption func(*UsageTracker)
+
+// WithUsageLimits задает мягкий и жесткий пороги числа шифрований
+func WithUsageLimits(soft, hard uint64) UsageOption {
+	return func(t *UsageTracker) {
+		t.soft = soft
+		t.hard = hard
+	}
+}
+
+// WithUsageWarning задает обработчик предупреждений после мягкого порога
+// (по умолчанию предупреждение пишется в стандартный логгер)
+func WithUsageWarning(fn func(UsageStatus)) UsageOption {
+	return func(t *UsageTracker) {
+		t.onWarning = fn
+	}
+}
+
+// NewUsageTracker создает счетчик использования ключей поверх хранилища.
+// Если store равен nil, счетчики хранятся в файле DefaultUsageFile.
+func NewUsageTracker(store UsageStore, opts ...UsageOption) *UsageTracker {
+	if store == nil {
+		store = defaultUsageStore()
+	}
+	t := &UsageTracker{
+		store:     store,
+		exhausted: make(map[string]bool),
+		soft:      DefaultUsageSoftLimit,
+		hard:      DefaultUsageHardLimit,
+		onWarning: func(s UsageStatus) {
+			log.Printf("encryption key %s used %d times (soft limit %d): rotation is due", s.KeyID, s.Count, s.SoftLimit)
+		},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// WithUsageTracker включает учет шифрований для Encryptor
+func WithUsageTracker(t *UsageTracker) Option {
+	return func(o *options) {
+		o.usage = t
+	}
+}
+
+// DefaultUsageFile возвращает путь файла счетчиков по умолчанию:
+// go-encryptor/key-usage.json в каталоге настроек пользователя
+func DefaultUsageFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate the default key usage file: %w", err)
+	}
+	return filepath.Join(dir, "go-encryptor", "key-usage.json"), nil
+}
+
+// defaultUsageStore возвращает файловое хранилище по умолчанию; если каталог
+// настроек не определен, хранилище возвращает эту ошибку при каждом обращении
+func defaultUsageStore() UsageStore {
+	path, err := DefaultUsageFile()
+	if err != nil {
+		return unavailableUsageStore{err: err}
+	}
+	return NewFileUsageStore(path)
+}
+
+// unavailableUsageStore хранилище, которое нельзя открыть
+type unavailableUsageStore struct {
+	err error
+}
+
+func (s unavailableUsageStore) Add(string, uint64, uint64) (uint64, error) { return 0, s.err }
+func (s unavailableUsageStore) Load(string) (uint64, error)                { return 0, s.err }
+
+// Record учитывает одно шифрование ключом keyID.
+// После жесткого порога возвращает interfaces.ErrKeyUsageExceeded, и шифровать нельзя;
+// порог проверяется хранилищем вместе с увеличением счетчика, поэтому
+// конкурентные шифрования его не превышают, а отказы в хранилище не записываются.
+func (t *UsageTracker) Record(keyID string) error {
+	t.mu.Lock()
+	exhausted := t.exhausted[keyID]
+	t.mu.Unlock()
+	if exhausted {
+		return t.exceeded(keyID)
+	}
+
+	count, err := t.store.Add(keyID, 1, t.hard)
+	if errors.Is(err, interfaces.ErrKeyUsageExceeded) {
+		t.mu.Lock()
+		t.exhausted[keyID] = true
+		t.mu.Unlock()
+		return t.exceeded(keyID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record key usage: %w", err)
+	}
+	if status := t.status(keyID, count); status.RotationDue && t.onWarning != nil {
+		t.onWarning(status)
+	}
+	return nil
+}
+
+// exceeded возвращает ошибку превышения жесткого порога
+func (t *UsageTracker) exceeded(keyID string) error {
+	return fmt.Errorf("%w: key %s reached hard limit %d", interfaces.ErrKeyUsageExceeded, keyID, t.hard)
+}
+
+// Close закрывает хранилище, если оно реализует io.Closer
+// (FileUsageStore возвращает неиспользованный резерв)
+func (t *UsageTracker) Close() error {
+	if c, ok := t.store.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Status возвращает состояние использования ключа
+func (t *UsageTracker) Status(keyID string) (UsageStatus, error) {
+	count, err := t.store.Load(keyID)
+	if err != nil {
+		return UsageStatus{}, fmt.Errorf("failed to load key usage: %w", err)
+	}
+	return t.status(keyID, count), nil
+}
+
+// RotationDue сообщает, достиг ли ключ мягкого порога
+func (t *UsageTracker) RotationDue(keyID string) (bool, error) {
+	status, err := t.Status(keyID)
+	return status.RotationDue, err
+}
+
+func (t *UsageTracker) status(keyID string, count uint64) UsageStatus {
+	return UsageStatus{
+		KeyID:       keyID,
+		Count:       count,
+		SoftLimit:   t.soft,
+		HardLimit:   t.hard,
+		RotationDue: count >= t.soft,
+	}
+}
+
+// KeyUsage возвращает состояние использования текущего ключа шифратора
+func (e *Encryptor) KeyUsage() (UsageStatus, error) {
+	if e.usage == nil {
+		return UsageStatus{}, errors.New("usage tracking is not enabled")
+	}
+	keyID, err := e.keyID()
+	if err != nil {
+		return UsageStatus{}, err
+	}
+	return e.usage.Status(keyID)
+}
+
+// recordUsage учитывает шифрование, если учет включен
+func (e *Encryptor) recordUsage() error {
+	if e.usage == nil {
+		return nil
+	}
+	keyID, err := e.keyID()
+	if err != nil {
+		return err
+	}
+	return e.usage.Record(keyID)
+}
+
+// keyID возвращает идентификатор ключа шифровальщика
+func (e *Encryptor) keyID() (string, error) {
+	identifier, ok := e.encryptor.(interfaces.KeyIdentifier)
+	if !ok {
+		return "", fmt.Errorf("%w: encryptor does not expose a key ID", interfaces.ErrInvalidConfig)
+	}
+	return identifier.KeyID(), nil
+}
+
+// MemoryUsageStore хранит счетчики в памяти процесса
+type MemoryUsageStore struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// NewMemoryUsageStore создает хранилище счетчиков в памяти
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{counts: make(map[string]uint64)}
+}
+
+// Add увеличивает счетчик ключа, если он не превысит limit
+func (s *MemoryUsageStore) Add(keyID string, n, limit uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := s.counts[keyID]
+	if n > limit || count > limit-n {
+		return count, usageLimitError(keyID, limit)
+	}
+	s.counts[keyID] = count + n
+	return count + n, nil
+}
+
+// usageLimitError возвращает ошибку хранилища для счетчика, достигшего limit
+func usageLimitError(keyID string, limit uint64) error {
+	return fmt.Errorf("%w: key %s reached limit %d", interfaces.ErrKeyUsageExceeded, keyID, limit)
+}
+
+// Load возвращает счетчик ключа
+func (s *MemoryUsageStore) Load(keyID string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[keyID], nil
+}
+
+// FileUsageStore хранит счетчики в JSON-файле, общем для нескольких процессов.
+// Чтобы не переписывать файл при каждом шифровании, хранилище резервирует
+// в нем блок счетчика (DefaultUsageReservation) и выдает значения из блока
+// в памяти. Файл изменяется под блокировкой файла PATH.lock и перезаписывается
+// атомарно (временный файл, fsync и rename). Блок не выходит за limit, а значения
+// разных процессов не пересекаются, поэтому порог не превышается и несколькими
+// процессами. Резерв процесса, завершившегося без Close, считается использованным;
+// пока процесс не вернул резерв, другие процессы могут получить отказ раньше порога.
+type FileUsageStore struct {
+	mu      sync.Mutex
+	path    string
+	reserve uint64
+	blocks  map[string]*usageBlock
+}
+
+// usageBlock зарезервированный блок счетчика: значения до end, последнее выданное - used
+type usageBlock struct {
+	used uint64
+	end  uint64
+}
+
+// FileUsageOption функция для настройки FileUsageStore
+type FileUsageOption func(*FileUsageStore)
+
+// WithUsageReservation задает размер резервируемого блока (1 - записывать каждое шифрование)
+func WithUsageReservation(n uint64) FileUsageOption {
+	return func(s *FileUsageStore) {
+		if n > 0 {
+			s.reserve = n
+		}
+	}
+}
+
+// NewFileUsageStore создает файловое хранилище счетчиков
+func NewFileUsageStore(path string, opts ...FileUsageOption) *FileUsageStore {
+	s := &FileUsageStore{
+		path:    path,
+		reserve: DefaultUsageReservation,
+		blocks:  make(map[string]*usageBlock),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add увеличивает счетчик ключа; когда резерв исчерпан, резервирует в файле
+// следующий блок, не выходящий за limit
+func (s *FileUsageStore) Add(keyID string, n, limit uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b := s.blocks[keyID]; b != nil && b.end-b.used >= n && b.used+n <= limit {
+		b.used += n
+		return b.used, nil
+	}
+
+	var start, size uint64
+	exceeded := false
+	err := s.update(func(counts map[string]uint64) bool {
+		start = counts[keyID]
+		if n > limit || start > limit-n {
+			exceeded = true
+			return false
+		}
+		size = s.reserve
+		if n > size {
+			size = n
+		}
+		if size > limit-start {
+			size = limit - start
+		}
+		counts[keyID] = start + size
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if exceeded {
+		return start, usageLimitError(keyID, limit)
+	}
+	s.blocks[keyID] = &usageBlock{used: start + n, end: start + size}
+	return start + n, nil
+}
+
+// Load возвращает счетчик ключа из файла без неиспользованного резерва этого хранилища
+func (s *FileUsageStore) Load(keyID string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+	count := counts[keyID]
+	if b := s.blocks[keyID]; b != nil && count >= b.end-b.used {
+		count -= b.end - b.used
+	}
+	return count, nil
+}
+
+// Close возвращает в файл неиспользованный резерв, если после него
+// никто не резервировал блок того же ключа
+func (s *FileUsageStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.blocks) == 0 {
+		return nil
+	}
+	err := s.update(func(counts map[string]uint64) bool {
+		changed := false
+		for keyID, b := range s.blocks {
+			if b.used < b.end && counts[keyID] == b.end {
+				counts[keyID] = b.used
+				changed = true
+			}
+		}
+		return changed
+	})
+	s.blocks = make(map[string]*usageBlock)
+	return err
+}
+
+// update читает, изменяет функцией fn и записывает файл под блокировкой файла;
+// если fn вернула false, файл не перезаписывается
+func (s *FileUsageStore) update(fn func(counts map[string]uint64) bool) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+	lock, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("failed to lock usage file: %w", err)
+	}
+	defer unlockFile(lock)
+
+	counts, err := s.read()
+	if err != nil {
+		return err
+	}
+	if !fn(counts) {
+		return nil
+	}
+	return s.write(counts)
+}
+
+func (s *FileUsageStore) read() (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return counts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file: %w", err)
+	}
+	return counts, nil
+}
+
+func (s *FileUsageStore) write(counts map[string]uint64) error {
+	data, err := json.MarshalIndent(counts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".usage-*")
+	if err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	// Счетчик защищает от превышения порога: он должен пережить сбой питания
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	return nil
+}
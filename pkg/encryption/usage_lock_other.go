@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package encryption
+
+import "os"
+
+// lockFile без flock: файл счетчиков защищен только блокировкой внутри процесса
+func lockFile(*os.File) error {
+	return nil
+}
+
+// unlockFile без flock ничего не делает
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package encryption
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile захватывает исключительную блокировку файла (flock), общую для процессов
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile снимает блокировку файла
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package encryption
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile захватывает исключительную блокировку файла (LockFileEx), общую для процессов
+func lockFile(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, new(windows.Overlapped))
+}
+
+// unlockFile снимает блокировку файла
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, new(windows.Overlapped))
+}
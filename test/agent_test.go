@@ -0,0 +1,300 @@
+package encryption_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/agent"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// startAgent запускает агент с шифровальщиком enc и возвращает путь к сокету
+func startAgent(t *testing.T, enc *encryption.Encryptor, status agent.Status, setup func(*agent.Server)) (string, *agent.Server) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("agent tests need Unix sockets with file permissions")
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := agent.Listen(socket)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	srv := agent.NewServer(enc, status)
+	if setup != nil {
+		setup(srv)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l) }()
+	t.Cleanup(func() {
+		srv.Lock()
+		if err := <-served; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+	return socket, srv
+}
+
+func TestAgent_EncryptDecrypt(t *testing.T) {
+	local := newRandomEncryptor(t)
+	socket, _ := startAgent(t, local, agent.Status{}, nil)
+
+	cfg, err := config.NewAgentConfig(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+
+	encrypted := mustEncrypt(t, remote, "secret")
+	if got, err := local.DecryptString(encrypted); err != nil || got != "secret" {
+		t.Errorf("local DecryptString() = %q, %v", got, err)
+	}
+	if got, err := remote.DecryptString(mustEncrypt(t, local, "other")); err != nil || got != "other" {
+		t.Errorf("agent DecryptString() = %q, %v", got, err)
+	}
+
+	// Ошибки агента передаются клиенту, sentinel-ошибки восстанавливаются по коду
+	_, err = remote.DecryptString(mustEncrypt(t, newRandomEncryptor(t), "foreign"))
+	var remoteErr *agent.RemoteError
+	if !errors.As(err, &remoteErr) || !strings.Contains(err.Error(), "message authentication failed") {
+		t.Errorf("DecryptString(foreign key) error = %v, want the agent's error", err)
+	}
+	if _, err := remote.DecryptString("plain"); !errors.Is(err, interfaces.ErrInvalidData) {
+		t.Errorf("DecryptString(plain) error = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestAgent_KeyringFromEnv(t *testing.T) {
+	path, _ := saveSealedKeyring(t, "keys.json", "pass")
+	unlocked, err := config.NewKeyringConfig(path, config.WithKeyringPassphrase(func() ([]byte, error) {
+		return []byte("pass"), nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := encryption.NewEncryptor(unlocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket, _ := startAgent(t, local, agent.Status{Keyring: path}, nil)
+
+	t.Setenv(config.KeyringPassphraseEnv, "")
+	t.Setenv(config.AgentSockEnv, socket)
+
+	// Набор ключей зашифрован, а парольной фразы нет: работает только агент
+	cfg, _ := config.NewKeyringConfig(path)
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() through agent error = %v", err)
+	}
+	if got, err := local.DecryptString(mustEncrypt(t, enc, "secret")); err != nil || got != "secret" {
+		t.Errorf("DecryptString() = %q, %v", got, err)
+	}
+
+	// Агент держит другой набор ключей: используется сам файл
+	other, _ := saveSealedKeyring(t, "other.json", "pass")
+	cfg, _ = config.NewKeyringConfig(other)
+	if _, err := encryption.NewEncryptor(cfg); !errors.Is(err, config.ErrKeyringLocked) {
+		t.Errorf("NewEncryptor(other keyring) error = %v, want fallback to ErrKeyringLocked", err)
+	}
+
+	// Агент не соблюдает требуемую политику
+	cfg, _ = config.NewAgentConfig(socket, config.WithPolicy(config.FIPSPolicy()))
+	if _, err := encryption.NewEncryptor(cfg); !errors.Is(err, config.ErrPolicyViolation) {
+		t.Errorf("NewEncryptor(FIPS) error = %v, want ErrPolicyViolation", err)
+	}
+}
+
+func TestAgent_Lock(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*agent.Server)
+		lock  func(t *testing.T, client *agent.Client)
+	}{
+		{
+			name:  "idle timeout",
+			setup: func(s *agent.Server) { s.IdleTimeout = 50 * time.Millisecond },
+		},
+		{
+			name:  "ttl",
+			setup: func(s *agent.Server) { s.TTL = 50 * time.Millisecond },
+		},
+		{
+			name: "lock request",
+			lock: func(t *testing.T, client *agent.Client) {
+				if err := client.Lock(context.Background()); err != nil {
+					t.Fatalf("Lock() error = %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socket, srv := startAgent(t, newRandomEncryptor(t), agent.Status{}, tt.setup)
+			client := agent.NewClient(&config.AgentConfig{Socket: socket})
+			if _, err := client.Status(context.Background()); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			if tt.lock != nil {
+				tt.lock(t, client)
+			}
+
+			select {
+			case <-srv.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("agent did not lock")
+			}
+			// Сокет удаляется вместе с ключами
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				_, err := client.Status(context.Background())
+				if errors.Is(err, config.ErrAgentUnavailable) {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Status() after lock error = %v, want ErrAgentUnavailable", err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+// blockingEncryptor шифрует только после release и запоминает, был ли он закрыт во время запроса
+type blockingEncryptor struct {
+	interfaces.Encryptor
+	started      chan struct{}
+	release      chan struct{}
+	closed       atomic.Bool
+	closedInCall atomic.Bool
+}
+
+func (b *blockingEncryptor) EncryptContext(context.Context, string) (string, error) {
+	close(b.started)
+	<-b.release
+	b.closedInCall.Store(b.closed.Load())
+	return "sealed", nil
+}
+
+func (b *blockingEncryptor) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestAgent_LockWaitsForRequests(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("agent tests need Unix sockets with file permissions")
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := agent.Listen(socket)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	enc := &blockingEncryptor{started: make(chan struct{}), release: make(chan struct{})}
+	srv := agent.NewServer(enc, agent.Status{})
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l) }()
+
+	client := agent.NewClient(&config.AgentConfig{Socket: socket})
+	type result struct {
+		resp *agent.Response
+		err  error
+	}
+	called := make(chan result, 1)
+	go func() {
+		resp, err := client.Call(context.Background(), &agent.Request{Version: agent.ProtocolVersion, Operation: agent.OperationEncrypt, Data: "secret"})
+		called <- result{resp, err}
+	}()
+	<-enc.started
+
+	locked := make(chan struct{})
+	go func() {
+		srv.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("Lock() returned while a request was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if enc.closed.Load() {
+		t.Fatal("encryptor closed while a request was in flight")
+	}
+
+	close(enc.release)
+	<-locked
+	if r := <-called; r.err != nil || r.resp.Data != "sealed" {
+		t.Errorf("Call() = %+v, %v", r.resp, r.err)
+	}
+	if !enc.closed.Load() || enc.closedInCall.Load() {
+		t.Errorf("closed = %v, closed during the request = %v; want closed after it", enc.closed.Load(), enc.closedInCall.Load())
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
+
+func TestAgent_SocketPermissions(t *testing.T) {
+	socket, _ := startAgent(t, newRandomEncryptor(t), agent.Status{}, nil)
+	client := agent.NewClient(&config.AgentConfig{Socket: socket})
+
+	if err := os.Chmod(socket, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Status(context.Background())
+	if !errors.Is(err, config.ErrAgentUnavailable) || !strings.Contains(err.Error(), "accessible by group or others") {
+		t.Errorf("Status(0666 socket) error = %v, want an insecure socket error", err)
+	}
+	if err := os.Chmod(socket, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := agent.Listen(socket); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("Listen(busy socket) error = %v", err)
+	}
+
+	shared := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(shared, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.Listen(filepath.Join(shared, "agent.sock")); err == nil {
+		t.Error("Listen() in a world-writable directory succeeded")
+	}
+}
+
+func TestAgent_BinaryData(t *testing.T) {
+	local := newRandomEncryptor(t)
+	socket, _ := startAgent(t, local, agent.Status{}, nil)
+	cfg, err := config.NewAgentConfig(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON протокола испортил бы байты, не являющиеся UTF-8: агент отказывает
+	binary := string([]byte{0x00, 0xff, 0xfe})
+	if _, err := remote.EncryptString(binary); !errors.Is(err, interfaces.ErrInvalidData) {
+		t.Errorf("EncryptString(binary) error = %v, want ErrInvalidData", err)
+	}
+	if _, err := remote.DecryptString(mustEncrypt(t, local, binary)); !errors.Is(err, interfaces.ErrInvalidData) {
+		t.Errorf("DecryptString(binary) error = %v, want ErrInvalidData", err)
+	}
+}
@@ -0,0 +1,168 @@
+package encryption_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/internal/ansiblevault"
+	"github.com/JohnnyFes/go-encryptor/internal/configfile"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// Фикстуры в testdata/ansiblevault созданы независимо от пакета ansiblevault
+// (см. testdata/ansiblevault/README.md) с паролем vaultPassword
+var vaultPassword = []byte("vault-pass")
+
+func TestAnsibleVault_DecryptFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/ansiblevault/secret.txt.vault")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	plaintext, _, err := ansiblevault.Decrypt(data, vaultPassword)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if string(plaintext) != "db-password-123" {
+		t.Errorf("Decrypt() = %q, want %q", plaintext, "db-password-123")
+	}
+
+	if _, _, err := ansiblevault.Decrypt(data, []byte("wrong")); !errors.Is(err, ansiblevault.ErrHMACMismatch) {
+		t.Errorf("Decrypt() with wrong password error = %v, want %v", err, ansiblevault.ErrHMACMismatch)
+	}
+}
+
+func TestAnsibleVault_EncryptDecrypt(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		vaultID string
+	}{
+		{name: "empty text", text: ""},
+		{name: "block sized text", text: "0123456789abcdef"},
+		{name: "unicode text", text: "Привет, мир!"},
+		{name: "vault id", text: "secret", vaultID: "prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault, err := ansiblevault.Encrypt([]byte(tt.text), vaultPassword, tt.vaultID)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			plaintext, vaultID, err := ansiblevault.Decrypt(vault, vaultPassword)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if string(plaintext) != tt.text || vaultID != tt.vaultID {
+				t.Errorf("Decrypt() = %q, %q, want %q, %q", plaintext, vaultID, tt.text, tt.vaultID)
+			}
+		})
+	}
+}
+
+func TestConfigFile_AnsibleVaultImportExport(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+
+	tests := []struct {
+		name    string
+		fixture string
+		want    map[string]string
+	}{
+		{
+			name:    "inline vault values",
+			fixture: "inline.yml",
+			want:    map[string]string{"password": "s3cr3t"},
+		},
+		{
+			name:    "whole vault file",
+			fixture: "whole.yml",
+			want:    map[string]string{"token": "tok-42"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := copyFixture(t, filepath.Join("testdata/ansiblevault", tt.fixture))
+
+			count, err := configfile.ImportAnsibleVault(path, vaultPassword, encryptor.EncryptString)
+			if err != nil {
+				t.Fatalf("ImportAnsibleVault() error = %v", err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("ImportAnsibleVault() count = %d, want %d", count, len(tt.want))
+			}
+
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), "$ANSIBLE_VAULT") {
+				t.Fatalf("vault data left after import:\n%s", data)
+			}
+			for key, want := range tt.want {
+				got := decryptYAMLValue(t, encryptor, string(data), key)
+				if got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+
+			if _, err := configfile.ExportAnsibleVault(path, vaultPassword, "", encryptor.DecryptString); err != nil {
+				t.Fatalf("ExportAnsibleVault() error = %v", err)
+			}
+			data, _ = os.ReadFile(path)
+			if strings.Contains(string(data), "ENC[") || !strings.Contains(string(data), "!vault") {
+				t.Errorf("ExportAnsibleVault() unexpected result:\n%s", data)
+			}
+		})
+	}
+}
+
+// testKey случайный 32-байтный ключ в base64
+const testKey = "wIw3wCwub3in9kTFfq3y3xIFZR7SEyKXgZL5RRl0DEY="
+
+func newTestEncryptor(t *testing.T) *encryption.Encryptor {
+	t.Helper()
+	cfg, err := config.NewConfig(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	encryptor, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create encryptor: %v", err)
+	}
+	return encryptor
+}
+
+func copyFixture(t *testing.T, src string) string {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), filepath.Base(src))
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		t.Fatalf("Failed to copy fixture: %v", err)
+	}
+	return dst
+}
+
+// decryptYAMLValue находит в YAML строку "key: ENC[...]" и расшифровывает значение
+func decryptYAMLValue(t *testing.T, encryptor *encryption.Encryptor, data, key string) string {
+	t.Helper()
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, key+": ") {
+			continue
+		}
+		value := strings.Trim(strings.TrimPrefix(line, key+": "), `"'`)
+		plaintext, err := encryptor.DecryptString(value)
+		if err != nil {
+			t.Fatalf("DecryptString(%s) error = %v", key, err)
+		}
+		return plaintext
+	}
+	t.Fatalf("key %s not found in:\n%s", key, data)
+	return ""
+}
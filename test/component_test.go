@@ -0,0 +1,120 @@
+package encryption_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// writeComponentFiles записывает компоненты ключа в файлы 0600 и возвращает их пути
+func writeComponentFiles(t *testing.T, components [][]byte) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(components))
+	for i, c := range components {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("component-%d.txt", i+1))
+		if err := config.WriteKeyFile(paths[i], []byte(base64.StdEncoding.EncodeToString(c)+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestComponentKeySource(t *testing.T) {
+	components, err := config.GenerateKeyComponents(config.AlgorithmAES256GCM, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := config.CombineKeyComponents(components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := writeComponentFiles(t, components)
+	t.Setenv("TEST_KEY_COMPONENT_3", "hex:"+hex.EncodeToString(components[2]))
+
+	prompted := ""
+	src := &config.ComponentKeySource{
+		Components: []config.KeyComponent{
+			{Name: "A", Source: &config.FileKeySource{Path: paths[0]}, CheckValue: config.KeyCheckValue(components[0])},
+			{Name: "B", Source: &config.PromptKeySource{Label: "custodian B", Prompt: func(label string) (string, error) {
+				prompted = label
+				return base64.StdEncoding.EncodeToString(components[1]) + "\n", nil
+			}}},
+			{Name: "C", Source: &config.EnvKeySource{Name: "TEST_KEY_COMPONENT_3"}},
+		},
+		CheckValue: config.KeyCheckValue(key),
+	}
+	cfg, err := config.NewConfigFromSource(src)
+	if err != nil {
+		t.Fatalf("NewConfigFromSource() error = %v", err)
+	}
+	if prompted != "custodian B" {
+		t.Errorf("prompt label = %q", prompted)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Собранный ключ - XOR компонент
+	assertSameKey(t, enc, key)
+
+	tests := []struct {
+		name    string
+		modify  func(src *config.ComponentKeySource)
+		wantErr error
+	}{
+		{"missing component", func(src *config.ComponentKeySource) { src.Components[1].Source = nil }, config.ErrKeyComponent},
+		{"missing file", func(src *config.ComponentKeySource) {
+			src.Components[0].Source = &config.FileKeySource{Path: filepath.Join(t.TempDir(), "lost.txt")}
+		}, os.ErrNotExist},
+		{"one component", func(src *config.ComponentKeySource) { src.Components = src.Components[:1] }, config.ErrKeyComponent},
+		{"component KCV", func(src *config.ComponentKeySource) { src.Components[0].CheckValue = "000000" }, config.ErrKeyCheckValue},
+		{"assembled KCV without a component", func(src *config.ComponentKeySource) { src.Components = src.Components[:2] }, config.ErrKeyCheckValue},
+		{"component length", func(src *config.ComponentKeySource) {
+			src.Components[2].Source = &literalKeySource{base64.StdEncoding.EncodeToString(make([]byte, 16))}
+		}, config.ErrKeyComponent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broken := *src
+			broken.Components = append([]config.KeyComponent(nil), src.Components...)
+			tt.modify(&broken)
+			if _, err := broken.LoadKey(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadKey() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateKeyComponents(t *testing.T) {
+	if _, err := config.GenerateKeyComponents(config.AlgorithmAES256GCM, 1); !errors.Is(err, config.ErrKeyComponent) {
+		t.Errorf("GenerateKeyComponents(1) error = %v, want ErrKeyComponent", err)
+	}
+	components, err := config.GenerateKeyComponents(config.AlgorithmChaCha20Poly1305, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := config.CombineKeyComponents(components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 || string(key) == string(components[0]) || string(key) == string(components[1]) {
+		t.Error("assembled key equals a component")
+	}
+}
+
+// literalKeySource источник, возвращающий заданный ключ
+type literalKeySource struct {
+	key string
+}
+
+func (s *literalKeySource) LoadKey() (string, error) {
+	return s.key, nil
+}
@@ -0,0 +1,74 @@
+package encryption_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// cancelAfterContext сообщает об отмене после заданного числа проверок Err()
+type cancelAfterContext struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.checks <= 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+func TestEncryptor_ContextCanceled(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := encryptor.EncryptStringContext(ctx, "secret"); !errors.Is(err, context.Canceled) {
+		t.Errorf("EncryptStringContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	encrypted, err := encryptor.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	if _, err := encryptor.DecryptStringContext(ctx, encrypted); !errors.Is(err, context.Canceled) {
+		t.Errorf("DecryptStringContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	user := struct {
+		Password string `encrypted:"true"`
+	}{Password: "secret"}
+	if err := encryptor.EncryptFieldsContext(ctx, &user); !errors.Is(err, context.Canceled) {
+		t.Errorf("EncryptFieldsContext() error = %v, want %v", err, context.Canceled)
+	}
+	if user.Password != "secret" {
+		t.Errorf("EncryptFieldsContext() modified field after cancellation: %q", user.Password)
+	}
+}
+
+func TestEncryptor_BatchStopsOnCancel(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	values := []string{"a", "b", "c", "d"}
+
+	got, err := encryptor.EncryptBatch(values)
+	if err != nil {
+		t.Fatalf("EncryptBatch() error = %v", err)
+	}
+	decrypted, err := encryptor.DecryptBatch(got)
+	if err != nil {
+		t.Fatalf("DecryptBatch() error = %v", err)
+	}
+	for i := range values {
+		if decrypted[i] != values[i] {
+			t.Errorf("DecryptBatch()[%d] = %q, want %q", i, decrypted[i], values[i])
+		}
+	}
+
+	// Контекст отменяется после обработки двух элементов
+	ctx := &cancelAfterContext{Context: context.Background(), checks: 4}
+	if _, err := encryptor.EncryptBatchContext(ctx, values); !errors.Is(err, context.Canceled) {
+		t.Errorf("EncryptBatchContext() error = %v, want %v", err, context.Canceled)
+	}
+}
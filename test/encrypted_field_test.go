@@ -0,0 +1,154 @@
+package encryption_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+type limits struct {
+	Max    int      `json:"max"`
+	Labels []string `json:"labels"`
+}
+
+type account struct {
+	Name    string
+	Balance encryption.Encrypted[int]       `json:"balance" yaml:"balance"`
+	Expires encryption.Encrypted[time.Time] `json:"expires" yaml:"expires"`
+	Limits  *encryption.Encrypted[limits]   `json:"limits" yaml:"limits"`
+	Quotas  map[string]encryption.Encrypted[int]
+	Blob    []byte `encrypted:"true"`
+	NilBlob []byte `encrypted:"true"`
+}
+
+func newAccount() account {
+	return account{
+		Name:    "acme",
+		Balance: encryption.Encrypted[int]{Value: 42},
+		Expires: encryption.Encrypted[time.Time]{Value: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Limits:  &encryption.Encrypted[limits]{Value: limits{Max: 10, Labels: []string{"a"}}},
+		Quotas:  map[string]encryption.Encrypted[int]{"cpu": {Value: 8}},
+		Blob:    []byte{0x00, 0xff, 0xfe, 'x'},
+	}
+}
+
+func TestEncryptedField(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	acc := newAccount()
+
+	if err := encryptor.EncryptFields(&acc); err != nil {
+		t.Fatalf("EncryptFields() error = %v", err)
+	}
+	if !acc.Balance.IsSealed() || acc.Balance.Value != 0 || !acc.Expires.IsSealed() || !acc.Expires.Value.IsZero() ||
+		!acc.Limits.IsSealed() || !acc.Quotas["cpu"].IsSealed() {
+		t.Fatalf("EncryptFields() left values open: %+v", acc)
+	}
+	if !bytes.HasPrefix(acc.Blob, []byte("ENC[")) || acc.NilBlob != nil {
+		t.Errorf("Blob = %q, NilBlob = %v", acc.Blob, acc.NilBlob)
+	}
+
+	// Повторное шифрование не теряет зашифрованные значения
+	sealed := acc.Balance.Ciphertext
+	if err := encryptor.EncryptFields(&acc); err != nil || acc.Balance.Ciphertext != sealed {
+		t.Errorf("second EncryptFields() = %v, ciphertext changed: %v", err, acc.Balance.Ciphertext != sealed)
+	}
+	// []byte шифруется повторно, как и строки: расшифровываем один лишний слой
+	if blob, err := encryptor.DecryptString(string(acc.Blob)); err != nil {
+		t.Fatal(err)
+	} else {
+		acc.Blob = []byte(blob)
+	}
+
+	if err := encryptor.DecryptFields(&acc); err != nil {
+		t.Fatalf("DecryptFields() error = %v", err)
+	}
+	want := newAccount()
+	if acc.Balance != want.Balance || !acc.Expires.Value.Equal(want.Expires.Value) || acc.Limits.Value.Max != 10 ||
+		acc.Limits.Value.Labels[0] != "a" || acc.Quotas["cpu"].Value != 8 || !bytes.Equal(acc.Blob, want.Blob) {
+		t.Errorf("DecryptFields() = %+v, want %+v", acc, want)
+	}
+}
+
+func TestEncryptedField_Marshal(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	acc := newAccount()
+	if err := encryptor.EncryptFields(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	// Открытое значение записывается как есть
+	plain, err := json.Marshal(encryption.Encrypted[int]{Value: 7})
+	if err != nil || string(plain) != "7" {
+		t.Errorf("json.Marshal(open) = %s, %v", plain, err)
+	}
+
+	tests := []struct {
+		name      string
+		marshal   func(interface{}) ([]byte, error)
+		unmarshal func([]byte, interface{}) error
+	}{
+		{"json", json.Marshal, json.Unmarshal},
+		{"yaml", yaml.Marshal, yaml.Unmarshal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.marshal(acc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "2030") || !strings.Contains(string(data), acc.Balance.Ciphertext) {
+				t.Fatalf("marshaled sealed account = %s", data)
+			}
+			var got account
+			if err := tt.unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := encryptor.DecryptFields(&got); err != nil {
+				t.Fatalf("DecryptFields() error = %v", err)
+			}
+			if got.Balance.Value != 42 || got.Expires.Value.Year() != 2030 || got.Limits.Value.Max != 10 {
+				t.Errorf("round trip = %+v", got)
+			}
+		})
+	}
+
+	var open account
+	if err := yaml.Unmarshal([]byte("balance: 5\n"), &open); err != nil || open.Balance.IsSealed() || open.Balance.Value != 5 {
+		t.Errorf("yaml open value = %+v, %v", open.Balance, err)
+	}
+}
+
+func TestEncryptedField_Unsupported(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	tests := []struct {
+		name string
+		data interface{}
+		path string
+	}{
+		{"int", &struct {
+			Age int `encrypted:"true"`
+		}{42}, "field Age"},
+		{"time", &struct {
+			Created time.Time `encrypted:"true"`
+		}{time.Now()}, "field Created"},
+		{"int slice", &struct {
+			Ports []int `encrypted:"true"`
+		}{[]int{80}}, "field Ports[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := encryptor.EncryptFields(tt.data)
+			if !errors.Is(err, interfaces.ErrUnsupportedField) || !strings.Contains(err.Error(), tt.path+":") {
+				t.Errorf("EncryptFields() error = %v, want ErrUnsupportedField at %s", err, tt.path)
+			}
+		})
+	}
+}
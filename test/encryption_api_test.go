@@ -15,9 +15,29 @@ func TestNewEncryptor(t *testing.T) {
 	}{
 		{
 			name:    "valid key",
-			key:     "12345678901234567890123456789012",
+			key:     "9ZqRT1fBkJm4cW0/yH7EoX2vLdA8sNu3gPiKe6Ot5Cw=",
 			wantErr: false,
 		},
+		{
+			name:    "hex key",
+			key:     "hex:f59a914f57c19099b8716d3fc87ec4a17daf2dd03cb0dbb780f88a7ba3ade42c",
+			wantErr: false,
+		},
+		{
+			name:    "raw key",
+			key:     "raw:Vq8#mZ2!xL5@pT9$kW3^nR7&bY1*cF6+",
+			wantErr: false,
+		},
+		{
+			name:    "ascii key without prefix",
+			key:     "Vq8#mZ2!xL5@pT9$kW3^nR7&bY1*cF6+",
+			wantErr: true,
+		},
+		{
+			name:    "invalid hex key",
+			key:     "hex:not-hex",
+			wantErr: true,
+		},
 		{
 			name:    "invalid key length",
 			key:     "short",
@@ -29,9 +49,9 @@ func TestNewEncryptor(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:    "base64 key",
+			name:    "low entropy base64 key",
 			key:     "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=",
-			wantErr: false,
+			wantErr: true,
 		},
 	}
 
@@ -53,7 +73,7 @@ func TestNewEncryptor(t *testing.T) {
 }
 
 func TestEncryptor_EncryptDecrypt(t *testing.T) {
-	key := "12345678901234567890123456789012"
+	key := "9ZqRT1fBkJm4cW0/yH7EoX2vLdA8sNu3gPiKe6Ot5Cw="
 	cfg, err := config.NewConfig(key)
 	if err != nil {
 		t.Fatalf("Failed to create config: %v", err)
@@ -121,7 +141,7 @@ func TestEncryptor_EncryptDecrypt(t *testing.T) {
 }
 
 func TestEncryptor_DecryptInvalid(t *testing.T) {
-	key := "12345678901234567890123456789012"
+	key := "9ZqRT1fBkJm4cW0/yH7EoX2vLdA8sNu3gPiKe6Ot5Cw="
 	cfg, err := config.NewConfig(key)
 	if err != nil {
 		t.Fatalf("Failed to create config: %v", err)
@@ -0,0 +1,234 @@
+package encryption_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+)
+
+type credentials struct {
+	User     string
+	Password string `encrypted:"true"`
+}
+
+type Base struct {
+	Token string `encrypted:"true"`
+}
+
+type hidden struct {
+	Secret string `encrypted:"true"`
+}
+
+type databaseConfig struct {
+	Host    string
+	Primary credentials
+	Replica *credentials
+}
+
+type node struct {
+	Name   string `encrypted:"true"`
+	Next   *node
+	secret string `encrypted:"true"`
+}
+
+type serviceConfig struct {
+	Base
+	hidden
+	Database struct {
+		Main databaseConfig
+	}
+	Extra    interface{}
+	ExtraPtr interface{}
+	Nil      *credentials
+	Ring     *node
+}
+
+func TestFields_Nested(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	shared := &credentials{User: "shared", Password: "shared-secret"}
+
+	ring := &node{Name: "a", secret: "s"}
+	ring.Next = &node{Name: "b", Next: ring}
+
+	cfg := serviceConfig{
+		Base:     Base{Token: "token"},
+		hidden:   hidden{Secret: "hidden"},
+		Extra:    credentials{User: "extra", Password: "extra-secret"},
+		ExtraPtr: shared,
+		Ring:     ring,
+	}
+	cfg.Database.Main = databaseConfig{
+		Host:    "db",
+		Primary: credentials{User: "admin", Password: "primary-secret"},
+		Replica: shared,
+	}
+
+	if err := encryptor.EncryptFields(&cfg); err != nil {
+		t.Fatalf("EncryptFields() error = %v", err)
+	}
+	for name, value := range map[string]string{
+		"Base.Token":                     cfg.Token,
+		"hidden.Secret":                  cfg.Secret,
+		"Database.Main.Primary.Password": cfg.Database.Main.Primary.Password,
+		"Database.Main.Replica.Password": cfg.Database.Main.Replica.Password,
+		"Extra.Password":                 cfg.Extra.(credentials).Password,
+		"Ring.Name":                      cfg.Ring.Name,
+		"Ring.Next.Name":                 cfg.Ring.Next.Name,
+	} {
+		if !strings.HasPrefix(value, "ENC[") {
+			t.Errorf("%s = %q, want encrypted", name, value)
+		}
+	}
+	if cfg.Database.Main.Host != "db" || cfg.Database.Main.Primary.User != "admin" {
+		t.Error("untagged fields were modified")
+	}
+	if ring.secret != "s" {
+		t.Error("unexported field was modified")
+	}
+
+	// Указатель, доступный по двум путям, и цикл обрабатываются один раз
+	if err := encryptor.DecryptFields(&cfg); err != nil {
+		t.Fatalf("DecryptFields() error = %v", err)
+	}
+	if shared.Password != "shared-secret" || ring.Name != "a" || ring.Next.Name != "b" {
+		t.Errorf("shared or cyclic values = %q, %q, %q", shared.Password, ring.Name, ring.Next.Name)
+	}
+	if cfg.Token != "token" || cfg.Secret != "hidden" || cfg.Database.Main.Primary.Password != "primary-secret" ||
+		cfg.Extra.(credentials).Password != "extra-secret" {
+		t.Errorf("DecryptFields() = %+v", cfg)
+	}
+}
+
+func TestFields_SharedPointer(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+
+	// Указатель, сначала достигнутый через поле без тега, все равно шифруется в помеченном поле
+	secret := "hunter2"
+	cfg := struct {
+		Name   *string
+		Secret *string `encrypted:"true"`
+		Alias  *string `encrypted:"true"`
+	}{Name: &secret, Secret: &secret, Alias: &secret}
+	if err := encryptor.EncryptFields(&cfg); err != nil {
+		t.Fatalf("EncryptFields() error = %v", err)
+	}
+	if !strings.HasPrefix(*cfg.Secret, "ENC[") {
+		t.Fatalf("*Secret = %q, want encrypted", *cfg.Secret)
+	}
+	// Два помеченных поля с одним указателем шифруют значение один раз
+	if err := encryptor.DecryptFields(&cfg); err != nil || secret != "hunter2" {
+		t.Errorf("DecryptFields() = %q, %v", secret, err)
+	}
+
+	// То же для структур: поля без тега и с тегом обходят общую структуру один раз
+	creds := &credentials{Password: "p"}
+	nested := struct {
+		Plain  *credentials
+		Tagged *credentials `encrypted:"true"`
+	}{creds, creds}
+	if err := encryptor.EncryptFields(&nested); err != nil {
+		t.Fatal(err)
+	}
+	if err := encryptor.DecryptFields(&nested); err != nil || creds.Password != "p" {
+		t.Errorf("shared struct after round trip = %q, %v", creds.Password, err)
+	}
+}
+
+func TestFields_ErrorPath(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	cfg := databaseConfig{Primary: credentials{Password: "ENC[AES256:broken]"}}
+
+	err := encryptor.DecryptFields(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "field Primary.Password") {
+		t.Errorf("DecryptFields() error = %v, want the field path", err)
+	}
+
+	if err := encryptor.EncryptFields(cfg); !errors.Is(err, interfaces.ErrInvalidData) {
+		t.Errorf("EncryptFields(struct value) error = %v, want ErrInvalidData", err)
+	}
+}
+
+type containerConfig struct {
+	Tokens  []string          `encrypted:"true"`
+	Pair    [2]string         `encrypted:"true"`
+	Secrets map[string]string `encrypted:"true"`
+	ByID    map[int]*string   `encrypted:"true"`
+	Nested  [][]string        `encrypted:"true"`
+	Users   []credentials
+	Servers map[string]databaseConfig
+	Plain   []string
+	Empty   []string          `encrypted:"true"`
+	NilMap  map[string]string `encrypted:"true"`
+}
+
+func TestFields_Containers(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	id := "by-id"
+	cfg := containerConfig{
+		Tokens:  []string{"t1", "t2"},
+		Pair:    [2]string{"p1", "p2"},
+		Secrets: map[string]string{"api": "s1", "db": "s2"},
+		ByID:    map[int]*string{7: &id},
+		Nested:  [][]string{{"n1"}, {"n2", "n3"}},
+		Users:   []credentials{{User: "u", Password: "u-secret"}},
+		Servers: map[string]databaseConfig{"eu": {Host: "eu", Primary: credentials{Password: "eu-secret"}}},
+		Plain:   []string{"plain"},
+		Empty:   []string{},
+	}
+
+	if err := encryptor.EncryptFields(&cfg); err != nil {
+		t.Fatalf("EncryptFields() error = %v", err)
+	}
+	encrypted := []string{cfg.Tokens[0], cfg.Tokens[1], cfg.Pair[0], cfg.Pair[1], cfg.Secrets["api"], cfg.Secrets["db"],
+		id, cfg.Nested[0][0], cfg.Nested[1][1], cfg.Users[0].Password, cfg.Servers["eu"].Primary.Password}
+	for i, value := range encrypted {
+		if !strings.HasPrefix(value, "ENC[") {
+			t.Errorf("value %d = %q, want encrypted", i, value)
+		}
+	}
+	if cfg.Plain[0] != "plain" || cfg.Users[0].User != "u" || cfg.Servers["eu"].Host != "eu" {
+		t.Errorf("untagged values were modified: %+v", cfg)
+	}
+	if _, ok := cfg.Secrets["api"]; !ok || len(cfg.Secrets) != 2 {
+		t.Errorf("map keys were modified: %v", cfg.Secrets)
+	}
+
+	if err := encryptor.DecryptFields(&cfg); err != nil {
+		t.Fatalf("DecryptFields() error = %v", err)
+	}
+	if cfg.Tokens[1] != "t2" || cfg.Pair[0] != "p1" || cfg.Secrets["db"] != "s2" || id != "by-id" || cfg.Nested[1][1] != "n3" ||
+		cfg.Users[0].Password != "u-secret" || cfg.Servers["eu"].Primary.Password != "eu-secret" {
+		t.Errorf("DecryptFields() = %+v", cfg)
+	}
+}
+
+func TestFields_ContainerErrorPath(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+	tests := []struct {
+		name string
+		cfg  interface{}
+		path string
+	}{
+		{"slice", &struct {
+			Tokens []string `encrypted:"true"`
+		}{[]string{mustEncrypt(t, encryptor, "ok"), "broken"}}, "field Tokens[1]"},
+		{"map", &struct {
+			Secrets map[string]string `encrypted:"true"`
+		}{map[string]string{"api": "broken"}}, `field Secrets["api"]`},
+		{"struct in map", &struct {
+			Servers map[string]databaseConfig
+		}{map[string]databaseConfig{"eu": {Primary: credentials{Password: "broken"}}}}, `field Servers["eu"].Primary.Password`},
+		{"struct in slice", &struct {
+			Users []credentials
+		}{[]credentials{{Password: "broken"}}}, "field Users[0].Password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := encryptor.DecryptFields(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.path+":") {
+				t.Errorf("DecryptFields() error = %v, want path %s", err, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package encryption_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	internalenc "github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// newEmbedKeyIDEncryptor создает шифратор, записывающий отпечаток ключа в конверт
+func newEmbedKeyIDEncryptor(t *testing.T, key []byte) *encryption.Encryptor {
+	t.Helper()
+	cfg, err := config.NewConfig(base64.StdEncoding.EncodeToString(key), config.WithEmbedKeyID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	return enc
+}
+
+func TestKeyCheckValue(t *testing.T) {
+	// AES-256 нулевого блока нулевым ключом: dc95c078a2408989ad48a21492842087
+	if got := config.KeyCheckValue(make([]byte, 32)); got != "DC95C0" {
+		t.Errorf("KeyCheckValue(zero key) = %q, want DC95C0", got)
+	}
+	if got := config.KeyCheckValue(make([]byte, 7)); got != "" {
+		t.Errorf("KeyCheckValue(7 bytes) = %q, want empty", got)
+	}
+}
+
+func TestEmbedKeyID(t *testing.T) {
+	keyA, keyB := randomKey(t, 32), randomKey(t, 32)
+	encA, encB := newEmbedKeyIDEncryptor(t, keyA), newEmbedKeyIDEncryptor(t, keyB)
+
+	encrypted := mustEncrypt(t, encA, "secret")
+	env, err := internalenc.ParseEnvelope(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Attrs[internalenc.AttrKeyFingerprint] != config.KeyFingerprint(keyA) {
+		t.Errorf("kfp = %q, want the key fingerprint %q", env.Attrs[internalenc.AttrKeyFingerprint], config.KeyFingerprint(keyA))
+	}
+	if got, err := encA.DecryptString(encrypted); err != nil || got != "secret" {
+		t.Errorf("DecryptString() = %q, %v", got, err)
+	}
+	// Ключ без записи отпечатка расшифровывает такие значения тем же ключом
+	if got, err := newEncryptorForKey(t, base64.StdEncoding.EncodeToString(keyA)).DecryptString(encrypted); err != nil || got != "secret" {
+		t.Errorf("DecryptString() without EmbedKeyID = %q, %v", got, err)
+	}
+
+	_, err = encB.DecryptString(encrypted)
+	var mismatch *interfaces.KeyMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, interfaces.ErrKeyMismatch) || !errors.Is(err, interfaces.ErrDecryptionFailed) {
+		t.Fatalf("DecryptString() with another key error = %v, want KeyMismatchError", err)
+	}
+	if mismatch.Expected != config.KeyFingerprint(keyA) || mismatch.Actual != config.KeyFingerprint(keyB) {
+		t.Errorf("KeyMismatchError = %+v, want expected %s, actual %s", mismatch, config.KeyFingerprint(keyA), config.KeyFingerprint(keyB))
+	}
+
+	// Тот же ключ, но поврежденные данные: это не ошибка выбора ключа
+	payload, _ := base64.StdEncoding.DecodeString(env.Payload)
+	payload[len(payload)-1] ^= 1
+	env.Payload = base64.StdEncoding.EncodeToString(payload)
+	if _, err := encA.DecryptString(env.String()); !errors.Is(err, interfaces.ErrDecryptionFailed) || errors.Is(err, interfaces.ErrKeyMismatch) {
+		t.Errorf("DecryptString(tampered) error = %v, want ErrDecryptionFailed without ErrKeyMismatch", err)
+	}
+
+	// Подключи арендаторов записывают отпечаток мастер-ключа
+	tenantValue := mustEncrypt(t, encA.ForTenant("acme"), "secret")
+	if !strings.Contains(tenantValue, "kfp="+config.KeyFingerprint(keyA)) {
+		t.Errorf("tenant value %s does not carry the master key fingerprint", tenantValue)
+	}
+	if _, err := encB.ForTenant("acme").DecryptString(tenantValue); !errors.Is(err, interfaces.ErrKeyMismatch) {
+		t.Errorf("DecryptString(tenant value, another key) error = %v, want ErrKeyMismatch", err)
+	}
+}
+
+func TestKeyMismatch_WithoutKeyID(t *testing.T) {
+	encA, encB := newRandomEncryptor(t), newRandomEncryptor(t)
+	if _, err := encB.DecryptString(mustEncrypt(t, encA, "secret")); !errors.Is(err, interfaces.ErrDecryptionFailed) || errors.Is(err, interfaces.ErrKeyMismatch) {
+		t.Errorf("DecryptString() error = %v, want ErrDecryptionFailed: without kfp the key cannot be told apart", err)
+	}
+}
+
+func TestKeyMismatch_Keyring(t *testing.T) {
+	ring := config.NewKeyring()
+	if err := ring.Add(newKeyringKey(t, "k1", config.KeyStateActive)); err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := saveKeyring(t, ring)
+
+	other := config.NewKeyring()
+	if err := other.Add(newKeyringKey(t, "k9", config.KeyStateActive)); err != nil {
+		t.Fatal(err)
+	}
+	otherEnc, _ := saveKeyring(t, other)
+
+	_, err := enc.DecryptString(mustEncrypt(t, otherEnc, "secret"))
+	var mismatch *interfaces.KeyMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Expected != "k9" {
+		t.Errorf("DecryptString() error = %v, want KeyMismatchError naming k9", err)
+	}
+}
+
+func TestEmbedKeyID_KeyringMigration(t *testing.T) {
+	raw := randomKey(t, 32)
+	single := newEmbedKeyIDEncryptor(t, raw)
+
+	ring := config.NewKeyring()
+	key := newKeyringKey(t, "2024-01", config.KeyStateActive)
+	key.Key = base64.StdEncoding.EncodeToString(raw)
+	if err := ring.Add(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Add(newKeyringKey(t, "2024-02", config.KeyStateActive)); err != nil {
+		t.Fatal(err)
+	}
+	keyring, _ := saveKeyring(t, ring)
+
+	// Значение с отпечатком kfp открывается набором, в который перенесен ключ
+	if got, err := keyring.DecryptString(mustEncrypt(t, single, "secret")); err != nil || got != "secret" {
+		t.Errorf("keyring DecryptString(kfp value) = %q, %v", got, err)
+	}
+
+	// Значение набора с kid открывается тем же ключом без набора
+	ring2 := config.NewKeyring()
+	if err := ring2.Add(key); err != nil {
+		t.Fatal(err)
+	}
+	keyring2, _ := saveKeyring(t, ring2)
+	if got, err := single.DecryptString(mustEncrypt(t, keyring2, "secret")); err != nil || got != "secret" {
+		t.Errorf("single key DecryptString(kid value) = %q, %v", got, err)
+	}
+
+	// Отпечатка нет в наборе: ошибка выбора ключа, а не повреждение
+	_, err := keyring.DecryptString(mustEncrypt(t, newEmbedKeyIDEncryptor(t, randomKey(t, 32)), "secret"))
+	if !errors.Is(err, interfaces.ErrKeyMismatch) {
+		t.Errorf("keyring DecryptString(unknown kfp) error = %v, want ErrKeyMismatch", err)
+	}
+}
@@ -0,0 +1,169 @@
+package encryption_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	internalenc "github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+func TestNewConfig_KeyValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		opts    []config.Option
+		wantErr error
+	}{
+		{name: "base64", key: testKey},
+		{name: "base64 prefix", key: "base64:" + testKey},
+		{name: "hex prefix", key: "hex:" + hex.EncodeToString(mustDecode(t, testKey))},
+		{name: "raw prefix", key: "raw:Vq8#mZ2!xL5@pT9$kW3^nR7&bY1*cF6+"},
+		{
+			name: "hex encoding option",
+			key:  hex.EncodeToString(mustDecode(t, testKey)),
+			opts: []config.Option{config.WithKeyEncoding(config.KeyEncodingHex)},
+		},
+		{
+			name: "prefix overrides encoding option",
+			key:  "raw:Vq8#mZ2!xL5@pT9$kW3^nR7&bY1*cF6+",
+			opts: []config.Option{config.WithKeyEncoding(config.KeyEncodingHex)},
+		},
+		{
+			name:    "ascii key is not base64",
+			key:     "Vq8#mZ2!xL5@pT9$kW3^nR7&bY1*cF6+",
+			wantErr: config.ErrInvalidKeyEncoding,
+		},
+		{
+			name:    "invalid hex",
+			key:     "hex:zz",
+			wantErr: config.ErrInvalidKeyEncoding,
+		},
+		{
+			name:    "unknown encoding option",
+			key:     testKey,
+			opts:    []config.Option{config.WithKeyEncoding("base32")},
+			wantErr: config.ErrInvalidKeyEncoding,
+		},
+		{
+			name:    "base64 of 24 bytes",
+			key:     "12345678901234567890123456789012",
+			wantErr: config.ErrInvalidKeyLength,
+		},
+		{
+			name:    "raw key too long",
+			key:     "raw:Vq8#mZ2!xL5@pT9$kW3^nR7&bY1*cF6+extra",
+			wantErr: config.ErrInvalidKeyLength,
+		},
+		{
+			name:    "repeated bytes",
+			key:     "hex:" + hex.EncodeToString(make([]byte, 32)),
+			wantErr: config.ErrWeakKey,
+		},
+		{
+			name:    "digits",
+			key:     "raw:12345678901234567890123456789012",
+			wantErr: config.ErrWeakKey,
+		},
+		{
+			name: "legacy ascii key",
+			key:  "12345678901234567890123456789012",
+			opts: []config.Option{config.WithLegacyKey()},
+		},
+		{
+			name:    "legacy short key",
+			key:     "short",
+			opts:    []config.Option{config.WithLegacyKey()},
+			wantErr: config.ErrInvalidKeyLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.NewConfig(tt.key, tt.opts...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("NewConfig() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if _, err := encryption.NewEncryptor(cfg); err != nil {
+				t.Errorf("NewEncryptor() error = %v", err)
+			}
+		})
+	}
+}
+
+// Один и тот же ключ в разных кодировках дает совместимые шифраторы
+func TestNewConfig_KeyEncodingsInteroperate(t *testing.T) {
+	raw := mustDecode(t, testKey)
+	keys := []string{testKey, "base64:" + testKey, "hex:" + hex.EncodeToString(raw)}
+
+	first := newEncryptorForKey(t, keys[0])
+	encrypted, err := first.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	for _, key := range keys[1:] {
+		enc := newEncryptorForKey(t, key)
+		if got, err := enc.DecryptString(encrypted); err != nil || got != "secret" {
+			t.Errorf("DecryptString() with %q = %q, %v", key, got, err)
+		}
+	}
+}
+
+// Режим совместимости расшифровывает значения, зашифрованные дополненным ключом
+func TestNewConfig_LegacyKey(t *testing.T) {
+	const legacyKey = "12345678901234567890123456789012"
+	cfg, err := config.NewConfig(legacyKey, config.WithLegacyKey())
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	encrypted, err := enc.EncryptString("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Прежняя обработка: base64 "1234...12" дает 24 байта, дополненные нулями до 32
+	padded := make([]byte, 32)
+	copy(padded, mustDecode(t, legacyKey))
+	exact, err := internalenc.NewEncryptorFromKey(padded, internalenc.DefaultAlgorithm)
+	if err != nil {
+		t.Fatalf("NewEncryptorFromKey() error = %v", err)
+	}
+	if got, err := exact.Decrypt(encrypted); err != nil || got != "secret" {
+		t.Errorf("Decrypt() with zero-padded key = %q, %v", got, err)
+	}
+}
+
+func mustDecode(t *testing.T, key string) []byte {
+	t.Helper()
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newEncryptorForKey(t *testing.T, key string) *encryption.Encryptor {
+	t.Helper()
+	cfg, err := config.NewConfig(key)
+	if err != nil {
+		t.Fatalf("NewConfig(%q) error = %v", key, err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	return enc
+}
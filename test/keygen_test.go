@@ -0,0 +1,169 @@
+package encryption_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	internalenc "github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+func TestGenerateKey(t *testing.T) {
+	for _, alg := range []string{config.AlgorithmAES256GCM, config.AlgorithmChaCha20Poly1305} {
+		t.Run(alg, func(t *testing.T) {
+			a, err := config.GenerateKey(alg)
+			if err != nil {
+				t.Fatalf("GenerateKey() error = %v", err)
+			}
+			b, err := config.GenerateKey(alg)
+			if err != nil {
+				t.Fatalf("GenerateKey() error = %v", err)
+			}
+			if len(a) != 32 {
+				t.Errorf("GenerateKey() length = %d, want 32", len(a))
+			}
+			if bytes.Equal(a, b) {
+				t.Error("GenerateKey() returned the same key twice")
+			}
+
+			// Сгенерированный ключ проходит политику FIPS для AES256
+			if alg != config.AlgorithmAES256GCM {
+				return
+			}
+			cfg, err := config.NewConfig(base64.StdEncoding.EncodeToString(a), config.WithPolicy(config.FIPSPolicy()))
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			if _, err := encryption.NewEncryptor(cfg); err != nil {
+				t.Errorf("NewEncryptor() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := config.GenerateKey(config.AlgorithmAES256CBC); err == nil {
+		t.Error("GenerateKey() for unsupported algorithm succeeded")
+	}
+}
+
+func TestGenerateKey_MatchesRegistry(t *testing.T) {
+	// Для каждого зарегистрированного алгоритма генерируется ключ нужной ему длины
+	for _, name := range internalenc.Algorithms() {
+		alg, _ := internalenc.LookupAlgorithm(name)
+		key, err := config.GenerateKey(name)
+		if err != nil {
+			t.Errorf("GenerateKey(%s) error = %v", name, err)
+			continue
+		}
+		if len(key) != alg.KeySize {
+			t.Errorf("GenerateKey(%s) length = %d, registry key size %d", name, len(key), alg.KeySize)
+		}
+		if _, err := alg.NewAEAD(key); err != nil {
+			t.Errorf("%s NewAEAD(generated key) error = %v", name, err)
+		}
+	}
+
+	// Ключи генерируются только для зарегистрированных алгоритмов AEAD
+	for _, name := range []string{config.AlgorithmAES256CBC, config.AlgorithmAES256CTRHMAC, config.AlgorithmTransit} {
+		if _, ok := internalenc.LookupAlgorithm(name); ok {
+			continue
+		}
+		if _, err := config.GenerateKey(name); err == nil {
+			t.Errorf("GenerateKey(%s) succeeded for an algorithm missing from the registry", name)
+		}
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	key := randomKey(t, 32)
+	fingerprint := config.KeyFingerprint(key)
+	if len(fingerprint) != 16 || fingerprint != config.KeyFingerprint(key) {
+		t.Fatalf("KeyFingerprint() = %q, want stable 16 hex characters", fingerprint)
+	}
+	if fingerprint == config.KeyFingerprint(randomKey(t, 32)) {
+		t.Error("different keys have the same fingerprint")
+	}
+
+	// Отпечаток совпадает с идентификатором ключа шифратора
+	cfg, err := config.NewConfig(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := encryption.NewUsageTracker(encryption.NewMemoryUsageStore())
+	enc, err := encryption.NewEncryptor(cfg, encryption.WithUsageTracker(tracker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := enc.KeyUsage()
+	if err != nil {
+		t.Fatalf("KeyUsage() error = %v", err)
+	}
+	if status.KeyID != fingerprint {
+		t.Errorf("KeyID = %q, want fingerprint %q", status.KeyID, fingerprint)
+	}
+}
+
+func TestNewKeyringKey(t *testing.T) {
+	key, err := config.NewKeyringKey("2024-01", config.AlgorithmAES256GCM)
+	if err != nil {
+		t.Fatalf("NewKeyringKey() error = %v", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(key.Key)
+	if err != nil || len(raw) != 32 {
+		t.Fatalf("key = %q, want 32 bytes in base64", key.Key)
+	}
+	if key.Fingerprint != config.KeyFingerprint(raw) {
+		t.Errorf("Fingerprint = %q, want %q", key.Fingerprint, config.KeyFingerprint(raw))
+	}
+
+	ring := config.NewKeyring()
+	if err := ring.Add(key); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// Отпечаток, не совпадающий с ключом, отклоняется
+	tampered := key
+	tampered.ID = "tampered"
+	tampered.Fingerprint = "0000000000000000"
+	tampered.State = config.KeyStateActive
+	if err := ring.Add(tampered); err == nil {
+		t.Error("Add() with wrong fingerprint succeeded")
+	}
+	// Отклоненный ключ не попадает в набор и не снимает активный ключ
+	if len(ring.Keys) != 1 || ring.Keys[0].State != config.KeyStateActive {
+		t.Errorf("keyring after failed Add() = %+v", ring.Keys)
+	}
+	if err := ring.Validate(); err != nil {
+		t.Errorf("Validate() after failed Add() error = %v", err)
+	}
+}
+
+func TestWriteKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.txt")
+	if err := config.WriteKeyFile(path, []byte("secret\n")); err != nil {
+		t.Fatalf("WriteKeyFile() error = %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("key file mode = %04o, want 0600", perm)
+		}
+	}
+
+	// Существующий файл не перезаписывается
+	if err := config.WriteKeyFile(path, []byte("other\n")); !errors.Is(err, os.ErrExist) {
+		t.Errorf("WriteKeyFile() over existing file error = %v, want os.ErrExist", err)
+	}
+	key, err := (&config.FileKeySource{Path: path}).LoadKey()
+	if err != nil || key != "secret" {
+		t.Errorf("LoadKey() = %q, %v, want original key", key, err)
+	}
+}
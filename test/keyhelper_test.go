@@ -0,0 +1,191 @@
+package encryption_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// buildRefHelper собирает эталонную программу-помощник и файл ключей для нее
+func buildRefHelper(t *testing.T, keys map[string][]byte, kek []byte) (bin, keysPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	bin = filepath.Join(dir, "refhelper")
+	out, err := exec.Command("go", "build", "-o", bin, "github.com/JohnnyFes/go-encryptor/internal/keyhelper/refhelper").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go build refhelper: %v: %s", err, out)
+	}
+
+	file := struct {
+		Keys map[string]string `json:"keys"`
+		KEK  string            `json:"kek,omitempty"`
+	}{Keys: make(map[string]string)}
+	for id, key := range keys {
+		file.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	if kek != nil {
+		file.KEK = base64.StdEncoding.EncodeToString(kek)
+	}
+	data, _ := json.Marshal(file)
+	keysPath = filepath.Join(dir, "keys.json")
+	if err := os.WriteFile(keysPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return bin, keysPath
+}
+
+func randomKey(t *testing.T, size int) []byte {
+	t.Helper()
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// assertSameKey проверяет, что enc шифрует ключом key
+func assertSameKey(t *testing.T, enc *encryption.Encryptor, key []byte) {
+	t.Helper()
+	encrypted, err := enc.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	cfg, err := config.NewConfig(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	local, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	if decrypted, err := local.DecryptString(encrypted); err != nil || decrypted != "secret" {
+		t.Errorf("local DecryptString() = %q, %v: helper key was not used", decrypted, err)
+	}
+}
+
+func TestKeyHelper_Get(t *testing.T) {
+	defaultKey, paymentsKey := randomKey(t, 32), randomKey(t, 32)
+	bin, keysPath := buildRefHelper(t, map[string][]byte{"default": defaultKey, "payments": paymentsKey}, nil)
+
+	tests := []struct {
+		name  string
+		keyID string
+		want  []byte
+	}{
+		{name: "default key", want: defaultKey},
+		{name: "key by id", keyID: "payments", want: paymentsKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.NewKeyHelperConfig(&config.KeyHelperConfig{
+				Command: bin,
+				Args:    []string{"-keys", keysPath},
+				KeyID:   tt.keyID,
+			})
+			if err != nil {
+				t.Fatalf("NewKeyHelperConfig() error = %v", err)
+			}
+			enc, err := encryption.NewEncryptor(cfg)
+			if err != nil {
+				t.Fatalf("NewEncryptor() error = %v", err)
+			}
+			assertSameKey(t, enc, tt.want)
+		})
+	}
+}
+
+func TestKeyHelper_Unwrap(t *testing.T) {
+	kek, key := randomKey(t, 32), randomKey(t, 32)
+	bin, keysPath := buildRefHelper(t, nil, kek)
+
+	// Заворачиваем ключ так же, как это сделал бы сервис ключей
+	block, _ := aes.NewCipher(kek)
+	aead, _ := cipher.NewGCM(block)
+	nonce := randomKey(t, aead.NonceSize())
+	wrapped := aead.Seal(nonce, nonce, key, []byte("payments"))
+
+	cfg, err := config.NewKeyHelperConfig(&config.KeyHelperConfig{
+		Command:    bin,
+		Args:       []string{"-keys", keysPath},
+		KeyID:      "payments",
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+	})
+	if err != nil {
+		t.Fatalf("NewKeyHelperConfig() error = %v", err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	assertSameKey(t, enc, key)
+}
+
+func TestNewKeyHelperConfig_EmptyCommand(t *testing.T) {
+	for _, helper := range []*config.KeyHelperConfig{nil, {}, {Command: " \t"}} {
+		if _, err := config.NewKeyHelperConfig(helper); err == nil {
+			t.Errorf("NewKeyHelperConfig(%+v) succeeded, want an error", helper)
+		}
+	}
+}
+
+func TestKeyHelper_Errors(t *testing.T) {
+	bin, keysPath := buildRefHelper(t, map[string][]byte{"default": randomKey(t, 32), "short": randomKey(t, 16)}, nil)
+
+	tests := []struct {
+		name    string
+		helper  config.KeyHelperConfig
+		wantErr string
+	}{
+		{
+			name:    "unknown key",
+			helper:  config.KeyHelperConfig{Command: bin, Args: []string{"-keys", keysPath}, KeyID: "missing"},
+			wantErr: `key "missing" not found`,
+		},
+		{
+			name:    "wrong key size",
+			helper:  config.KeyHelperConfig{Command: bin, Args: []string{"-keys", keysPath}, KeyID: "short"},
+			wantErr: "16-byte key",
+		},
+		{
+			name:    "timeout",
+			helper:  config.KeyHelperConfig{Command: bin, Args: []string{"-keys", keysPath, "-delay", "10s"}, Timeout: 200 * time.Millisecond},
+			wantErr: "timed out",
+		},
+		{
+			name:    "helper exits with error",
+			helper:  config.KeyHelperConfig{Command: bin, Args: []string{"-unknown-flag"}},
+			wantErr: "flag provided but not defined",
+		},
+		{
+			name:    "missing executable",
+			helper:  config.KeyHelperConfig{Command: filepath.Join(t.TempDir(), "missing")},
+			wantErr: "failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := tt.helper
+			cfg, err := config.NewKeyHelperConfig(&helper)
+			if err != nil {
+				t.Fatalf("NewKeyHelperConfig() error = %v", err)
+			}
+			_, err = encryption.NewEncryptor(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewEncryptor() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
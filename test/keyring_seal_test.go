@@ -0,0 +1,196 @@
+package encryption_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// saveSealedKeyring сохраняет набор из одного активного ключа под парольной фразой
+func saveSealedKeyring(t *testing.T, name, passphrase string) (string, config.KeyringKey) {
+	t.Helper()
+	key := newKeyringKey(t, "k1", config.KeyStateActive)
+	ring := config.NewKeyring()
+	if err := ring.Add(key); err != nil {
+		t.Fatal(err)
+	}
+	ring.SetPassphrase([]byte(passphrase))
+	path := filepath.Join(t.TempDir(), name)
+	if err := ring.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	return path, key
+}
+
+func TestKeyring_Passphrase(t *testing.T) {
+	for _, name := range []string{"keys.json", "keys.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path, key := saveSealedKeyring(t, name, "correct horse")
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Имя поля state слишком длинное, чтобы случайно встретиться в base64 шифротекста
+			if strings.Contains(string(data), key.Key) || strings.Contains(string(data), "state") {
+				t.Fatal("encrypted keyring contains key material or metadata in plaintext")
+			}
+
+			ring, err := config.LoadKeyring(path, config.WithPassphrase([]byte("correct horse")))
+			if err != nil {
+				t.Fatalf("LoadKeyring() error = %v", err)
+			}
+			if got, ok := ring.Lookup("k1"); !ok || got.Key != key.Key || !ring.Encrypted() {
+				t.Errorf("LoadKeyring() = %+v, want the saved key", ring.Keys)
+			}
+
+			if _, err := config.LoadKeyring(path, config.WithPassphrase([]byte("wrong"))); !errors.Is(err, config.ErrKeyringPassphrase) {
+				t.Errorf("LoadKeyring(wrong passphrase) error = %v, want ErrKeyringPassphrase", err)
+			}
+		})
+	}
+}
+
+func TestKeyring_PassphraseFromEnv(t *testing.T) {
+	path, _ := saveSealedKeyring(t, "keys.json", "from-env")
+
+	t.Setenv(config.KeyringPassphraseEnv, "")
+	if _, err := config.LoadKeyring(path); !errors.Is(err, config.ErrKeyringLocked) {
+		t.Errorf("LoadKeyring() without passphrase error = %v, want ErrKeyringLocked", err)
+	}
+
+	t.Setenv(config.KeyringPassphraseEnv, "from-env")
+	cfg, err := config.NewKeyringConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	if _, err := enc.EncryptString("secret"); err != nil {
+		t.Errorf("EncryptString() error = %v", err)
+	}
+}
+
+func TestKeyring_ChangePassphrase(t *testing.T) {
+	path, key := saveSealedKeyring(t, "keys.json", "old")
+
+	cfg, err := config.NewKeyringConfig(path, config.WithKeyringPassphrase(func() ([]byte, error) {
+		return []byte("old"), nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	encrypted := mustEncrypt(t, enc, "secret")
+
+	ring, err := config.LoadKeyring(path, config.WithPassphrase([]byte("old")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring.SetPassphrase([]byte("new"))
+	if err := ring.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if _, err := config.LoadKeyring(path, config.WithPassphrase([]byte("old"))); !errors.Is(err, config.ErrKeyringPassphrase) {
+		t.Errorf("LoadKeyring(old passphrase) error = %v, want ErrKeyringPassphrase", err)
+	}
+	ring, err = config.LoadKeyring(path, config.WithPassphrase([]byte("new")))
+	if err != nil {
+		t.Fatalf("LoadKeyring(new passphrase) error = %v", err)
+	}
+	if got, _ := ring.Lookup("k1"); got == nil || got.Key != key.Key {
+		t.Error("keys changed with the passphrase")
+	}
+
+	cfg, _ = config.NewKeyringConfig(path, config.WithKeyringPassphrase(func() ([]byte, error) {
+		return []byte("new"), nil
+	}))
+	enc, err = encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	if got, err := enc.DecryptString(encrypted); err != nil || got != "secret" {
+		t.Errorf("DecryptString() after passwd = %q, %v", got, err)
+	}
+}
+
+func TestKeyring_SealedTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(sealed map[string]interface{})
+		wantErr string
+	}{
+		{
+			name: "ciphertext",
+			modify: func(sealed map[string]interface{}) {
+				ct := []byte(sealed["ciphertext"].(string))
+				ct[10] ^= 'A' ^ 'B'
+				if ct[10] == '=' || ct[10] == '+' {
+					ct[10] = 'C'
+				}
+				sealed["ciphertext"] = string(ct)
+			},
+			wantErr: "wrong keyring passphrase",
+		},
+		{
+			name:    "huge Argon2id memory",
+			modify:  func(sealed map[string]interface{}) { sealed["memory"] = 1 << 30 },
+			wantErr: "invalid Argon2id parameters",
+		},
+		{
+			name:    "unknown KDF",
+			modify:  func(sealed map[string]interface{}) { sealed["kdf"] = "scrypt" },
+			wantErr: "unsupported keyring encryption",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := saveSealedKeyring(t, "keys.json", "pass")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var file map[string]interface{}
+			if err := json.Unmarshal(data, &file); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(file["sealed"].(map[string]interface{}))
+			data, _ = json.Marshal(file)
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = config.LoadKeyring(path, config.WithPassphrase([]byte("pass")))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadKeyring() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyring_PassphrasePolicy(t *testing.T) {
+	path, _ := saveSealedKeyring(t, "keys.json", "pass")
+	cfg, err := config.NewKeyringConfig(path,
+		config.WithPolicy(config.FIPSPolicy()),
+		config.WithKeyringPassphrase(func() ([]byte, error) { return []byte("pass"), nil }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encryption.NewEncryptor(cfg); !errors.Is(err, config.ErrPolicyViolation) {
+		t.Errorf("NewEncryptor() error = %v, want ErrPolicyViolation for Argon2id", err)
+	}
+}
@@ -0,0 +1,322 @@
+package encryption_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// newKeyringKey создает ключ набора со случайным ключевым материалом
+func newKeyringKey(t *testing.T, id string, state config.KeyState) config.KeyringKey {
+	t.Helper()
+	return config.KeyringKey{
+		ID:        id,
+		Algorithm: config.AlgorithmAES256GCM,
+		Key:       base64.StdEncoding.EncodeToString(randomKey(t, 32)),
+		State:     state,
+		Created:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// saveKeyring сохраняет набор ключей во временный файл и открывает по нему шифратор
+func saveKeyring(t *testing.T, ring *config.Keyring, opts ...config.Option) (*encryption.Encryptor, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := ring.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	cfg, err := config.NewKeyringConfig(path, opts...)
+	if err != nil {
+		t.Fatalf("NewKeyringConfig() error = %v", err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	return enc, path
+}
+
+func TestKeyring_SaveLoad(t *testing.T) {
+	for _, name := range []string{"keys.json", "keys.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			ring := config.NewKeyring()
+			if err := ring.Add(newKeyringKey(t, "2024-01", "")); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			expiring := newKeyringKey(t, "2024-02", "")
+			expires := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			expiring.Expires = &expires
+			if err := ring.Add(expiring); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), name)
+			if err := ring.Save(path); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if runtime.GOOS != "windows" {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if perm := info.Mode().Perm(); perm != 0o600 {
+					t.Errorf("keyring mode = %04o, want 0600", perm)
+				}
+			}
+
+			loaded, err := config.LoadKeyring(path)
+			if err != nil {
+				t.Fatalf("LoadKeyring() error = %v", err)
+			}
+			if len(loaded.Keys) != 2 {
+				t.Fatalf("loaded %d keys, want 2", len(loaded.Keys))
+			}
+			if active, ok := loaded.Active(); !ok || active.ID != "2024-01" {
+				t.Errorf("Active() = %v, %v, want 2024-01", active, ok)
+			}
+			second, _ := loaded.Lookup("2024-02")
+			if second.State != config.KeyStateDecryptOnly {
+				t.Errorf("second key state = %q, want %q", second.State, config.KeyStateDecryptOnly)
+			}
+			if second.Expires == nil || !second.Expires.Equal(expires) {
+				t.Errorf("second key expires = %v, want %v", second.Expires, expires)
+			}
+		})
+	}
+}
+
+func TestKeyring_LoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		wantErr string
+	}{
+		{
+			name:    "insecure permissions",
+			content: `{"version":1,"keys":[]}`,
+			mode:    0o644,
+			wantErr: "insecure",
+		},
+		{
+			name:    "unsupported version",
+			content: `{"version":2,"keys":[]}`,
+			mode:    0o600,
+			wantErr: "unsupported keyring version",
+		},
+		{
+			name: "two active keys",
+			content: `{"version":1,"keys":[
+				{"id":"a","algorithm":"AES256","key":"` + testKey + `","state":"active"},
+				{"id":"b","algorithm":"AES256","key":"` + testKey + `","state":"active"}]}`,
+			mode:    0o600,
+			wantErr: "at most one",
+		},
+		{
+			name:    "unknown state",
+			content: `{"version":1,"keys":[{"id":"a","algorithm":"AES256","key":"` + testKey + `","state":"paused"}]}`,
+			mode:    0o600,
+			wantErr: "unknown state",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && tt.mode != 0o600 {
+				t.Skip("file permissions are not checked on windows")
+			}
+			path := filepath.Join(t.TempDir(), "keys.json")
+			if err := os.WriteFile(path, []byte(tt.content), tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			_, err := config.LoadKeyring(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadKeyring() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyring_Lifecycle(t *testing.T) {
+	ring := config.NewKeyring()
+	for _, id := range []string{"a", "b"} {
+		if err := ring.Add(newKeyringKey(t, id, "")); err != nil {
+			t.Fatalf("Add(%s) error = %v", id, err)
+		}
+	}
+
+	state := func(id string) config.KeyState {
+		k, _ := ring.Lookup(id)
+		return k.State
+	}
+
+	if err := ring.Promote("b"); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	if state("a") != config.KeyStateDecryptOnly || state("b") != config.KeyStateActive {
+		t.Errorf("after Promote(b): a=%s b=%s", state("a"), state("b"))
+	}
+
+	if err := ring.Revoke("a"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := ring.Promote("a"); err == nil {
+		t.Error("Promote() of revoked key succeeded")
+	}
+
+	if err := ring.Retire("b"); err != nil {
+		t.Fatalf("Retire() error = %v", err)
+	}
+	if _, ok := ring.Active(); ok {
+		t.Error("Active() found a key after retiring the only active key")
+	}
+
+	if err := ring.Add(newKeyringKey(t, "b", "")); err == nil {
+		t.Error("Add() of duplicate id succeeded")
+	}
+	if err := ring.Promote("missing"); err == nil {
+		t.Error("Promote() of unknown key succeeded")
+	}
+}
+
+func TestKeyring_Encryptor(t *testing.T) {
+	ring := config.NewKeyring()
+	oldKey := newKeyringKey(t, "old", config.KeyStateActive)
+	if err := ring.Add(oldKey); err != nil {
+		t.Fatal(err)
+	}
+	oldEnc, _ := saveKeyring(t, ring)
+	oldValue, err := oldEnc.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	if !strings.HasPrefix(oldValue, "ENC[AES256;kid=old:") {
+		t.Errorf("EncryptString() = %q, want kid=old in the envelope", oldValue)
+	}
+
+	// Новый ключ продвигается, старый остается для расшифровки
+	if err := ring.Add(newKeyringKey(t, "new", config.KeyStateActive)); err != nil {
+		t.Fatal(err)
+	}
+	enc, path := saveKeyring(t, ring)
+	newValue, err := enc.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	if !strings.HasPrefix(newValue, "ENC[AES256;kid=new:") {
+		t.Errorf("EncryptString() = %q, want kid=new in the envelope", newValue)
+	}
+	for _, v := range []string{oldValue, newValue} {
+		if got, err := enc.DecryptString(v); err != nil || got != "secret" {
+			t.Errorf("DecryptString(%q) = %q, %v", v, got, err)
+		}
+	}
+
+	// Значение, зашифрованное ключом до перехода на набор ключей (без kid)
+	legacyCfg, err := config.NewConfig(oldKey.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := encryption.NewEncryptor(legacyCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyValue, err := legacy.EncryptString("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := enc.DecryptString(legacyValue); err != nil || got != "secret" {
+		t.Errorf("DecryptString(legacy) = %q, %v", got, err)
+	}
+
+	// Отозванный ключ больше не расшифровывает
+	if err := ring.Revoke("old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := config.NewKeyringConfig(path)
+	revoked, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	if _, err := revoked.DecryptString(oldValue); !errors.Is(err, interfaces.ErrKeyRevoked) {
+		t.Errorf("DecryptString(revoked) error = %v, want ErrKeyRevoked", err)
+	}
+	if _, err := revoked.DecryptString(legacyValue); !errors.Is(err, interfaces.ErrDecryptionFailed) {
+		t.Errorf("DecryptString(legacy, revoked) error = %v, want ErrDecryptionFailed", err)
+	}
+	if got, err := revoked.DecryptString(newValue); err != nil || got != "secret" {
+		t.Errorf("DecryptString(new) = %q, %v", got, err)
+	}
+}
+
+func TestKeyring_EncryptErrors(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	expired := newKeyringKey(t, "expired", config.KeyStateActive)
+	expired.Expires = &past
+
+	tests := []struct {
+		name    string
+		keys    []config.KeyringKey
+		wantErr error
+	}{
+		{
+			name:    "no active key",
+			keys:    []config.KeyringKey{newKeyringKey(t, "a", config.KeyStateDecryptOnly)},
+			wantErr: interfaces.ErrNoActiveKey,
+		},
+		{
+			name:    "expired active key",
+			keys:    []config.KeyringKey{expired},
+			wantErr: interfaces.ErrKeyExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring := &config.Keyring{Version: config.KeyringVersion, Keys: tt.keys}
+			enc, _ := saveKeyring(t, ring)
+			if _, err := enc.EncryptString("secret"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("EncryptString() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyring_Policy(t *testing.T) {
+	ring := config.NewKeyring()
+	if err := ring.Add(newKeyringKey(t, "aes", config.KeyStateActive)); err != nil {
+		t.Fatal(err)
+	}
+	chacha := newKeyringKey(t, "chacha", config.KeyStateDecryptOnly)
+	chacha.Algorithm = "CHACHA20"
+	if err := ring.Add(chacha); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := ring.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.NewKeyringConfig(path, config.WithPolicy(config.FIPSPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encryption.NewEncryptor(cfg); !errors.Is(err, config.ErrPolicyViolation) {
+		t.Errorf("NewEncryptor() error = %v, want ErrPolicyViolation", err)
+	}
+}
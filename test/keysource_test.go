@@ -0,0 +1,91 @@
+package encryption_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+const sourceKey = "fPEwQLy7sDHHV/XN0msaaR2UbQcQZYAQcQO/zEIeXw8="
+
+func TestKeySource_Load(t *testing.T) {
+	t.Setenv("TEST_ENCRYPTION_KEY", sourceKey+"\n")
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte(sourceKey+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		src  config.KeySource
+	}{
+		{name: "env", src: &config.EnvKeySource{Name: "TEST_ENCRYPTION_KEY"}},
+		{name: "file", src: &config.FileKeySource{Path: keyFile}},
+		{name: "reader", src: &config.ReaderKeySource{Reader: strings.NewReader(sourceKey + "\r\n"), Name: "test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.NewConfigFromSource(tt.src)
+			if err != nil {
+				t.Fatalf("NewConfigFromSource() error = %v", err)
+			}
+			if cfg.Key != sourceKey {
+				t.Errorf("Key = %q, want %q", cfg.Key, sourceKey)
+			}
+		})
+	}
+}
+
+func TestKeySource_Errors(t *testing.T) {
+	dir := t.TempDir()
+	insecure := filepath.Join(dir, "insecure")
+	if err := os.WriteFile(insecure, []byte(sourceKey), 0644); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		src     config.KeySource
+		wantErr error
+	}{
+		{name: "unset env", src: &config.EnvKeySource{Name: "TEST_ENCRYPTION_KEY_UNSET"}},
+		{name: "missing file", src: &config.FileKeySource{Path: filepath.Join(dir, "missing")}, wantErr: os.ErrNotExist},
+		{name: "empty file", src: &config.FileKeySource{Path: empty}, wantErr: config.ErrEmptyKey},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name    string
+			src     config.KeySource
+			wantErr error
+		}{name: "insecure permissions", src: &config.FileKeySource{Path: insecure}, wantErr: config.ErrInsecureKeyFile})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.src.LoadKey()
+			if err == nil {
+				t.Fatalf("LoadKey() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadKey() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Проверку прав можно отключить явно
+	if _, err := (&config.FileKeySource{Path: insecure, AllowInsecurePermissions: true}).LoadKey(); err != nil {
+		t.Errorf("LoadKey() with AllowInsecurePermissions error = %v", err)
+	}
+}
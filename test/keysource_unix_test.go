@@ -0,0 +1,37 @@
+//go:build unix
+
+package encryption_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+)
+
+func TestKeySource_FD(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString(sourceKey + "\n"); err != nil {
+		t.Fatalf("Failed to write pipe: %v", err)
+	}
+	w.Close()
+
+	// FDKeySource закрывает дескриптор, поэтому передаем копию
+	fd, err := syscall.Dup(int(r.Fd()))
+	if err != nil {
+		t.Fatalf("Failed to dup descriptor: %v", err)
+	}
+
+	key, err := (&config.FDKeySource{FD: uintptr(fd)}).LoadKey()
+	if err != nil {
+		t.Fatalf("LoadKey() error = %v", err)
+	}
+	if key != sourceKey {
+		t.Errorf("LoadKey() = %q, want %q", key, sourceKey)
+	}
+}
@@ -0,0 +1,258 @@
+package encryption_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/internal/kms"
+	"github.com/JohnnyFes/go-encryptor/internal/kms/kmstest"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+var kmsCreds = kms.Credentials{AccessKeyID: "AKIDTEST", SecretAccessKey: "test-secret", SessionToken: "test-session"}
+
+func newKMSServer(t *testing.T) *kmstest.Server {
+	t.Helper()
+	srv := kmstest.NewServer(kmsCreds)
+	t.Cleanup(srv.Close)
+	srv.AddKey("alias/app")
+	return srv
+}
+
+func newKMSEncryptor(t *testing.T, srv *kmstest.Server, kmsCfg config.KMSConfig, opts ...config.Option) *encryption.Encryptor {
+	t.Helper()
+	kmsCfg.Endpoint = srv.URL
+	kmsCfg.Region = "eu-central-1"
+	if kmsCfg.KeyID == "" {
+		kmsCfg.KeyID = "alias/app"
+	}
+	if kmsCfg.AccessKeyID == "" {
+		kmsCfg.AccessKeyID = kmsCreds.AccessKeyID
+		kmsCfg.SecretAccessKey = kmsCreds.SecretAccessKey
+		kmsCfg.SessionToken = kmsCreds.SessionToken
+	}
+	cfg, err := config.NewKMSConfig(&kmsCfg, opts...)
+	if err != nil {
+		t.Fatalf("NewKMSConfig() error = %v", err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	return enc
+}
+
+func TestSignRequest_KnownAnswer(t *testing.T) {
+	// Пример get-vanilla из набора тестов AWS Signature Version 4
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	kms.SignRequest(req, nil, kms.Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s, want %s", got, want)
+	}
+}
+
+func TestKMS_Roundtrip(t *testing.T) {
+	srv := newKMSServer(t)
+
+	tests := []struct {
+		name string
+		opts []config.Option
+		alg  string
+	}{
+		{name: "aes-256-gcm", alg: "AES256"},
+		{name: "chacha20-poly1305", opts: []config.Option{config.WithAlgorithm("CHACHA20")}, alg: "CHACHA20"},
+		{name: "fips policy", opts: []config.Option{config.WithPolicy(config.FIPSPolicy())}, alg: "AES256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := newKMSEncryptor(t, srv, config.KMSConfig{}, tt.opts...)
+
+			encrypted, err := enc.EncryptString("secret data")
+			if err != nil {
+				t.Fatalf("EncryptString() error = %v", err)
+			}
+			if !strings.HasPrefix(encrypted, "ENC["+tt.alg+";dk=") {
+				t.Errorf("EncryptString() = %s, want wrapped data key in envelope", encrypted)
+			}
+
+			decrypted, err := enc.DecryptString(encrypted)
+			if err != nil || decrypted != "secret data" {
+				t.Errorf("DecryptString() = %q, %v, want %q", decrypted, err, "secret data")
+			}
+		})
+	}
+}
+
+func TestKMS_DataKeyCache(t *testing.T) {
+	srv := newKMSServer(t)
+	writer := newKMSEncryptor(t, srv, config.KMSConfig{DataKeyTTL: time.Hour})
+
+	values, err := writer.EncryptBatch([]string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("EncryptBatch() error = %v", err)
+	}
+	if got := srv.Calls("GenerateDataKey"); got != 1 {
+		t.Errorf("GenerateDataKey calls = %d, want 1", got)
+	}
+
+	// Новый экземпляр расшифровывает ключ данных один раз
+	reader := newKMSEncryptor(t, srv, config.KMSConfig{DataKeyTTL: time.Hour})
+	decrypted, err := reader.DecryptBatch(values)
+	if err != nil {
+		t.Fatalf("DecryptBatch() error = %v", err)
+	}
+	if strings.Join(decrypted, ",") != "a,b,c" {
+		t.Errorf("DecryptBatch() = %v", decrypted)
+	}
+	if got := srv.Calls("Decrypt"); got != 1 {
+		t.Errorf("Decrypt calls = %d, want 1", got)
+	}
+}
+
+func TestKMS_DataKeyCacheSize(t *testing.T) {
+	srv := newKMSServer(t)
+	var values []string
+	for i := 0; i < 3; i++ {
+		values = append(values, mustEncrypt(t, newKMSEncryptor(t, srv, config.KMSConfig{}), "secret"))
+	}
+
+	reader := newKMSEncryptor(t, srv, config.KMSConfig{DataKeyTTL: time.Hour, DataKeyCacheSize: 2})
+	decrypt := func(value string, wantCalls int) {
+		t.Helper()
+		if got, err := reader.DecryptString(value); err != nil || got != "secret" {
+			t.Fatalf("DecryptString() = %q, %v", got, err)
+		}
+		if got := srv.Calls("Decrypt"); got != wantCalls {
+			t.Errorf("Decrypt calls = %d, want %d", got, wantCalls)
+		}
+	}
+	decrypt(values[0], 1)
+	decrypt(values[1], 2)
+	decrypt(values[0], 2)
+	// Третий ключ вытесняет давно не использованный второй
+	decrypt(values[2], 3)
+	decrypt(values[0], 3)
+	decrypt(values[1], 4)
+
+	// Ключ данных в конверте другого алгоритма не берется из кэша
+	swapped := strings.Replace(values[1], "ENC[AES256;", "ENC[CHACHA20;", 1)
+	if _, err := reader.DecryptString(swapped); !errors.Is(err, interfaces.ErrDecryptionFailed) {
+		t.Errorf("DecryptString(swapped algorithm) error = %v, want ErrDecryptionFailed", err)
+	}
+	if got := srv.Calls("Decrypt"); got != 5 {
+		t.Errorf("Decrypt calls = %d, want the swapped-algorithm key decrypted again", got)
+	}
+}
+
+func TestKMS_DataKeyTTL(t *testing.T) {
+	srv := newKMSServer(t)
+	enc := newKMSEncryptor(t, srv, config.KMSConfig{DataKeyTTL: 20 * time.Millisecond})
+
+	first, err := enc.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	second, err := enc.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+
+	if got := srv.Calls("GenerateDataKey"); got != 2 {
+		t.Errorf("GenerateDataKey calls = %d, want 2 after TTL expiry", got)
+	}
+	dataKey := func(v string) string { return strings.SplitN(v, ":", 2)[0] }
+	if dataKey(first) == dataKey(second) {
+		t.Error("data key was not rotated after TTL expiry")
+	}
+
+	// Значение с истекшим ключом расшифровывается повторным вызовом KMS
+	if decrypted, err := enc.DecryptString(first); err != nil || decrypted != "secret" {
+		t.Errorf("DecryptString() = %q, %v", decrypted, err)
+	}
+}
+
+func TestKMS_Errors(t *testing.T) {
+	srv := newKMSServer(t)
+	enc := newKMSEncryptor(t, srv, config.KMSConfig{EncryptionContext: map[string]string{"app": "billing"}})
+	encrypted, err := enc.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+
+	t.Run("invalid signature", func(t *testing.T) {
+		bad := newKMSEncryptor(t, srv, config.KMSConfig{AccessKeyID: kmsCreds.AccessKeyID, SecretAccessKey: "wrong"})
+		if _, err := bad.EncryptString("secret"); !errors.Is(err, interfaces.ErrEncryptionFailed) {
+			t.Errorf("EncryptString() error = %v, want ErrEncryptionFailed", err)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		bad := newKMSEncryptor(t, srv, config.KMSConfig{KeyID: "alias/missing"})
+		if _, err := bad.EncryptString("secret"); !errors.Is(err, interfaces.ErrEncryptionFailed) {
+			t.Errorf("EncryptString() error = %v, want ErrEncryptionFailed", err)
+		}
+	})
+
+	t.Run("encryption context mismatch", func(t *testing.T) {
+		other := newKMSEncryptor(t, srv, config.KMSConfig{EncryptionContext: map[string]string{"app": "payroll"}})
+		if _, err := other.DecryptString(encrypted); !errors.Is(err, interfaces.ErrDecryptionFailed) {
+			t.Errorf("DecryptString() error = %v, want ErrDecryptionFailed", err)
+		}
+	})
+
+	t.Run("tampered data key", func(t *testing.T) {
+		tampered := strings.Replace(encrypted, ";dk=", ";dk=AAAA", 1)
+		if _, err := enc.DecryptString(tampered); !errors.Is(err, interfaces.ErrDecryptionFailed) {
+			t.Errorf("DecryptString() error = %v, want ErrDecryptionFailed", err)
+		}
+	})
+}
+
+func TestNewKMSConfig_Environment(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "us-west-2")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	kmsCfg := &config.KMSConfig{KeyID: "alias/app"}
+	cfg, err := config.NewKMSConfig(kmsCfg)
+	if err != nil {
+		t.Fatalf("NewKMSConfig() error = %v", err)
+	}
+	if cfg.KMS.Region != "us-west-2" || cfg.KMS.AccessKeyID != "AKIDENV" {
+		t.Errorf("NewKMSConfig() region = %q, access key = %q", cfg.KMS.Region, cfg.KMS.AccessKeyID)
+	}
+	// Значения окружения не записываются в структуру вызывающего
+	if kmsCfg.Region != "" || kmsCfg.AccessKeyID != "" || kmsCfg.SecretAccessKey != "" {
+		t.Errorf("NewKMSConfig() modified its argument: %+v", kmsCfg)
+	}
+
+	// Повторный вызов с той же структурой видит новое окружение
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDROTATED")
+	if cfg, err := config.NewKMSConfig(kmsCfg); err != nil || cfg.KMS.AccessKeyID != "AKIDROTATED" {
+		t.Errorf("NewKMSConfig() after env change = %v, %v, want the new access key", cfg, err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	if _, err := config.NewKMSConfig(&config.KMSConfig{KeyID: "alias/app"}); err == nil {
+		t.Error("NewKMSConfig() without credentials error = nil")
+	}
+	if _, err := config.NewKMSConfig(&config.KMSConfig{}); err == nil {
+		t.Error("NewKMSConfig() without key ID error = nil")
+	}
+}
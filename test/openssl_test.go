@@ -0,0 +1,168 @@
+package encryption_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// Фикстуры в testdata/openssl сгенерированы командой openssl enc -aes-256-cbc
+// с парольной фразой opensslPassphrase из файла plaintext.txt
+const opensslPassphrase = "openssl-pass"
+
+func TestOpenSSL_DecryptFixtures(t *testing.T) {
+	want, err := os.ReadFile("testdata/openssl/plaintext.txt")
+	if err != nil {
+		t.Fatalf("Failed to read plaintext: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		opts []encryption.OpenSSLOption
+	}{
+		{
+			name: "pbkdf2 base64",
+			file: "pbkdf2.txt",
+		},
+		{
+			name: "pbkdf2 binary",
+			file: "pbkdf2.bin",
+		},
+		{
+			name: "pbkdf2 sha512 200000 iterations",
+			file: "pbkdf2-iter200000-sha512.txt",
+			opts: []encryption.OpenSSLOption{
+				encryption.WithOpenSSLDigest("sha512"),
+				encryption.WithOpenSSLIterations(200000),
+			},
+		},
+		{
+			name: "legacy md5",
+			file: "legacy-md5.txt",
+			opts: []encryption.OpenSSLOption{
+				encryption.WithOpenSSLLegacyKDF(),
+				encryption.WithOpenSSLDigest("md5"),
+			},
+		},
+		{
+			name: "legacy sha256",
+			file: "legacy-sha256.txt",
+			opts: []encryption.OpenSSLOption{encryption.WithOpenSSLLegacyKDF()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata/openssl", tt.file))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			got, err := encryption.OpenSSLDecrypt(opensslPassphrase, data, tt.opts...)
+			if err != nil {
+				t.Fatalf("OpenSSLDecrypt() error = %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("OpenSSLDecrypt() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestOpenSSL_EncryptDecrypt(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts []encryption.OpenSSLOption
+	}{
+		{name: "simple text", text: "Hello, World!"},
+		{name: "empty text", text: ""},
+		{name: "long text", text: "this text is long enough to be wrapped into several base64 lines by the encoder"},
+		{name: "legacy kdf", text: "Привет, мир!", opts: []encryption.OpenSSLOption{encryption.WithOpenSSLLegacyKDF()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := encryption.OpenSSLEncryptString(opensslPassphrase, tt.text, tt.opts...)
+			if err != nil {
+				t.Fatalf("OpenSSLEncryptString() error = %v", err)
+			}
+			decrypted, err := encryption.OpenSSLDecryptString(opensslPassphrase, encrypted, tt.opts...)
+			if err != nil {
+				t.Fatalf("OpenSSLDecryptString() error = %v", err)
+			}
+			if decrypted != tt.text {
+				t.Errorf("OpenSSLDecryptString() = %q, want %q", decrypted, tt.text)
+			}
+		})
+	}
+}
+
+func TestOpenSSL_SingleLine(t *testing.T) {
+	text := strings.Repeat("long secret ", 10)
+	wrapped, err := encryption.OpenSSLEncryptString(opensslPassphrase, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(wrapped, "\n") {
+		t.Errorf("OpenSSLEncryptString() = %q, want lines of 64 characters", wrapped)
+	}
+
+	single, err := encryption.OpenSSLEncryptString(opensslPassphrase, text, encryption.WithOpenSSLSingleLine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(single, "\n") || len(single) <= 64 {
+		t.Errorf("OpenSSLEncryptString(single line) = %q, want one line", single)
+	}
+	if got, err := encryption.OpenSSLDecryptString(opensslPassphrase, single); err != nil || got != text {
+		t.Errorf("OpenSSLDecryptString(single line) = %q, %v", got, err)
+	}
+}
+
+func TestOpenSSL_DecryptInvalid(t *testing.T) {
+	data, err := os.ReadFile("testdata/openssl/pbkdf2.txt")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		data       string
+		wantErr    error
+	}{
+		{
+			name:       "wrong passphrase",
+			passphrase: "wrong",
+			data:       string(data),
+			wantErr:    interfaces.ErrDecryptionFailed,
+		},
+		{
+			name:       "missing header",
+			passphrase: opensslPassphrase,
+			data:       "aGVsbG8gd29ybGQ=",
+			wantErr:    interfaces.ErrInvalidData,
+		},
+		{
+			name:       "invalid base64",
+			passphrase: opensslPassphrase,
+			data:       "not base64!",
+			wantErr:    interfaces.ErrInvalidData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encryption.OpenSSLDecryptString(tt.passphrase, tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("OpenSSLDecryptString() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
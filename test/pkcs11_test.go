@@ -0,0 +1,213 @@
+//go:build cgo
+
+package encryption_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	p11 "github.com/miekg/pkcs11"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+const (
+	softHSMToken = "go-encryptor"
+	softHSMPIN   = "1234"
+	softHSMKey   = "payments"
+)
+
+// softHSMModule ищет библиотеку SoftHSMv2 (или берет путь из SOFTHSM2_MODULE)
+func softHSMModule() string {
+	candidates := []string{
+		os.Getenv("SOFTHSM2_MODULE"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	}
+	for _, path := range candidates {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// setupSoftHSM создает временный токен SoftHSMv2 с AES-ключом softHSMKey
+func setupSoftHSM(t *testing.T) string {
+	t.Helper()
+	module := softHSMModule()
+	if module == "" {
+		t.Skip("SoftHSMv2 is not installed")
+	}
+	if _, err := exec.LookPath("softhsm2-util"); err != nil {
+		t.Skip("softhsm2-util is not installed")
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "softhsm2.conf")
+	tokens := filepath.Join(dir, "tokens")
+	if err := os.Mkdir(tokens, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(conf, []byte("directories.tokendir = "+tokens+"\nobjectstore.backend = file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	out, err := exec.Command("softhsm2-util", "--init-token", "--free", "--label", softHSMToken,
+		"--pin", softHSMPIN, "--so-pin", "5678").CombinedOutput()
+	if err != nil {
+		t.Fatalf("softhsm2-util: %v: %s", err, out)
+	}
+
+	ctx := p11.New(module)
+	if err := ctx.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	defer func() {
+		_ = ctx.Finalize()
+		ctx.Destroy()
+	}()
+	slots, err := ctx.GetSlotList(true)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("GetSlotList() = %v, %v", slots, err)
+	}
+	session, err := ctx.OpenSession(slots[0], p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatalf("OpenSession() error = %v", err)
+	}
+	defer ctx.CloseSession(session)
+	if err := ctx.Login(session, p11.CKU_USER, softHSMPIN); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	_, err = ctx.GenerateKey(session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_KEY_GEN, nil)}, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_LABEL, softHSMKey),
+		p11.NewAttribute(p11.CKA_VALUE_LEN, 32),
+		p11.NewAttribute(p11.CKA_ENCRYPT, true),
+		p11.NewAttribute(p11.CKA_DECRYPT, true),
+		p11.NewAttribute(p11.CKA_WRAP, true),
+		p11.NewAttribute(p11.CKA_UNWRAP, true),
+		p11.NewAttribute(p11.CKA_SENSITIVE, true),
+		p11.NewAttribute(p11.CKA_EXTRACTABLE, false),
+	})
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return module
+}
+
+func newPKCS11Encryptor(t *testing.T, module string, p config.PKCS11Config, opts ...config.Option) (*encryption.Encryptor, error) {
+	t.Helper()
+	p.ModulePath = module
+	p.TokenLabel = softHSMToken
+	p.PIN = softHSMPIN
+	if p.KeyLabel == "" {
+		p.KeyLabel = softHSMKey
+	}
+	cfg, err := config.NewPKCS11Config(&p, opts...)
+	if err != nil {
+		t.Fatalf("NewPKCS11Config() error = %v", err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err == nil {
+		t.Cleanup(func() { _ = enc.Close() })
+	}
+	return enc, err
+}
+
+func TestPKCS11_Roundtrip(t *testing.T) {
+	module := setupSoftHSM(t)
+
+	tests := []struct {
+		name   string
+		mode   string
+		opts   []config.Option
+		prefix string
+	}{
+		{name: "gcm in token", mode: config.PKCS11ModeGCM, prefix: "ENC[AES256;hsm=payments:"},
+		{name: "wrapped data key", mode: config.PKCS11ModeWrap, prefix: "ENC[AES256;dk="},
+		{name: "wrapped chacha20 key", mode: config.PKCS11ModeWrap, opts: []config.Option{config.WithAlgorithm("CHACHA20")}, prefix: "ENC[CHACHA20;dk="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := newPKCS11Encryptor(t, module, config.PKCS11Config{Mode: tt.mode}, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewEncryptor() error = %v", err)
+			}
+
+			encrypted, err := enc.EncryptString("card 4111")
+			if err != nil {
+				t.Fatalf("EncryptString() error = %v", err)
+			}
+			if !strings.HasPrefix(encrypted, tt.prefix) {
+				t.Errorf("EncryptString() = %s, want prefix %s", encrypted, tt.prefix)
+			}
+			decrypted, err := enc.DecryptString(encrypted)
+			if err != nil || decrypted != "card 4111" {
+				t.Errorf("DecryptString() = %q, %v, want %q", decrypted, err, "card 4111")
+			}
+		})
+	}
+}
+
+func TestPKCS11_Errors(t *testing.T) {
+	module := setupSoftHSM(t)
+
+	if _, err := newPKCS11Encryptor(t, module, config.PKCS11Config{KeyLabel: "missing"}); !errors.Is(err, interfaces.ErrInvalidConfig) {
+		t.Errorf("NewEncryptor() with unknown key error = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := newPKCS11Encryptor(t, module, config.PKCS11Config{}, config.WithAlgorithm("CHACHA20")); !errors.Is(err, interfaces.ErrInvalidConfig) {
+		t.Errorf("NewEncryptor() gcm mode with CHACHA20 error = %v, want ErrInvalidConfig", err)
+	}
+
+	enc, err := newPKCS11Encryptor(t, module, config.PKCS11Config{})
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	encrypted, err := enc.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	// Меняем символ шифротекста после nonce
+	i := strings.LastIndex(encrypted, ":") + 20
+	flipped := byte('A')
+	if encrypted[i] == 'A' {
+		flipped = 'B'
+	}
+	tampered := encrypted[:i] + string(flipped) + encrypted[i+1:]
+	if _, err := enc.DecryptString(tampered); !errors.Is(err, interfaces.ErrDecryptionFailed) {
+		t.Errorf("DecryptString(tampered) error = %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestNewPKCS11Config_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *config.PKCS11Config
+	}{
+		{name: "nil", p: nil},
+		{name: "no module", p: &config.PKCS11Config{KeyLabel: "k"}},
+		{name: "no key label", p: &config.PKCS11Config{ModulePath: "/lib/p11.so"}},
+		{name: "unknown mode", p: &config.PKCS11Config{ModulePath: "/lib/p11.so", KeyLabel: "k", Mode: "ecb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := config.NewPKCS11Config(tt.p); err == nil {
+				t.Error("NewPKCS11Config() error = nil, want error")
+			}
+		})
+	}
+}
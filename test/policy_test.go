@@ -0,0 +1,170 @@
+package encryption_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// fipsKey 32 случайных байта в base64
+const fipsKey = "QnOz+WFWmoSQ2yr3zyHoK6cvFengSU0wEO4NsIOmwwQ="
+
+func TestPolicy_NewEncryptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		opts    []config.Option
+		wantErr bool
+	}{
+		{
+			name: "approved algorithm and exact key",
+			key:  fipsKey,
+		},
+		{
+			name:    "legacy key padded with zeros",
+			key:     "12345678901234567890123456789012",
+			opts:    []config.Option{config.WithLegacyKey()},
+			wantErr: true,
+		},
+		{
+			name:    "legacy key hashed to 32 bytes",
+			key:     "this is a very long key that will be hashed to 32 bytes using SHA-256",
+			opts:    []config.Option{config.WithLegacyKey()},
+			wantErr: true,
+		},
+		{
+			name:    "non-approved algorithm",
+			key:     fipsKey,
+			opts:    []config.Option{config.WithAlgorithm(config.AlgorithmChaCha20Poly1305)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]config.Option{config.WithPolicy(config.FIPSPolicy())}, tt.opts...)
+			cfg, err := config.NewConfig(tt.key, opts...)
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			_, err = encryption.NewEncryptor(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEncryptor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, config.ErrPolicyViolation) {
+				t.Errorf("NewEncryptor() error = %v, want %v", err, config.ErrPolicyViolation)
+			}
+		})
+	}
+}
+
+func TestPolicy_DecryptRefusesDisallowedAlgorithm(t *testing.T) {
+	cfg, err := config.NewConfig(fipsKey, config.WithAlgorithm(config.AlgorithmChaCha20Poly1305))
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	chacha, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create encryptor: %v", err)
+	}
+	encrypted, err := chacha.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+
+	cfg, err = config.NewConfig(fipsKey, config.WithPolicy(config.FIPSPolicy()))
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	fips, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create encryptor: %v", err)
+	}
+
+	if _, err := fips.DecryptString(encrypted); !errors.Is(err, config.ErrPolicyViolation) {
+		t.Errorf("DecryptString() error = %v, want %v", err, config.ErrPolicyViolation)
+	}
+
+	// Без политики тот же ключ расшифровывает значение
+	cfg, _ = config.NewConfig(fipsKey)
+	plain, _ := encryption.NewEncryptor(cfg)
+	if got, err := plain.DecryptString(encrypted); err != nil || got != "secret" {
+		t.Errorf("DecryptString() = %q, %v, want %q", got, err, "secret")
+	}
+}
+
+func TestPolicy_CheckKDF(t *testing.T) {
+	policy := config.FIPSPolicy()
+
+	tests := []struct {
+		name       string
+		kdf        string
+		iterations int
+		wantErr    bool
+	}{
+		{name: "hkdf", kdf: config.KDFHKDFSHA256},
+		{name: "pbkdf2 enough iterations", kdf: config.KDFPBKDF2SHA256, iterations: 600000},
+		{name: "pbkdf2 too few iterations", kdf: config.KDFPBKDF2SHA256, iterations: 10000, wantErr: true},
+		{name: "evp bytes to key", kdf: config.KDFEVPBytesToKey, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.CheckKDF(tt.kdf, tt.iterations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckKDF() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_OpenSSL(t *testing.T) {
+	if err := encryption.CheckOpenSSLPolicy(config.FIPSPolicy()); !errors.Is(err, config.ErrPolicyViolation) {
+		t.Errorf("CheckOpenSSLPolicy(fips) error = %v, want %v", err, config.ErrPolicyViolation)
+	}
+
+	cbc := &config.Policy{
+		Name:                "cbc",
+		Algorithms:          []string{config.AlgorithmAES256CBC},
+		KDFs:                []string{config.KDFPBKDF2SHA256},
+		MinPBKDF2Iterations: 100000,
+	}
+	tests := []struct {
+		name    string
+		opts    []encryption.OpenSSLOption
+		wantErr bool
+	}{
+		{name: "default iterations", wantErr: true},
+		{name: "enough iterations", opts: []encryption.OpenSSLOption{encryption.WithOpenSSLIterations(600000)}},
+		{name: "sha1 digest", opts: []encryption.OpenSSLOption{encryption.WithOpenSSLIterations(600000), encryption.WithOpenSSLDigest("sha1")}, wantErr: true},
+		{name: "evp bytes to key", opts: []encryption.OpenSSLOption{encryption.WithOpenSSLLegacyKDF()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := encryption.CheckOpenSSLPolicy(cbc, tt.opts...)
+			if (err != nil) != tt.wantErr || (tt.wantErr && !errors.Is(err, config.ErrPolicyViolation)) {
+				t.Errorf("CheckOpenSSLPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_NewEncryptorChecksKDF(t *testing.T) {
+	// Политика без HKDF: ключ из подписи ssh-agent отклоняется до подключения к агенту
+	noHKDF := &config.Policy{
+		Name:       "pbkdf2-only",
+		Algorithms: []string{config.AlgorithmAES256GCM},
+		KDFs:       []string{config.KDFPBKDF2SHA256},
+	}
+	t.Setenv("SSH_AUTH_SOCK", filepath.Join(t.TempDir(), "no-agent.sock"))
+	cfg, err := config.NewSSHAgentConfig(&config.SSHAgentConfig{}, config.WithPolicy(noHKDF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encryption.NewEncryptor(cfg); !errors.Is(err, config.ErrPolicyViolation) {
+		t.Errorf("NewEncryptor(ssh-agent) error = %v, want %v", err, config.ErrPolicyViolation)
+	}
+}
@@ -0,0 +1,267 @@
+package encryption_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/internal/configfile"
+	internalenc "github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// writeProjectConfig записывает .encryptor.yaml в каталог dir
+func writeProjectConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, config.ProjectFileName)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProjectConfig_FindAndMatch(t *testing.T) {
+	root := t.TempDir()
+	writeProjectConfig(t, root, `
+creation_rules:
+  - path_glob: configs/prod/**
+    keyring: keys/prod.json
+    key_id: k2
+    policy: fips
+  - path_glob: "**/*.json"
+    key_env: DEV_KEY
+    algorithm: CHACHA20
+  - key_file: default.key
+`)
+	nested := filepath.Join(root, "configs", "prod", "eu")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := config.FindProjectConfig(nested)
+	if err != nil {
+		t.Fatalf("FindProjectConfig() error = %v", err)
+	}
+	if project.Path != filepath.Join(root, config.ProjectFileName) {
+		t.Errorf("Path = %q", project.Path)
+	}
+	if got := project.CreationRules[0].Keyring; got != filepath.Join(root, "keys", "prod.json") {
+		t.Errorf("keyring = %q, want it relative to the project directory", got)
+	}
+
+	tests := []struct {
+		file string
+		want int
+	}{
+		{"configs/prod/app.yml", 0},
+		{"configs/prod/eu/app.json", 0},
+		{"configs/dev/app.json", 1},
+		{"app.json", 1},
+		{"configs/dev/app.yml", 2},
+	}
+	for _, tt := range tests {
+		rule, err := project.Match(filepath.Join(root, tt.file))
+		if err != nil {
+			t.Errorf("Match(%s) error = %v", tt.file, err)
+			continue
+		}
+		if rule != &project.CreationRules[tt.want] {
+			t.Errorf("Match(%s) = %+v, want rule %d", tt.file, rule, tt.want+1)
+		}
+	}
+	if _, err := project.Match(filepath.Join(filepath.Dir(root), "other.yml")); !errors.Is(err, config.ErrNoCreationRule) {
+		t.Errorf("Match(outside the project) error = %v, want ErrNoCreationRule", err)
+	}
+
+	if _, err := config.FindProjectConfig(t.TempDir()); !errors.Is(err, config.ErrNoProjectConfig) {
+		t.Errorf("FindProjectConfig() without the file error = %v, want ErrNoProjectConfig", err)
+	}
+}
+
+func TestProjectConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"two key sources", "creation_rules:\n  - key_file: a\n    key_env: B\n", "only one of"},
+		{"key_id without keyring", "creation_rules:\n  - key_id: k1\n", "key_id requires keyring"},
+		{"unknown policy", "creation_rules:\n  - policy: nope\n", "nope"},
+		{"bad regex", "creation_rules:\n  - encrypted_regex: \"(\"\n", "invalid encrypted_regex"},
+		{"bad glob", "creation_rules:\n  - path_glob: \"[\"\n", "invalid path_glob"},
+		{"unknown field", "creation_rules:\n  - pgp: ABC\n", "pgp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeProjectConfig(t, t.TempDir(), tt.content)
+			if _, err := config.LoadProjectConfig(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadProjectConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreationRule_ShouldEncrypt(t *testing.T) {
+	path := writeProjectConfig(t, t.TempDir(), `
+creation_rules:
+  - encrypted_fields: [db.password, tokens]
+    encrypted_regex: ^(secret|api_key)$
+`)
+	project, err := config.LoadProjectConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := &project.CreationRules[0]
+
+	for field, want := range map[string]bool{
+		"db.password":          true,
+		"db.password_hint":     false,
+		"db.user":              false,
+		"tokens[1]":            true,
+		"tokens_count":         false,
+		"services[0].api_key":  true,
+		"secret.nested[2].val": true,
+		"secrets":              false,
+	} {
+		if got := rule.ShouldEncrypt(field); got != want {
+			t.Errorf("ShouldEncrypt(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestCreationRule_NewConfig(t *testing.T) {
+	dir := t.TempDir()
+	ring := config.NewKeyring()
+	for _, k := range []config.KeyringKey{
+		newKeyringKey(t, "k1", config.KeyStateActive),
+		newKeyringKey(t, "k2", config.KeyStateDecryptOnly),
+	} {
+		if err := ring.Add(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ring.Save(filepath.Join(dir, "keys.json")); err != nil {
+		t.Fatal(err)
+	}
+	path := writeProjectConfig(t, dir, `
+creation_rules:
+  - path_glob: "*.yml"
+    keyring: keys.json
+    key_id: k1
+  - path_glob: "*.json"
+    keyring: keys.json
+    key_id: missing
+  - path_glob: "*.toml"
+    keyring: keys.json
+    key_id: k2
+`)
+	project, err := config.LoadProjectConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := project.CreationRules[0].NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	env, err := internalenc.ParseEnvelope(mustEncrypt(t, enc, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Attrs[internalenc.AttrKeyID] != "k1" {
+		t.Errorf("kid = %q, want the key_id of the rule", env.Attrs[internalenc.AttrKeyID])
+	}
+
+	cfg, err = project.CreationRules[1].NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encryption.NewEncryptor(cfg); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("NewEncryptor() with an unknown key_id error = %v", err)
+	}
+
+	// Ключ только для расшифровки не шифрует новые значения
+	cfg, err = project.CreationRules[2].NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encryption.NewEncryptor(cfg); !errors.Is(err, interfaces.ErrNoActiveKey) {
+		t.Errorf("NewEncryptor() with a decrypt-only key_id error = %v, want ErrNoActiveKey", err)
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	enc := newRandomEncryptor(t)
+	existing := mustEncrypt(t, enc, "old")
+	shouldEncrypt := func(field string) bool {
+		return strings.HasSuffix(field, "password") || strings.HasPrefix(field, "tokens")
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    int
+		plain   []string
+	}{
+		{
+			name:    "yaml",
+			file:    "app.yml",
+			content: "db:\n  user: app\n  password: s3cret # comment\nredis:\n  password: " + existing + "\ntokens: [1, ~]\n",
+			want:    2,
+			plain:   []string{"user: app", "# comment", existing, ", ~]"},
+		},
+		{
+			name:    "json",
+			file:    "app.json",
+			content: `{"db":{"user":"app","password":"s3cret"},"tokens":["a",true]}`,
+			want:    3,
+			plain:   []string{`"user": "app"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := configfile.EncryptFile(path, shouldEncrypt, enc.EncryptString, true)
+			if err != nil || n != tt.want {
+				t.Fatalf("EncryptFile(dry run) = %d, %v, want %d", n, err, tt.want)
+			}
+			if data, _ := os.ReadFile(path); string(data) != tt.content {
+				t.Fatal("dry run modified the file")
+			}
+
+			if n, err := configfile.EncryptFile(path, shouldEncrypt, enc.EncryptString, false); err != nil || n != tt.want {
+				t.Fatalf("EncryptFile() = %d, %v, want %d", n, err, tt.want)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := string(data)
+			if strings.Contains(out, "s3cret") {
+				t.Errorf("password left in plaintext:\n%s", out)
+			}
+			for _, s := range tt.plain {
+				if !strings.Contains(out, s) {
+					t.Errorf("output lost %q:\n%s", s, out)
+				}
+			}
+
+			if n, err := configfile.EncryptFile(path, shouldEncrypt, enc.EncryptString, false); err != nil || n != 0 {
+				t.Errorf("EncryptFile() second run = %d, %v, want 0", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package encryption_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/internal/configfile"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// newRandomEncryptor создает шифратор со случайным ключом
+func newRandomEncryptor(t *testing.T) *encryption.Encryptor {
+	t.Helper()
+	return newEncryptorForKey(t, base64.StdEncoding.EncodeToString(randomKey(t, 32)))
+}
+
+func mustEncrypt(t *testing.T, enc *encryption.Encryptor, value string) string {
+	t.Helper()
+	encrypted, err := enc.EncryptString(value)
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	return encrypted
+}
+
+func TestRotateFile(t *testing.T) {
+	oldEnc, newEnc, otherEnc := newRandomEncryptor(t), newRandomEncryptor(t), newRandomEncryptor(t)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name: "yaml",
+			file: "config.yml",
+			content: "# database settings\n" +
+				"db:\n" +
+				"  password: " + mustEncrypt(t, oldEnc, "db-secret") + " # rotated\n" +
+				"  port: 5432\n" +
+				"tokens:\n" +
+				"  - " + mustEncrypt(t, oldEnc, "token") + "\n" +
+				"  - plain\n" +
+				"foreign: " + mustEncrypt(t, otherEnc, "other") + "\n",
+			want: map[string]string{"db.password": "db-secret", "tokens[0]": "token"},
+		},
+		{
+			name: "json",
+			file: "config.json",
+			content: `{"zeta": {"password": "` + mustEncrypt(t, oldEnc, "db-secret") + `", "port": 5432, "ratio": 0.5, "on": true, "none": null},` +
+				` "alpha": ["` + mustEncrypt(t, oldEnc, "token") + `", "<plain>"],` +
+				` "foreign": "` + mustEncrypt(t, otherEnc, "other") + `"}`,
+			want: map[string]string{"zeta.password": "db-secret", "alpha[0]": "token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o640); err != nil {
+				t.Fatal(err)
+			}
+
+			// Пробный прогон не изменяет файл
+			result, err := configfile.RotateFile(path, oldEnc.DecryptString, newEnc.EncryptString, true)
+			if err != nil {
+				t.Fatalf("RotateFile(dry run) error = %v", err)
+			}
+			if result.Rotated != 2 || len(result.Failed) != 1 {
+				t.Errorf("RotateFile(dry run) = %d rotated, %d failed, want 2 and 1", result.Rotated, len(result.Failed))
+			}
+			if data, _ := os.ReadFile(path); string(data) != tt.content {
+				t.Error("dry run modified the file")
+			}
+
+			result, err = configfile.RotateFile(path, oldEnc.DecryptString, newEnc.EncryptString, false)
+			if err != nil {
+				t.Fatalf("RotateFile() error = %v", err)
+			}
+			if result.Rotated != 2 {
+				t.Errorf("Rotated = %d, want 2", result.Rotated)
+			}
+			if len(result.Failed) != 1 || result.Failed[0].Field != "foreign" {
+				t.Errorf("Failed = %+v, want the foreign value", result.Failed)
+			}
+
+			values := map[string]string{}
+			err = configfile.ScanValues(path, func(field, _, value string) {
+				values[field] = value
+			})
+			if err != nil {
+				t.Fatalf("ScanValues() error = %v", err)
+			}
+			for field, plaintext := range tt.want {
+				if got, err := newEnc.DecryptString(values[field]); err != nil || got != plaintext {
+					t.Errorf("%s: new key DecryptString() = %q, %v", field, got, err)
+				}
+			}
+			if got, err := otherEnc.DecryptString(values["foreign"]); err != nil || got != "other" {
+				t.Errorf("foreign value changed: %q, %v", got, err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.name == "yaml" && !strings.Contains(string(data), "# database settings") {
+				t.Error("comments were not preserved")
+			}
+			if tt.name == "json" {
+				text := string(data)
+				if !strings.HasPrefix(text, "{") || strings.Index(text, `"zeta"`) > strings.Index(text, `"alpha"`) {
+					t.Errorf("JSON key order or format changed:\n%s", text)
+				}
+				if !strings.Contains(text, `"ratio": 0.5`) || !strings.Contains(text, `"none": null`) || !strings.Contains(text, `"<plain>"`) {
+					t.Errorf("JSON scalars changed:\n%s", text)
+				}
+			}
+			if runtime.GOOS != "windows" {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if perm := info.Mode().Perm(); perm != 0o640 {
+					t.Errorf("file mode = %04o, want 0640", perm)
+				}
+			}
+		})
+	}
+}
+
+func TestRotate_Directory(t *testing.T) {
+	oldEnc, newEnc := newRandomEncryptor(t), newRandomEncryptor(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.yaml":         "password: " + mustEncrypt(t, oldEnc, "a") + "\n",
+		"nested/db.json":   `{"password": "` + mustEncrypt(t, oldEnc, "b") + `"}`,
+		"nested/notes.txt": "password: " + mustEncrypt(t, oldEnc, "c") + "\n",
+		".git/skip.yml":    "password: " + mustEncrypt(t, oldEnc, "d") + "\n",
+		"broken.yml":       "password: [unclosed\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := configfile.Rotate([]string{dir}, oldEnc.DecryptString, newEnc.EncryptString, false)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	got := map[string]*configfile.RotateResult{}
+	for _, r := range results {
+		rel, _ := filepath.Rel(dir, r.Path)
+		got[filepath.ToSlash(rel)] = r
+	}
+	if len(got) != 3 {
+		t.Fatalf("Rotate() processed %v, want app.yaml, nested/db.json and broken.yml", got)
+	}
+	for _, name := range []string{"app.yaml", "nested/db.json"} {
+		if r := got[name]; r == nil || r.Rotated != 1 || len(r.Failed) != 0 {
+			t.Errorf("%s: result = %+v, want 1 rotated", name, r)
+		}
+	}
+	if r := got["broken.yml"]; r == nil || len(r.Failed) != 1 || r.Failed[0].Field != "" {
+		t.Errorf("broken.yml: result = %+v, want a file-level failure", r)
+	}
+
+	// Файлы вне YAML/JSON и скрытые каталоги не изменяются
+	for _, name := range []string{"nested/notes.txt", ".git/skip.yml"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != files[name] {
+			t.Errorf("%s was modified", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package encryption_test
+
+import (
+	"strings"
+	"testing"
+
+	internal "github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+func TestSelfTest_Report(t *testing.T) {
+	cfg, err := config.NewConfig(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	encryptor, err := encryption.NewEncryptor(cfg, encryption.WithSelfTest())
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+
+	report, err := encryptor.SelfTest()
+	if err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+	if want := len(internal.Algorithms()) + 1; len(report.Results) != want {
+		t.Errorf("SelfTest() results = %d, want %d", len(report.Results), want)
+	}
+	for _, name := range internal.Algorithms() {
+		if !strings.Contains(report.String(), "PASS KAT "+name) {
+			t.Errorf("SelfTest() report has no passed KAT for %s:\n%s", name, report)
+		}
+	}
+}
+
+func TestSelfTest_KnownAnswerMismatch(t *testing.T) {
+	for _, name := range internal.Algorithms() {
+		t.Run(name, func(t *testing.T) {
+			alg, _ := internal.LookupAlgorithm(name)
+			if err := internal.RunKnownAnswerTest(alg); err != nil {
+				t.Fatalf("RunKnownAnswerTest() error = %v", err)
+			}
+
+			// Портим последний байт тега
+			kat := alg.KAT.Ciphertext
+			last := "0"
+			if strings.HasSuffix(kat, "0") {
+				last = "1"
+			}
+			alg.KAT.Ciphertext = kat[:len(kat)-1] + last
+			if err := internal.RunKnownAnswerTest(alg); err == nil {
+				t.Errorf("RunKnownAnswerTest() with corrupted vector error = nil")
+			}
+		})
+	}
+}
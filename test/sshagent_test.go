@@ -0,0 +1,182 @@
+package encryption_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	internalenc "github.com/JohnnyFes/go-encryptor/internal/encryption"
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/internal/sshagent"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// startSSHAgent запускает ssh-agent в процессе теста с ключами keys и возвращает его сокет
+func startSSHAgent(t *testing.T, keys ...interface{}) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("ssh-agent tests need Unix sockets")
+	}
+	keyring := agent.NewKeyring()
+	for _, k := range keys {
+		if err := keyring.Add(agent.AddedKey{PrivateKey: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	socket := filepath.Join(t.TempDir(), "ssh-agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	return socket
+}
+
+func newEd25519Key(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ssh.NewPublicKey(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, ssh.FingerprintSHA256(pub)
+}
+
+func newSSHAgentEncryptor(t *testing.T, socket, fingerprint string) *encryption.Encryptor {
+	t.Helper()
+	cfg, err := config.NewSSHAgentConfig(&config.SSHAgentConfig{Socket: socket, Fingerprint: fingerprint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	t.Cleanup(func() { enc.Close() })
+	return enc
+}
+
+func TestSSHAgent_EncryptDecrypt(t *testing.T) {
+	keyA, fingerprintA := newEd25519Key(t)
+	keyB, fingerprintB := newEd25519Key(t)
+	socket := startSSHAgent(t, keyA, keyB)
+
+	encA := newSSHAgentEncryptor(t, socket, fingerprintA)
+	encrypted := mustEncrypt(t, encA, "secret")
+	env, err := internalenc.ParseEnvelope(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Attrs[internalenc.AttrKeyID] != fingerprintA {
+		t.Errorf("kid = %q, want %q", env.Attrs[internalenc.AttrKeyID], fingerprintA)
+	}
+
+	// Подпись Ed25519 детерминирована: новый шифратор получает тот же ключ
+	if got, err := newSSHAgentEncryptor(t, socket, "").DecryptString(encrypted); err != nil || got != "secret" {
+		t.Errorf("DecryptString() with a new connection = %q, %v", got, err)
+	}
+	// Ключ для расшифровки выбирается по kid среди ключей агента
+	encB := newSSHAgentEncryptor(t, socket, fingerprintB)
+	if got, err := encB.DecryptString(encrypted); err != nil || got != "secret" {
+		t.Errorf("DecryptString() by kid = %q, %v", got, err)
+	}
+	if mustEncrypt(t, encB, "secret") == encrypted {
+		t.Error("different SSH keys produced the same value")
+	}
+
+	// В агенте нет ключа A
+	other := newSSHAgentEncryptor(t, startSSHAgent(t, keyB), "")
+	if _, err := other.DecryptString(encrypted); !errors.Is(err, interfaces.ErrDecryptionFailed) || !strings.Contains(err.Error(), fingerprintA) {
+		t.Errorf("DecryptString() without the key error = %v, want ErrDecryptionFailed naming %s", err, fingerprintA)
+	}
+}
+
+func TestSSHAgent_DeriveKeyKnownAnswer(t *testing.T) {
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	priv := ed25519.NewKeyFromSeed(seed)
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := sshagent.DeriveKey(keyring.(agent.ExtendedAgent), signer.PublicKey(), config.AlgorithmAES256GCM, 32)
+	if err != nil {
+		t.Fatalf("DeriveKey() error = %v", err)
+	}
+	// Ключ не должен меняться между версиями, иначе старые значения не расшифруются
+	const want = "a73220bc394e30447a470ec3d3bfc4a77767eb436e87b446a8008f83bde86342"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("DeriveKey() = %s, want %s", got, want)
+	}
+}
+
+func TestSSHAgent_Errors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPub, err := ssh.NewPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := startSSHAgent(t, ecKey)
+
+	tests := []struct {
+		name        string
+		socket      string
+		fingerprint string
+		wantErr     string
+	}{
+		{"no Ed25519 key", socket, "", "ssh-agent has no ssh-ed25519 key"},
+		{"ECDSA key", socket, ssh.FingerprintSHA256(ecPub), "only ssh-ed25519 signatures are deterministic"},
+		{"unknown key", socket, "SHA256:unknown", "ssh-agent has no key SHA256:unknown"},
+		{"no agent", filepath.Join(t.TempDir(), "missing.sock"), "", "failed to connect to ssh-agent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.NewSSHAgentConfig(&config.SSHAgentConfig{Socket: tt.socket, Fingerprint: tt.fingerprint})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := encryption.NewEncryptor(cfg); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewEncryptor() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Setenv(config.SSHAuthSockEnv, "")
+	if _, err := config.NewSSHAgentConfig(nil); err == nil {
+		t.Error("NewSSHAgentConfig() without SSH_AUTH_SOCK succeeded")
+	}
+}
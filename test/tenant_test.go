@@ -0,0 +1,93 @@
+package encryption_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+func TestEncryptor_ForTenant(t *testing.T) {
+	master := newTestEncryptor(t)
+
+	tests := []struct {
+		name     string
+		tenantID string
+	}{
+		{name: "simple id", tenantID: "acme"},
+		{name: "id with separators", tenantID: "org:1;team=2]"},
+		{name: "unicode id", tenantID: "арендатор"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := master.ForTenant(tt.tenantID)
+			encrypted, err := tenant.EncryptString("secret")
+			if err != nil {
+				t.Fatalf("EncryptString() error = %v", err)
+			}
+			if !strings.HasPrefix(encrypted, "ENC[AES256;tenant=") {
+				t.Errorf("EncryptString() = %s, want tenant in envelope", encrypted)
+			}
+
+			decrypted, err := tenant.DecryptString(encrypted)
+			if err != nil || decrypted != "secret" {
+				t.Errorf("DecryptString() = %q, %v, want %q", decrypted, err, "secret")
+			}
+
+			// Другой арендатор и мастер-ключ не расшифровывают значение
+			if _, err := master.ForTenant("other").DecryptString(encrypted); !errors.Is(err, interfaces.ErrTenantMismatch) {
+				t.Errorf("other tenant DecryptString() error = %v, want %v", err, interfaces.ErrTenantMismatch)
+			}
+			if _, err := master.DecryptString(encrypted); !errors.Is(err, interfaces.ErrTenantMismatch) {
+				t.Errorf("master DecryptString() error = %v, want %v", err, interfaces.ErrTenantMismatch)
+			}
+		})
+	}
+}
+
+func TestEncryptor_ForTenantDeterministic(t *testing.T) {
+	// Ключ арендатора зависит только от мастер-ключа и идентификатора
+	encrypted, err := newTestEncryptor(t).ForTenant("acme").EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	decrypted, err := newTestEncryptor(t).ForTenant("acme").DecryptString(encrypted)
+	if err != nil || decrypted != "secret" {
+		t.Errorf("DecryptString() = %q, %v, want %q", decrypted, err, "secret")
+	}
+
+	// Подмена арендатора в заголовке не проходит аутентификацию
+	forged := strings.Replace(encrypted, "tenant=acme", "tenant=evil", 1)
+	if _, err := newTestEncryptor(t).ForTenant("evil").DecryptString(forged); err == nil {
+		t.Errorf("DecryptString() of forged envelope error = nil")
+	}
+}
+
+func TestEncryptor_ForTenantCache(t *testing.T) {
+	cfg, err := config.NewConfig(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	master, err := encryption.NewEncryptor(cfg, encryption.WithTenantCacheSize(2))
+	if err != nil {
+		t.Fatalf("Failed to create encryptor: %v", err)
+	}
+
+	a := master.ForTenant("a")
+	if master.ForTenant("a") != a {
+		t.Errorf("ForTenant() did not return cached encryptor")
+	}
+	master.ForTenant("b")
+	master.ForTenant("c")
+	if master.ForTenant("a") == a {
+		t.Errorf("ForTenant() returned evicted encryptor")
+	}
+
+	if _, err := master.ForTenant("").EncryptString("secret"); !errors.Is(err, interfaces.ErrInvalidConfig) {
+		t.Errorf("ForTenant(\"\") EncryptString() error = %v, want %v", err, interfaces.ErrInvalidConfig)
+	}
+}
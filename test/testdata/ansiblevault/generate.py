@@ -0,0 +1,53 @@
+#!/usr/bin/env python3
+"""Генерирует фикстуры Ansible Vault 1.1 (AES256) независимо от кода на Go.
+
+Повторяет VaultAES256 из ansible/parsing/vault/__init__.py: случайная соль
+32 байта, PBKDF2-HMAC-SHA256 (10000 итераций) дает key1, key2 и IV,
+открытый текст дополняется PKCS#7 и шифруется AES-256-CTR, HMAC-SHA256
+ключом key2 считается от шифротекста. AES-CTR выполняет openssl enc,
+остальное - стандартная библиотека Python.
+
+    cd test/testdata/ansiblevault && python3 generate.py
+"""
+
+import binascii
+import hashlib
+import hmac
+import os
+import subprocess
+
+PASSWORD = b"vault-pass"
+
+
+def encrypt(plaintext: bytes) -> str:
+    salt = os.urandom(32)
+    derived = hashlib.pbkdf2_hmac("sha256", PASSWORD, salt, 10000, 80)
+    key1, key2, iv = derived[:32], derived[32:64], derived[64:]
+
+    pad = 16 - len(plaintext) % 16
+    padded = plaintext + bytes([pad]) * pad
+    ciphertext = subprocess.run(
+        ["openssl", "enc", "-aes-256-ctr", "-K", key1.hex(), "-iv", iv.hex(), "-nopad"],
+        input=padded, capture_output=True, check=True,
+    ).stdout
+    mac = hmac.new(key2, ciphertext, hashlib.sha256).digest()
+
+    body = binascii.hexlify(b"\n".join(binascii.hexlify(part) for part in (salt, mac, ciphertext))).decode()
+    lines = ["$ANSIBLE_VAULT;1.1;AES256"] + [body[i:i + 80] for i in range(0, len(body), 80)]
+    return "\n".join(lines) + "\n"
+
+
+def indent(text: str, prefix: str) -> str:
+    return "".join(prefix + line + "\n" for line in text.splitlines())
+
+
+with open("secret.txt.vault", "w") as f:
+    f.write(encrypt(b"db-password-123"))
+
+with open("whole.yml", "w") as f:
+    f.write(encrypt(b"api:\n  token: tok-42\n  port: 8080\n"))
+
+with open("inline.yml", "w") as f:
+    f.write("database:\n  user: app\n  password: !vault |\n")
+    f.write(indent(encrypt(b"s3cr3t"), "    "))
+    f.write("redis:\n  host: localhost\n")
@@ -0,0 +1,193 @@
+package encryption_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/internal/transit/transittest"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+func newTransitEncryptor(t *testing.T, srv *transittest.Server, transit config.TransitConfig) *encryption.Encryptor {
+	t.Helper()
+	transit.Address = srv.URL
+	if transit.KeyName == "" {
+		transit.KeyName = "app"
+	}
+	if transit.RetryBackoff == 0 {
+		transit.RetryBackoff = time.Millisecond
+	}
+	cfg, err := config.NewTransitConfig(&transit)
+	if err != nil {
+		t.Fatalf("NewTransitConfig() error = %v", err)
+	}
+	enc, err := encryption.NewEncryptor(cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	return enc
+}
+
+func TestTransit_Roundtrip(t *testing.T) {
+	srv := transittest.NewServer("root")
+	defer srv.Close()
+	srv.AddAppRole("role", "secret")
+
+	tests := []struct {
+		name    string
+		transit config.TransitConfig
+	}{
+		{name: "token", transit: config.TransitConfig{Token: "root"}},
+		{name: "approle", transit: config.TransitConfig{RoleID: "role", SecretID: "secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := newTransitEncryptor(t, srv, tt.transit)
+
+			encrypted, err := enc.EncryptString("secret data")
+			if err != nil {
+				t.Fatalf("EncryptString() error = %v", err)
+			}
+			if !strings.HasPrefix(encrypted, "ENC[TRANSIT;kid=app/v1:") {
+				t.Errorf("EncryptString() = %s, want ENC[TRANSIT;kid=app/v1:...]", encrypted)
+			}
+
+			decrypted, err := enc.DecryptString(encrypted)
+			if err != nil || decrypted != "secret data" {
+				t.Errorf("DecryptString() = %q, %v, want %q", decrypted, err, "secret data")
+			}
+		})
+	}
+}
+
+func TestTransit_KeyRotation(t *testing.T) {
+	srv := transittest.NewServer("root")
+	defer srv.Close()
+	enc := newTransitEncryptor(t, srv, config.TransitConfig{Token: "root"})
+
+	old, err := enc.EncryptString("before rotation")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	srv.RotateKey("app")
+
+	current, err := enc.EncryptString("after rotation")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	if !strings.HasPrefix(current, "ENC[TRANSIT;kid=app/v2:") {
+		t.Errorf("EncryptString() after rotation = %s, want key version v2", current)
+	}
+
+	// Старые значения расшифровываются версией ключа из конверта
+	if decrypted, err := enc.DecryptString(old); err != nil || decrypted != "before rotation" {
+		t.Errorf("DecryptString(v1) = %q, %v", decrypted, err)
+	}
+	if decrypted, err := enc.DecryptString(current); err != nil || decrypted != "after rotation" {
+		t.Errorf("DecryptString(v2) = %q, %v", decrypted, err)
+	}
+}
+
+func TestTransit_WrongKeyName(t *testing.T) {
+	srv := transittest.NewServer("root")
+	defer srv.Close()
+	app := newTransitEncryptor(t, srv, config.TransitConfig{Token: "root", KeyName: "app"})
+	other := newTransitEncryptor(t, srv, config.TransitConfig{Token: "root", KeyName: "other"})
+
+	encrypted, err := app.EncryptString("secret")
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	if _, err := other.DecryptString(encrypted); !errors.Is(err, interfaces.ErrDecryptionFailed) {
+		t.Errorf("DecryptString() error = %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestTransit_Retries(t *testing.T) {
+	srv := transittest.NewServer("root")
+	defer srv.Close()
+	enc := newTransitEncryptor(t, srv, config.TransitConfig{Token: "root", MaxRetries: 2})
+
+	// Два сбоя укладываются в число повторов
+	srv.FailNext(2)
+	if _, err := enc.EncryptString("secret"); err != nil {
+		t.Fatalf("EncryptString() after transient errors: %v", err)
+	}
+
+	// Три сбоя превышают его
+	srv.FailNext(3)
+	if _, err := enc.EncryptString("secret"); !errors.Is(err, interfaces.ErrEncryptionFailed) {
+		t.Errorf("EncryptString() error = %v, want ErrEncryptionFailed", err)
+	}
+}
+
+func TestTransit_AppRoleRelogin(t *testing.T) {
+	srv := transittest.NewServer("root")
+	defer srv.Close()
+	srv.AddAppRole("role", "secret")
+	enc := newTransitEncryptor(t, srv, config.TransitConfig{RoleID: "role", SecretID: "secret"})
+
+	if _, err := enc.EncryptString("secret"); err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	// Токен истек: клиент должен войти заново
+	srv.RevokeTokens()
+	if _, err := enc.EncryptString("secret"); err != nil {
+		t.Errorf("EncryptString() after token revocation: %v", err)
+	}
+}
+
+func TestTransit_BadCredentials(t *testing.T) {
+	srv := transittest.NewServer("root")
+	defer srv.Close()
+
+	enc := newTransitEncryptor(t, srv, config.TransitConfig{Token: "wrong"})
+	if _, err := enc.EncryptString("secret"); !errors.Is(err, interfaces.ErrEncryptionFailed) {
+		t.Errorf("EncryptString() error = %v, want ErrEncryptionFailed", err)
+	}
+}
+
+func TestTransit_ContextCanceled(t *testing.T) {
+	srv := transittest.NewServer("root")
+	defer srv.Close()
+	enc := newTransitEncryptor(t, srv, config.TransitConfig{Token: "root", RetryBackoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv.FailNext(1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	// Отмена прерывает ожидание перед повтором
+	if _, err := enc.EncryptStringContext(ctx, "secret"); !errors.Is(err, context.Canceled) {
+		t.Errorf("EncryptStringContext() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewTransitConfig_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		transit *config.TransitConfig
+	}{
+		{name: "nil", transit: nil},
+		{name: "no address", transit: &config.TransitConfig{KeyName: "app", Token: "t"}},
+		{name: "no key name", transit: &config.TransitConfig{Address: "http://vault", Token: "t"}},
+		{name: "no credentials", transit: &config.TransitConfig{Address: "http://vault", KeyName: "app"}},
+		{name: "partial approle", transit: &config.TransitConfig{Address: "http://vault", KeyName: "app", RoleID: "r"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := config.NewTransitConfig(tt.transit); err == nil {
+				t.Error("NewTransitConfig() error = nil, want error")
+			}
+		})
+	}
+}
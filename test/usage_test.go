@@ -0,0 +1,265 @@
+package encryption_test
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/JohnnyFes/go-encryptor/internal/interfaces"
+	"github.com/JohnnyFes/go-encryptor/pkg/config"
+	"github.com/JohnnyFes/go-encryptor/pkg/encryption"
+)
+
+// noUsageLimit порог для UsageStore.Add, который не достигается
+const noUsageLimit = math.MaxUint64
+
+func TestUsageTracker_Limits(t *testing.T) {
+	var warnings []encryption.UsageStatus
+	tracker := encryption.NewUsageTracker(
+		encryption.NewMemoryUsageStore(),
+		encryption.WithUsageLimits(2, 3),
+		encryption.WithUsageWarning(func(s encryption.UsageStatus) {
+			warnings = append(warnings, s)
+		}),
+	)
+
+	cfg, err := config.NewConfig(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	encryptor, err := encryption.NewEncryptor(cfg, encryption.WithUsageTracker(tracker))
+	if err != nil {
+		t.Fatalf("Failed to create encryptor: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if _, err := encryptor.EncryptString("secret"); err != nil {
+			t.Fatalf("EncryptString() #%d error = %v", i, err)
+		}
+	}
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %d, want 2", len(warnings))
+	}
+
+	status, err := encryptor.KeyUsage()
+	if err != nil {
+		t.Fatalf("KeyUsage() error = %v", err)
+	}
+	if status.Count != 3 || !status.RotationDue || status.KeyID == "" {
+		t.Errorf("KeyUsage() = %+v, want count 3 and rotation due", status)
+	}
+
+	if _, err := encryptor.EncryptString("secret"); !errors.Is(err, interfaces.ErrKeyUsageExceeded) {
+		t.Errorf("EncryptString() past hard limit error = %v, want %v", err, interfaces.ErrKeyUsageExceeded)
+	}
+
+	// Ключ арендатора учитывается отдельно
+	tenantStatus, err := encryptor.ForTenant("acme").KeyUsage()
+	if err != nil {
+		t.Fatalf("KeyUsage() error = %v", err)
+	}
+	if tenantStatus.KeyID == status.KeyID || tenantStatus.Count != 0 {
+		t.Errorf("tenant KeyUsage() = %+v, want separate counter", tenantStatus)
+	}
+}
+
+func TestFileUsageStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	store := encryption.NewFileUsageStore(path)
+	if _, err := store.Add("key-1", 5, noUsageLimit); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := store.Add("key-2", 1, noUsageLimit); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// Close возвращает неиспользованный резерв
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened := encryption.NewFileUsageStore(path)
+	count, err := reopened.Add("key-1", 1, noUsageLimit)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if count != 6 {
+		t.Errorf("Add() = %d, want 6", count)
+	}
+
+	tracker := encryption.NewUsageTracker(reopened, encryption.WithUsageLimits(6, 10))
+	due, err := tracker.RotationDue("key-1")
+	if err != nil || !due {
+		t.Errorf("RotationDue(key-1) = %v, %v, want true", due, err)
+	}
+	due, err = tracker.RotationDue("key-2")
+	if err != nil || due {
+		t.Errorf("RotationDue(key-2) = %v, %v, want false", due, err)
+	}
+}
+
+func TestFileUsageStore_Reservation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	// Процесс, завершившийся без Close, оставляет резерв учтенным: счетчик не занижается
+	crashed := encryption.NewFileUsageStore(path, encryption.WithUsageReservation(100))
+	if count, err := crashed.Add("key", 1, noUsageLimit); err != nil || count != 1 {
+		t.Fatalf("Add() = %d, %v", count, err)
+	}
+	if count, err := crashed.Load("key"); err != nil || count != 1 {
+		t.Errorf("Load() in the same store = %d, %v, want 1", count, err)
+	}
+	if count, err := encryption.NewFileUsageStore(path).Load("key"); err != nil || count != 100 {
+		t.Errorf("Load() in another store = %d, %v, want the reserved 100", count, err)
+	}
+
+	// Несколько хранилищ над одним файлом выдают непересекающиеся значения
+	const stores, adds = 4, 50
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < stores; i++ {
+		store := encryption.NewFileUsageStore(path, encryption.WithUsageReservation(7))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer store.Close()
+			for j := 0; j < adds; j++ {
+				count, err := store.Add("key", 1, noUsageLimit)
+				if err != nil {
+					t.Errorf("Add() error = %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[count] {
+					t.Errorf("count %d issued twice", count)
+				}
+				seen[count] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	count, err := encryption.NewFileUsageStore(path).Load("key")
+	if err != nil || count < 100+stores*adds {
+		t.Errorf("Load() = %d, %v, want at least %d", count, err, 100+stores*adds)
+	}
+}
+
+func TestUsageTracker_RefusalsNotRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	store := encryption.NewFileUsageStore(path, encryption.WithUsageReservation(1))
+	tracker := encryption.NewUsageTracker(store, encryption.WithUsageLimits(2, 3), encryption.WithUsageWarning(func(encryption.UsageStatus) {}))
+
+	for i := 1; i <= 3; i++ {
+		if err := tracker.Record("key"); err != nil {
+			t.Fatalf("Record() #%d error = %v", i, err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if err := tracker.Record("key"); !errors.Is(err, interfaces.ErrKeyUsageExceeded) {
+			t.Fatalf("Record() past hard limit error = %v, want %v", err, interfaces.ErrKeyUsageExceeded)
+		}
+	}
+	if count, _ := store.Load("key"); count != 3 {
+		t.Errorf("count after refusals = %d, want 3", count)
+	}
+
+	// Новый процесс не увеличивает счетчик исчерпанного ключа
+	again := encryption.NewUsageTracker(encryption.NewFileUsageStore(path), encryption.WithUsageLimits(2, 3))
+	if err := again.Record("key"); !errors.Is(err, interfaces.ErrKeyUsageExceeded) {
+		t.Errorf("Record() in a new tracker error = %v, want %v", err, interfaces.ErrKeyUsageExceeded)
+	}
+	if count, _ := store.Load("key"); count != 3 {
+		t.Errorf("count after new tracker = %d, want 3", count)
+	}
+}
+
+func TestUsageTracker_ConcurrentHardLimit(t *testing.T) {
+	const hard = 500
+	path := filepath.Join(t.TempDir(), "usage.json")
+	memory := encryption.NewMemoryUsageStore()
+
+	tests := []struct {
+		name   string
+		stores func() []encryption.UsageStore
+	}{
+		{"memory store", func() []encryption.UsageStore { return []encryption.UsageStore{memory} }},
+		{"file stores over one file", func() []encryption.UsageStore {
+			// Несколько хранилищ над одним файлом моделируют несколько процессов
+			var stores []encryption.UsageStore
+			for i := 0; i < 4; i++ {
+				stores = append(stores, encryption.NewFileUsageStore(path, encryption.WithUsageReservation(7)))
+			}
+			return stores
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var successes atomic.Int64
+			var wg sync.WaitGroup
+			stores := tt.stores()
+			for _, store := range stores {
+				tracker := encryption.NewUsageTracker(store, encryption.WithUsageLimits(hard, hard))
+				for g := 0; g < 4; g++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for {
+							err := tracker.Record("key")
+							if errors.Is(err, interfaces.ErrKeyUsageExceeded) {
+								return
+							}
+							if err != nil {
+								t.Errorf("Record() error = %v", err)
+								return
+							}
+							successes.Add(1)
+						}
+					}()
+				}
+			}
+			wg.Wait()
+			if got := successes.Load(); got != hard {
+				t.Errorf("successful Record() calls = %d, want exactly %d", got, hard)
+			}
+			if count, err := stores[0].Load("key"); err != nil || count != hard {
+				t.Errorf("stored count = %d, %v, want %d", count, err, hard)
+			}
+		})
+	}
+}
+
+func TestUsageTracker_DefaultStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+
+	tracker := encryption.NewUsageTracker(nil)
+	if err := tracker.Record("key"); err != nil {
+		t.Fatalf("Record() with the default store error = %v", err)
+	}
+	if err := tracker.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	path, err := encryption.DefaultUsageFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, err := encryption.NewFileUsageStore(path).Load("key"); err != nil || count != 1 {
+		t.Errorf("default usage file count = %d, %v, want 1", count, err)
+	}
+
+	// Без каталога настроек Record возвращает ошибку, а не паникует
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "")
+	t.Setenv("AppData", "")
+	if err := encryption.NewUsageTracker(nil).Record("key"); err == nil {
+		t.Error("Record() without a config directory succeeded")
+	}
+}